@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"log"
 	"net/http"
@@ -18,11 +19,24 @@ import (
 	"github.com/agenteats/agenteats/internal/handlers"
 	"github.com/agenteats/agenteats/internal/mcpserver"
 	authmw "github.com/agenteats/agenteats/internal/middleware"
+	"github.com/agenteats/agenteats/internal/notify"
+	"github.com/agenteats/agenteats/internal/services"
+	"github.com/agenteats/agenteats/internal/webhooks"
 )
 
 func main() {
 	cfg := config.Load()
+	if err := webhooks.AllowNets(cfg.WebhookAllowNets); err != nil {
+		log.Fatalf("WEBHOOK_ALLOW_NETS: %v", err)
+	}
+	handlers.TrustForwardedHost = cfg.TrustForwardedHost
 	database.Init(cfg)
+	// Issue manage tokens to reservations booked before they existed.
+	if issued, sent, err := services.IssueMissingManageTokens(database.DB); err != nil {
+		log.Fatalf("failed to issue manage tokens: %v", err)
+	} else if issued > 0 {
+		log.Printf("Issued manage tokens to %d existing reservations; %d were queued for emailing to their guests", issued, sent)
+	}
 
 	r := chi.NewRouter()
 
@@ -41,7 +55,7 @@ func main() {
 	}
 	r.Use(cors.Handler(cors.Options{
 		AllowedOrigins:   allowedOrigins,
-		AllowedMethods:   []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
+		AllowedMethods:   []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
 		AllowedHeaders:   []string{"*"},
 		AllowCredentials: true,
 		MaxAge:           300,
@@ -56,16 +70,29 @@ func main() {
 		r.Get("/restaurants", handlers.SearchRestaurants)
 		r.Get("/restaurants/{restaurantID}", handlers.GetRestaurant)
 		r.Get("/restaurants/{restaurantID}/menu", handlers.GetMenu)
+		r.Get("/menu-items/search", handlers.SearchDishes)
 		r.Get("/restaurants/{restaurantID}/availability", handlers.CheckAvailability)
-		r.Get("/restaurants/{restaurantID}/reservations", handlers.ListReservations)
+		r.Get("/restaurants/{restaurantID}/occupancy", handlers.GetOccupancy)
+		r.With(authmw.OptionalAPIKey).Get("/restaurants/{restaurantID}/reviews", handlers.ListReviews)
+		r.With(authmw.OptionalAPIKey).Get("/restaurants/{restaurantID}/calendar.ics", handlers.RestaurantCalendar)
 		r.Get("/recommendations", handlers.GetRecommendations)
+		r.Get("/taxonomy", handlers.GetTaxonomy)
 	})
 
-	// --- Reservation endpoints (rate-limited) ---
+	// --- Reservation, waitlist and review endpoints (rate-limited) ---
 	r.Group(func(r chi.Router) {
 		r.Use(httprate.LimitByIP(20, time.Minute))
 		r.Post("/restaurants/{restaurantID}/reservations", handlers.MakeReservation)
-		r.Delete("/reservations/{reservationID}", handlers.CancelReservation)
+		r.With(authmw.OptionalAPIKey).Get("/reservations/{reservationID}", handlers.GetReservation)
+		r.With(authmw.OptionalAPIKey).Get("/reservations/{reservationID}/calendar.ics", handlers.GetReservationCalendar)
+		r.With(authmw.OptionalAPIKey).Patch("/reservations/{reservationID}", handlers.ModifyReservation)
+		r.With(authmw.OptionalAPIKey).Delete("/reservations/{reservationID}", handlers.CancelReservation)
+
+		r.Post("/restaurants/{restaurantID}/waitlist", handlers.JoinWaitlist)
+		r.With(authmw.OptionalAPIKey).Get("/waitlist/{entryID}", handlers.GetWaitlistEntry)
+		r.With(authmw.OptionalAPIKey).Delete("/waitlist/{entryID}", handlers.LeaveWaitlist)
+
+		r.Post("/reservations/{reservationID}/review", handlers.SubmitReview)
 	})
 
 	// --- Owner registration (strict rate limit) ---
@@ -88,6 +115,54 @@ func main() {
 		// Menu management
 		r.Post("/restaurants/{restaurantID}/menu/items", handlers.AddOwnedMenuItem)
 		r.Post("/restaurants/{restaurantID}/menu/import", handlers.BulkImportMenu)
+		r.Get("/restaurants/{restaurantID}/menu/export", handlers.ExportMenu)
+		r.Put("/restaurants/{restaurantID}/menu/items/{itemID}", handlers.UpdateOwnedMenuItem)
+		r.Patch("/restaurants/{restaurantID}/menu/items/{itemID}", handlers.PatchOwnedMenuItem)
+		r.Delete("/restaurants/{restaurantID}/menu/items/{itemID}", handlers.DeleteOwnedMenuItem)
+		r.Put("/restaurants/{restaurantID}/menu/items/{itemID}/availability", handlers.SetMenuItemAvailability)
+		r.Put("/restaurants/{restaurantID}/menu/order", handlers.ReorderMenu)
+
+		// Reservations
+		r.Get("/restaurants/{restaurantID}/reservations", handlers.ListOwnedReservations)
+		r.Get("/restaurants/{restaurantID}/no-shows", handlers.ListNoShows)
+		r.Post("/reservations/{reservationID}/status", handlers.UpdateReservationStatus)
+
+		// Waitlist
+		r.Get("/restaurants/{restaurantID}/waitlist", handlers.ListOwnedWaitlist)
+		r.Post("/waitlist/{entryID}/promote", handlers.PromoteWaitlistEntry)
+
+		// Reviews
+		r.Put("/reviews/{reviewID}/reply", handlers.ReplyToReview)
+		r.Post("/reviews/{reviewID}/status", handlers.ModerateReview)
+
+		// Guest notification templates
+		r.Get("/restaurants/{restaurantID}/notification-templates", handlers.ListNotificationTemplates)
+		r.Put("/restaurants/{restaurantID}/notification-templates/{kind}", handlers.SetNotificationTemplate)
+		r.Delete("/restaurants/{restaurantID}/notification-templates/{kind}", handlers.DeleteNotificationTemplate)
+
+		// Calendar feed
+		r.Post("/restaurants/{restaurantID}/calendar-feed", handlers.CreateCalendarFeed)
+		r.Delete("/restaurants/{restaurantID}/calendar-feed", handlers.DeleteCalendarFeed)
+
+		// Webhooks
+		r.Get("/owners/webhooks", handlers.ListWebhooks)
+		r.Post("/owners/webhooks", handlers.CreateWebhook)
+		r.Put("/owners/webhooks/{webhookID}", handlers.UpdateWebhook)
+		r.Delete("/owners/webhooks/{webhookID}", handlers.DeleteWebhook)
+		r.Get("/owners/webhooks/{webhookID}/deliveries", handlers.ListWebhookDeliveries)
+		r.Post("/owners/webhooks/{webhookID}/deliveries/{deliveryID}/replay", handlers.ReplayWebhookDelivery)
+
+		// Table inventory
+		r.Get("/restaurants/{restaurantID}/tables", handlers.ListOwnedTables)
+		r.Post("/restaurants/{restaurantID}/tables", handlers.CreateOwnedTable)
+		r.Put("/restaurants/{restaurantID}/tables/{tableID}", handlers.UpdateOwnedTable)
+		r.Delete("/restaurants/{restaurantID}/tables/{tableID}", handlers.DeleteOwnedTable)
+
+		// Special hours and closures
+		r.Get("/restaurants/{restaurantID}/hours-overrides", handlers.ListOwnedHoursOverrides)
+		r.Post("/restaurants/{restaurantID}/hours-overrides", handlers.CreateOwnedHoursOverride)
+		r.Put("/restaurants/{restaurantID}/hours-overrides/{overrideID}", handlers.UpdateOwnedHoursOverride)
+		r.Delete("/restaurants/{restaurantID}/hours-overrides/{overrideID}", handlers.DeleteOwnedHoursOverride)
 	})
 
 	// --- Remote MCP (Streamable HTTP, rate-limited) ---
@@ -100,6 +175,16 @@ func main() {
 		r.Mount("/mcp", httpMCP)
 	})
 
+	// Webhook deliveries and guest notifications are queued by the
+	// services; send them, and queue reminders, in the background.
+	notifier, err := notify.New(cfg)
+	if err != nil {
+		log.Fatalf("notifications: %v", err)
+	}
+	go webhooks.NewDispatcher(database.DB).Run(context.Background())
+	go notify.NewDispatcher(database.DB, notifier).Run(context.Background())
+	go services.ScheduleReminders(context.Background(), database.DB, cfg.ReminderHour, 10*time.Minute)
+
 	addr := fmt.Sprintf("%s:%d", cfg.Host, cfg.Port)
 	log.Printf("🚀 AgentEats API server starting on %s", addr)
 	if err := http.ListenAndServe(addr, r); err != nil {
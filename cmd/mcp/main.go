@@ -1,24 +1,49 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"log"
+	"time"
 
 	"github.com/mark3labs/mcp-go/server"
 
 	"github.com/agenteats/agenteats/internal/config"
 	"github.com/agenteats/agenteats/internal/database"
 	mcpserver "github.com/agenteats/agenteats/internal/mcpserver"
+	"github.com/agenteats/agenteats/internal/notify"
+	"github.com/agenteats/agenteats/internal/services"
+	"github.com/agenteats/agenteats/internal/webhooks"
 )
 
 func main() {
 	cfg := config.Load()
+	if err := webhooks.AllowNets(cfg.WebhookAllowNets); err != nil {
+		log.Fatalf("WEBHOOK_ALLOW_NETS: %v", err)
+	}
 	database.Init(cfg)
+	// Issue manage tokens to reservations booked before they existed.
+	if issued, sent, err := services.IssueMissingManageTokens(database.DB); err != nil {
+		log.Fatalf("failed to issue manage tokens: %v", err)
+	} else if issued > 0 {
+		log.Printf("Issued manage tokens to %d existing reservations; %d were queued for emailing to their guests", issued, sent)
+	}
 
 	s := mcpserver.NewServer()
 
 	switch cfg.MCPTransport {
 	case "http":
+		// Send queued webhook deliveries and guest notifications here too,
+		// so a standalone MCP server delivers them; claims keep
+		// dispatchers from doubling up.
+		notifier, err := notify.New(cfg)
+		if err != nil {
+			log.Fatalf("notifications: %v", err)
+		}
+		go webhooks.NewDispatcher(database.DB).Run(context.Background())
+		go notify.NewDispatcher(database.DB, notifier).Run(context.Background())
+		go services.ScheduleReminders(context.Background(), database.DB, cfg.ReminderHour, 10*time.Minute)
+
 		addr := fmt.Sprintf("%s:%d", cfg.Host, cfg.MCPPort)
 		httpServer := server.NewStreamableHTTPServer(s, server.WithStateLess(true))
 		log.Printf("🤖 AgentEats MCP server starting (Streamable HTTP on %s/mcp)", addr)
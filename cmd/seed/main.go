@@ -3,10 +3,13 @@ package main
 import (
 	"fmt"
 	"log"
+	"time"
 
 	"github.com/agenteats/agenteats/internal/config"
 	"github.com/agenteats/agenteats/internal/database"
 	"github.com/agenteats/agenteats/internal/models"
+	"github.com/agenteats/agenteats/internal/search"
+	"github.com/agenteats/agenteats/internal/services"
 )
 
 func ptr(f float64) *float64 { return &f }
@@ -29,6 +32,7 @@ type restaurantSeed struct {
 	WeekendOpen  string
 	WeekendClose string
 	ClosedDays   []string
+	Stars        []int // one sample review per entry; the rating is computed from them
 	Menu         []menuEntry
 }
 
@@ -92,10 +96,9 @@ func getSeedData() []restaurantSeed {
 				Website:     "https://bellanotte.example.com",
 				Features:    "outdoor_seating,wifi,live_music,wheelchair_accessible",
 				TotalSeats:  80,
-				Rating:      ptr(4.7),
-				ReviewCount: 342,
 			},
 			WeekdayOpen: "17:00", WeekdayClose: "23:00", WeekendOpen: "12:00", WeekendClose: "00:00",
+			Stars: []int{5, 5, 4},
 			Menu: []menuEntry{
 				{"Appetizer", "Bruschetta Trio", "Tomato basil, mushroom truffle, and nduja spread on grilled sourdough", 16.0, "vegetarian", true, nil},
 				{"Appetizer", "Burrata Caprese", "Fresh burrata with heirloom tomatoes, basil oil, and aged balsamic", 19.0, "vegetarian,gluten_free", true, nil},
@@ -135,11 +138,10 @@ func getSeedData() []restaurantSeed {
 				Website:     "https://sakurahouse.example.com",
 				Features:    "wifi,wheelchair_accessible",
 				TotalSeats:  45,
-				Rating:      ptr(4.8),
-				ReviewCount: 567,
 			},
 			WeekdayOpen: "12:00", WeekdayClose: "22:30", WeekendOpen: "12:00", WeekendClose: "23:00",
 			ClosedDays: []string{"monday"},
+			Stars:      []int{5, 5, 5, 4, 5},
 			Menu: []menuEntry{
 				{"Sushi", "Salmon Nigiri (2pc)", "Fresh Atlantic salmon over seasoned rice", 8.0, "gluten_free,raw", false, intPtr(120)},
 				{"Sushi", "Toro Nigiri (2pc)", "Fatty bluefin tuna belly", 18.0, "gluten_free,raw", true, intPtr(140)},
@@ -179,9 +181,8 @@ func getSeedData() []restaurantSeed {
 				Website:     "https://eljardin.example.com",
 				Features:    "outdoor_seating,live_music,parking,delivery,takeout,pet_friendly",
 				TotalSeats:  120,
-				Rating:      ptr(4.5),
-				ReviewCount: 891,
 			},
+			Stars: []int{5, 4, 5, 4},
 			Menu: []menuEntry{
 				{"Appetizer", "Tableside Guacamole", "Made fresh at your table with avocado, lime, cilantro, jalapeño", 15.0, "vegan,gluten_free", true, intPtr(320)},
 				{"Appetizer", "Elote", "Grilled street corn with cotija, mayo, chile, lime", 9.0, "vegetarian,gluten_free", true, intPtr(280)},
@@ -220,10 +221,9 @@ func getSeedData() []restaurantSeed {
 				Website:     "https://thegreenplate.example.com",
 				Features:    "outdoor_seating,wifi,delivery,takeout,wheelchair_accessible,pet_friendly",
 				TotalSeats:  60,
-				Rating:      ptr(4.6),
-				ReviewCount: 445,
 			},
 			WeekdayOpen: "08:00", WeekdayClose: "21:00", WeekendOpen: "08:00", WeekendClose: "22:00",
+			Stars: []int{5, 5, 4, 5, 4},
 			Menu: []menuEntry{
 				{"Breakfast", "Açaí Power Bowl", "Açaí, banana, granola, coconut, chia seeds, local berries", 16.0, "vegan,gluten_free,organic", true, intPtr(380)},
 				{"Breakfast", "Avocado Toast", "Sourdough, smashed avocado, everything seasoning, microgreens, hemp seeds", 14.0, "vegan", true, intPtr(320)},
@@ -261,11 +261,10 @@ func getSeedData() []restaurantSeed {
 				Website:     "https://maisonlaurent.example.com",
 				Features:    "wifi,wheelchair_accessible,parking",
 				TotalSeats:  40,
-				Rating:      ptr(4.9),
-				ReviewCount: 218,
 			},
 			WeekdayOpen: "17:30", WeekdayClose: "22:00", WeekendOpen: "17:00", WeekendClose: "22:30",
 			ClosedDays: []string{"monday", "tuesday"},
+			Stars:      []int{5, 5, 5, 5, 5, 5, 4},
 			Menu: []menuEntry{
 				{"Amuse-Bouche", "Foie Gras Bonbon", "Seared foie gras in a dark chocolate shell with fleur de sel", 0.0, "", false, intPtr(180)},
 				{"Appetizer", "Tartare de Boeuf", "Hand-cut beef tartare, quail egg yolk, cornichon, dijon", 28.0, "gluten_free,raw", true, intPtr(280)},
@@ -302,9 +301,8 @@ func getSeedData() []restaurantSeed {
 				Website:     "https://spiceroute.example.com",
 				Features:    "delivery,takeout,wifi,wheelchair_accessible,parking",
 				TotalSeats:  90,
-				Rating:      ptr(4.4),
-				ReviewCount: 623,
 			},
+			Stars: []int{5, 4, 4, 5, 4},
 			Menu: []menuEntry{
 				{"Appetizer", "Samosa (2pc)", "Crispy pastry filled with spiced potatoes and peas, tamarind chutney", 8.0, "vegetarian,vegan", true, intPtr(320)},
 				{"Appetizer", "Chicken Tikka", "Tandoor-roasted chicken marinated in yogurt and spices", 14.0, "gluten_free", true, intPtr(280)},
@@ -343,10 +341,9 @@ func getSeedData() []restaurantSeed {
 				Website:     "https://burgerbarrel.example.com",
 				Features:    "outdoor_seating,wifi,delivery,takeout,parking,pet_friendly",
 				TotalSeats:  100,
-				Rating:      ptr(4.3),
-				ReviewCount: 1205,
 			},
 			WeekdayOpen: "11:00", WeekdayClose: "23:00", WeekendOpen: "10:00", WeekendClose: "00:00",
+			Stars: []int{4, 5, 4, 4, 5, 4},
 			Menu: []menuEntry{
 				{"Burger", "Classic Smash Burger", "Double grass-fed beef patties, American cheese, lettuce, tomato, pickles, special sauce", 14.0, "", true, intPtr(780)},
 				{"Burger", "BBQ Bacon Burger", "Beef patty, smoked bacon, cheddar, crispy onion rings, BBQ sauce", 16.0, "", true, intPtr(920)},
@@ -384,10 +381,9 @@ func getSeedData() []restaurantSeed {
 				Website:     "https://jadepalace.example.com",
 				Features:    "wifi,wheelchair_accessible,parking,takeout,delivery",
 				TotalSeats:  150,
-				Rating:      ptr(4.5),
-				ReviewCount: 734,
 			},
 			WeekdayOpen: "10:30", WeekdayClose: "22:00", WeekendOpen: "09:00", WeekendClose: "22:30",
+			Stars: []int{4, 5, 5, 4},
 			Menu: []menuEntry{
 				{"Dim Sum", "Har Gow (4pc)", "Crystal shrimp dumplings", 8.0, "gluten_free", true, intPtr(160)},
 				{"Dim Sum", "Siu Mai (4pc)", "Pork and shrimp dumplings", 8.0, "", true, intPtr(200)},
@@ -410,6 +406,57 @@ func getSeedData() []restaurantSeed {
 	}
 }
 
+var reviewers = []string{"Dana Whitfield", "Omar Haddad", "Priya Nair", "Tom Becker", "Lucia Ferreira", "Sam O'Neill", "Grace Kim"}
+
+var reviewComments = map[int][]string{
+	5: {
+		"Wonderful evening from start to finish. We'll be back.",
+		"Everything we ordered was excellent and the staff made us feel at home.",
+		"Best meal we've had in months. Book ahead on weekends.",
+	},
+	4: {
+		"Great food, though the service was a little slow when it got busy.",
+		"Lovely atmosphere and solid dishes. Desserts could be better.",
+	},
+}
+
+// seedReview adds the n-th completed sample visit to a restaurant, with a
+// published review of the given stars.
+func seedReview(restaurantID string, n, stars int) {
+	name := reviewers[n%len(reviewers)]
+	completed := time.Date(2026, time.January, 5+3*n, 21, 30, 0, 0, time.UTC)
+	_, tokenHash := models.GenerateManageToken()
+	res := models.Reservation{
+		ID:              models.NewID(),
+		RestaurantID:    restaurantID,
+		CustomerName:    name,
+		PartySize:       2,
+		Date:            completed.Format("2006-01-02"),
+		Time:            "19:30",
+		Status:          models.StatusCompleted,
+		ManageTokenHash: tokenHash,
+		CompletedAt:     &completed,
+	}
+	if err := database.DB.Create(&res).Error; err != nil {
+		log.Fatalf("Failed to create sample visit: %v", err)
+	}
+	comments := reviewComments[stars]
+	review := models.Review{
+		ID:            models.NewID(),
+		RestaurantID:  restaurantID,
+		ReservationID: res.ID,
+		AuthorName:    models.ReviewAuthorName(name),
+		Rating:        stars,
+		Comment:       comments[n%len(comments)],
+		VisitDate:     res.Date,
+		Status:        models.ReviewPublished,
+		CreatedAt:     completed.Add(24 * time.Hour),
+	}
+	if err := database.DB.Create(&review).Error; err != nil {
+		log.Fatalf("Failed to create sample review: %v", err)
+	}
+}
+
 func main() {
 	cfg := config.Load()
 	database.Init(cfg)
@@ -460,6 +507,7 @@ func main() {
 		r := entry.Info
 		r.ID = models.NewID()
 		r.IsActive = true
+		r.Timezone = models.DefaultTimezone(r.Country, r.State)
 
 		// Assign an owner (cycle through demo owners)
 		r.OwnerID = ownerIDs[i%len(ownerIDs)]
@@ -492,7 +540,15 @@ func main() {
 			database.DB.Create(&item)
 		}
 
-		fmt.Printf("  ✓ %s (%s) — %d menu items\n", r.Name, r.City, len(entry.Menu))
+		// Past visits with reviews; the rating is computed from them
+		for j, stars := range entry.Stars {
+			seedReview(r.ID, j, stars)
+		}
+		if err := services.RecomputeRating(database.DB, r.ID); err != nil {
+			log.Fatalf("Failed to compute rating for %s: %v", r.Name, err)
+		}
+
+		fmt.Printf("  ✓ %s (%s) — %d menu items, %d reviews\n", r.Name, r.City, len(entry.Menu), len(entry.Stars))
 	}
 
 	// Sample reservations
@@ -505,8 +561,20 @@ func main() {
 		{ID: models.NewID(), RestaurantID: restaurants[2].ID, CustomerName: "Carol Davis", CustomerEmail: "carol@example.com", PartySize: 6, Date: "2026-02-21", Time: "19:30", Status: models.StatusConfirmed, SpecialRequests: "Birthday celebration — can you do a cake?"},
 	}
 
+	fmt.Println("\n🎫 Sample reservation manage tokens (needed to cancel as a guest):")
 	for _, res := range sampleReservations {
+		rawToken, tokenHash := models.GenerateManageToken()
+		res.ManageTokenHash = tokenHash
 		database.DB.Create(&res)
+		fmt.Printf("  %-20s %s  %s\n", res.CustomerName, res.ID, rawToken)
+	}
+
+	// Link the fixtures' cuisines, features and dietary labels to the taxonomy
+	database.BackfillTaxonomy()
+
+	// Index the new restaurants and menus for free-text search
+	if err := search.Migrate(database.DB); err != nil {
+		log.Fatalf("Failed to index restaurants for search: %v", err)
 	}
 
 	fmt.Printf("\n✅ Seeded %d restaurants with menus and sample reservations.\n", len(seedData))
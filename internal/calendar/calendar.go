@@ -0,0 +1,198 @@
+// Package calendar writes iCalendar (RFC 5545) files. Events are given in
+// a single time zone, which is embedded as a VTIMEZONE so calendar apps
+// show them at the restaurant's local time wherever the reader is.
+package calendar
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Event is a VEVENT. Start and End are converted to the calendar's
+// Location.
+type Event struct {
+	UID         string // stable across updates, e.g. "<reservation id>@agenteats"
+	Sequence    int    // incremented each time the event changes
+	Summary     string
+	Description string
+	Location    string
+	URL         string
+	Start, End  time.Time
+	Cancelled   bool
+	Created     time.Time
+}
+
+// Calendar is a VCALENDAR of events in one time zone.
+type Calendar struct {
+	Name     string // shown by apps that subscribe to a feed
+	Location *time.Location
+	Events   []Event
+}
+
+const (
+	prodID      = "-//AgentEats//AgentEats//EN"
+	localLayout = "20060102T150405"
+	utcLayout   = "20060102T150405Z"
+)
+
+// Bytes renders the calendar with CRLF line endings and long lines folded.
+func (c *Calendar) Bytes() []byte {
+	loc := c.Location
+	if loc == nil {
+		loc = time.UTC
+	}
+	w := &writer{}
+	w.line("BEGIN:VCALENDAR")
+	w.line("VERSION:2.0")
+	w.line("PRODID:" + prodID)
+	w.line("CALSCALE:GREGORIAN")
+	w.line("METHOD:PUBLISH")
+	if c.Name != "" {
+		w.line("X-WR-CALNAME:" + escape(c.Name))
+	}
+	w.line("X-WR-TIMEZONE:" + loc.String())
+
+	if len(c.Events) > 0 {
+		from, to := c.Events[0].Start, c.Events[0].End
+		for _, e := range c.Events[1:] {
+			if e.Start.Before(from) {
+				from = e.Start
+			}
+			if e.End.After(to) {
+				to = e.End
+			}
+		}
+		writeTimezone(w, loc, from, to)
+	}
+
+	stamp := time.Now().UTC().Format(utcLayout)
+	for _, e := range c.Events {
+		w.line("BEGIN:VEVENT")
+		w.line("UID:" + escape(e.UID))
+		w.line("DTSTAMP:" + stamp)
+		if !e.Created.IsZero() {
+			w.line("CREATED:" + e.Created.UTC().Format(utcLayout))
+		}
+		w.line("SEQUENCE:" + fmt.Sprint(e.Sequence))
+		w.line("DTSTART;TZID=" + loc.String() + ":" + e.Start.In(loc).Format(localLayout))
+		w.line("DTEND;TZID=" + loc.String() + ":" + e.End.In(loc).Format(localLayout))
+		w.line("SUMMARY:" + escape(e.Summary))
+		if e.Description != "" {
+			w.line("DESCRIPTION:" + escape(e.Description))
+		}
+		if e.Location != "" {
+			w.line("LOCATION:" + escape(e.Location))
+		}
+		if e.URL != "" {
+			w.line("URL:" + e.URL)
+		}
+		if e.Cancelled {
+			w.line("STATUS:CANCELLED")
+		} else {
+			w.line("STATUS:CONFIRMED")
+		}
+		w.line("TRANSP:OPAQUE")
+		w.line("END:VEVENT")
+	}
+	w.line("END:VCALENDAR")
+	return []byte(w.String())
+}
+
+// writeTimezone writes a VTIMEZONE for loc covering from to to: the offset
+// in effect at from and every transition up to to. Transitions are listed
+// one by one rather than as recurrence rules, which Go's zone data doesn't
+// provide.
+func writeTimezone(w *writer, loc *time.Location, from, to time.Time) {
+	start := from.In(loc).AddDate(0, 0, -1)
+	w.line("BEGIN:VTIMEZONE")
+	w.line("TZID:" + loc.String())
+
+	name, offset := start.Zone()
+	observance(w, start.IsDST(), name, offset, offset, start)
+	for t := start; t.Before(to); {
+		next, ok := nextTransition(loc, t, to)
+		if !ok {
+			break
+		}
+		newName, newOffset := next.Zone()
+		// DTSTART is the local time of the onset under the old offset.
+		observance(w, next.IsDST(), newName, offset, newOffset, next.In(time.FixedZone("", offset)))
+		offset = newOffset
+		t = next
+	}
+	w.line("END:VTIMEZONE")
+}
+
+func observance(w *writer, dst bool, name string, from, to int, onset time.Time) {
+	kind := "STANDARD"
+	if dst {
+		kind = "DAYLIGHT"
+	}
+	w.line("BEGIN:" + kind)
+	w.line("DTSTART:" + onset.Format(localLayout))
+	w.line("TZOFFSETFROM:" + formatOffset(from))
+	w.line("TZOFFSETTO:" + formatOffset(to))
+	if name != "" && !strings.HasPrefix(name, "+") && !strings.HasPrefix(name, "-") {
+		w.line("TZNAME:" + name)
+	}
+	w.line("END:" + kind)
+}
+
+// nextTransition finds the first instant after t, and no later than
+// limit, at which loc's UTC offset changes.
+func nextTransition(loc *time.Location, t, limit time.Time) (time.Time, bool) {
+	_, offset := t.In(loc).Zone()
+	for day := t; !day.After(limit.Add(24 * time.Hour)); day = day.Add(24 * time.Hour) {
+		next := day.Add(24 * time.Hour)
+		if _, o := next.In(loc).Zone(); o == offset {
+			continue
+		}
+		// The change is within (day, next]; narrow it to the second.
+		lo, hi := day, next
+		for hi.Sub(lo) > time.Second {
+			mid := lo.Add(hi.Sub(lo) / 2)
+			if _, o := mid.In(loc).Zone(); o == offset {
+				lo = mid
+			} else {
+				hi = mid
+			}
+		}
+		return hi.Truncate(time.Second).In(loc), true
+	}
+	return time.Time{}, false
+}
+
+func formatOffset(seconds int) string {
+	sign := "+"
+	if seconds < 0 {
+		sign = "-"
+		seconds = -seconds
+	}
+	return fmt.Sprintf("%s%02d%02d", sign, seconds/3600, seconds/60%60)
+}
+
+// escape escapes a TEXT value.
+func escape(s string) string {
+	return strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`, "\r", `\n`).Replace(s)
+}
+
+// writer accumulates content lines, folding them at 75 octets without
+// splitting UTF-8 sequences.
+type writer struct {
+	strings.Builder
+}
+
+func (w *writer) line(s string) {
+	limit := 75
+	for len(s) > limit {
+		cut := limit
+		for cut > 0 && s[cut]&0xC0 == 0x80 {
+			cut--
+		}
+		w.WriteString(s[:cut] + "\r\n ")
+		s = s[cut:]
+		limit = 74 // continuation lines start with a space
+	}
+	w.WriteString(s + "\r\n")
+}
@@ -8,13 +8,27 @@ import (
 
 // Config holds all application configuration, loaded from environment variables.
 type Config struct {
-	Host         string `envconfig:"HOST" default:"0.0.0.0"`
-	Port         int    `envconfig:"PORT" default:"8000"`
-	DatabaseURL  string `envconfig:"DATABASE_URL" default:"agenteats.db"`
-	Debug        bool   `envconfig:"DEBUG" default:"false"`
-	MCPTransport string `envconfig:"MCP_TRANSPORT" default:"stdio"` // "stdio" or "http"
-	MCPPort      int    `envconfig:"MCP_PORT" default:"8001"`
-	CORSOrigins  string `envconfig:"CORS_ORIGINS" default:"*"` // comma-separated allowed origins
+	Host               string `envconfig:"HOST" default:"0.0.0.0"`
+	Port               int    `envconfig:"PORT" default:"8000"`
+	DatabaseURL        string `envconfig:"DATABASE_URL" default:"agenteats.db"`
+	Debug              bool   `envconfig:"DEBUG" default:"false"`
+	MCPTransport       string `envconfig:"MCP_TRANSPORT" default:"stdio"` // "stdio" or "http"
+	MCPPort            int    `envconfig:"MCP_PORT" default:"8001"`
+	CORSOrigins        string `envconfig:"CORS_ORIGINS" default:"*"`             // comma-separated allowed origins
+	TrustForwardedHost bool   `envconfig:"TRUST_FORWARDED_HOST" default:"false"` // use X-Forwarded-Host in returned links; only behind a proxy that sets it
+
+	// Guest notifications
+	Notifier      string `envconfig:"NOTIFIER" default:"log"` // "log" or "smtp"
+	NotifyLogFile string `envconfig:"NOTIFY_LOG_FILE"`        // log notifier output; empty for stderr
+	SMTPHost      string `envconfig:"SMTP_HOST" default:"localhost"`
+	SMTPPort      int    `envconfig:"SMTP_PORT" default:"587"`
+	SMTPUsername  string `envconfig:"SMTP_USERNAME"`
+	SMTPPassword  string `envconfig:"SMTP_PASSWORD"`
+	SMTPFrom      string `envconfig:"SMTP_FROM" default:"AgentEats <no-reply@agenteats.dev>"`
+	ReminderHour  int    `envconfig:"REMINDER_HOUR" default:"10"` // local hour from which day-before reminders go out
+
+	// Webhooks
+	WebhookAllowNets string `envconfig:"WEBHOOK_ALLOW_NETS"` // comma-separated private CIDRs webhooks may reach, e.g. 127.0.0.0/8 for local testing
 }
 
 // Load reads configuration from environment variables.
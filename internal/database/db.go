@@ -11,6 +11,7 @@ import (
 
 	"github.com/agenteats/agenteats/internal/config"
 	"github.com/agenteats/agenteats/internal/models"
+	"github.com/agenteats/agenteats/internal/search"
 )
 
 // DB is the global database connection.
@@ -37,7 +38,7 @@ func Init(cfg *config.Config) {
 		DB, err = gorm.Open(postgres.Open(dsn), gormCfg)
 		log.Println("Using PostgreSQL database")
 	} else {
-		DB, err = gorm.Open(sqlite.Open(dsn), gormCfg)
+		DB, err = gorm.Open(sqlite.Open(sqliteDSN(dsn)), gormCfg)
 		log.Println("Using SQLite database:", dsn)
 	}
 	if err != nil {
@@ -47,13 +48,94 @@ func Init(cfg *config.Config) {
 	// Auto-migrate all models
 	if err := DB.AutoMigrate(
 		&models.Owner{},
+		&models.TaxonomyTerm{},
+		&models.TaxonomySynonym{},
 		&models.Restaurant{},
 		&models.OperatingHours{},
+		&models.HoursOverride{},
+		&models.TurnTime{},
+		&models.DiningTable{},
 		&models.MenuItem{},
+		&models.MenuCategory{},
+		&models.MenuOptionGroup{},
+		&models.MenuOption{},
 		&models.Reservation{},
+		&models.ReservationChange{},
+		&models.ReservationStatusChange{},
+		&models.WaitlistEntry{},
+		&models.Review{},
+		&models.WebhookSubscription{},
+		&models.WebhookDelivery{},
+		&models.NotificationTemplate{},
+		&models.Notification{},
 	); err != nil {
 		log.Fatalf("failed to migrate database: %v", err)
 	}
 
+	backfillTimezones()
+	backfillAllergenDeclarations()
+	seedTaxonomy()
+	BackfillTaxonomy()
+
+	if err := search.Migrate(DB); err != nil {
+		log.Fatalf("failed to set up search index: %v", err)
+	}
+
 	log.Println("Database initialized")
 }
+
+// backfillAllergenDeclarations marks menu items saved with allergens before
+// items recorded whether their allergens were declared. Items with empty
+// lists stay undeclared, since those lists can't be told from unknown ones.
+func backfillAllergenDeclarations() {
+	result := DB.Model(&models.MenuItem{}).
+		Where("allergens_declared = ? AND (allergens <> '' OR may_contain_allergens <> '')", false).
+		Update("allergens_declared", true)
+	if result.Error != nil {
+		log.Fatalf("failed to backfill allergen declarations: %v", result.Error)
+	}
+	if result.RowsAffected > 0 {
+		log.Printf("Marked allergens as declared on %d existing menu items", result.RowsAffected)
+	}
+}
+
+// backfillTimezones assigns a time zone to restaurants created before
+// restaurants had one, guessed from their country and state.
+func backfillTimezones() {
+	var restaurants []models.Restaurant
+	if err := DB.Select("id", "country", "state").Where("timezone IS NULL OR timezone = ''").
+		Find(&restaurants).Error; err != nil {
+		log.Fatalf("failed to load restaurants for timezone backfill: %v", err)
+	}
+	for _, r := range restaurants {
+		tz := models.DefaultTimezone(r.Country, r.State)
+		if err := DB.Model(&models.Restaurant{}).Where("id = ?", r.ID).
+			Update("timezone", tz).Error; err != nil {
+			log.Fatalf("failed to backfill timezone: %v", err)
+		}
+	}
+	if len(restaurants) > 0 {
+		log.Printf("Assigned time zones to %d existing restaurants", len(restaurants))
+	}
+}
+
+// sqliteDSN adds the connection options several processes need to share one
+// SQLite file, as the API and the stdio MCP server do. Transactions begin
+// IMMEDIATE, taking the write lock up front, so two bookings in different
+// processes can't both pass a capacity check; and a writer waits up to five
+// seconds for the lock rather than failing at once with SQLITE_BUSY. Options
+// already in dsn are kept.
+func sqliteDSN(dsn string) string {
+	for _, opt := range []string{"_busy_timeout=5000", "_txlock=immediate"} {
+		name, _, _ := strings.Cut(opt, "=")
+		if strings.Contains(dsn, name+"=") {
+			continue
+		}
+		sep := "?"
+		if strings.Contains(dsn, "?") {
+			sep = "&"
+		}
+		dsn += sep + opt
+	}
+	return dsn
+}
@@ -0,0 +1,167 @@
+package database
+
+import (
+	"log"
+	"strings"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/agenteats/agenteats/internal/models"
+)
+
+// seedTaxonomy installs any models.DefaultTaxonomy terms and synonyms the
+// database does not have yet. Existing terms are left alone, so labels
+// edited in the database survive restarts.
+func seedTaxonomy() {
+	for _, d := range models.DefaultTaxonomy {
+		term := models.TaxonomyTerm{Kind: d.Kind, Slug: d.Slug(), Label: d.Label}
+		if err := DB.Where("kind = ? AND slug = ?", term.Kind, term.Slug).
+			FirstOrCreate(&term).Error; err != nil {
+			log.Fatalf("failed to seed taxonomy term %q: %v", d.Label, err)
+		}
+		keys := append([]string{d.Label}, d.Synonyms...)
+		if err := addSynonyms(DB, &term, keys); err != nil {
+			log.Fatalf("failed to seed synonyms for %q: %v", d.Label, err)
+		}
+	}
+}
+
+// addSynonyms maps each value's key to term, skipping keys the kind
+// already uses.
+func addSynonyms(db *gorm.DB, term *models.TaxonomyTerm, values []string) error {
+	for _, v := range values {
+		key := models.TaxonomyKey(v)
+		if key == "" || key == term.Slug {
+			continue
+		}
+		syn := models.TaxonomySynonym{TermID: term.ID, Kind: term.Kind, Key: key}
+		if err := db.Clauses(clause.OnConflict{DoNothing: true}).Create(&syn).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// BackfillTaxonomy links restaurants and menu items that only have the
+// comma-separated Cuisines, Features or DietaryLabels columns to taxonomy
+// terms, and rewrites those columns in canonical form. Unknown cuisines
+// become new terms. Unknown features and dietary labels are logged and
+// linked to that vocabulary's "other" term, and a column holding any of
+// them is kept as it was so no owner data is lost. Each row is marked
+// TaxonomyBackfilled as it is linked, so later runs skip it. Init runs it
+// on startup; the seed command runs it again after inserting its fixtures.
+func BackfillTaxonomy() {
+	var terms []models.TaxonomyTerm
+	if err := DB.Preload("Synonyms").Find(&terms).Error; err != nil {
+		log.Fatalf("failed to load taxonomy: %v", err)
+	}
+	idx := models.NewTaxonomyIndex(terms)
+
+	var restaurants []models.Restaurant
+	if err := DB.Select("id", "cuisines", "features").
+		Where("taxonomy_backfilled = ?", false).
+		Where("(cuisines <> '' AND NOT EXISTS (SELECT 1 FROM restaurant_cuisines rc WHERE rc.restaurant_id = restaurants.id))" +
+			" OR (features <> '' AND NOT EXISTS (SELECT 1 FROM restaurant_features rf WHERE rf.restaurant_id = restaurants.id))").
+		Find(&restaurants).Error; err != nil {
+		log.Fatalf("failed to load restaurants for taxonomy backfill: %v", err)
+	}
+	for _, r := range restaurants {
+		cuisines, _ := backfillTerms(idx, models.KindCuisine, r.Cuisines, "restaurant "+r.ID)
+		features, unknown := backfillTerms(idx, models.KindFeature, r.Features, "restaurant "+r.ID)
+		columns := map[string]any{"cuisines": models.TermsCSV(cuisines), "taxonomy_backfilled": true}
+		if !unknown {
+			columns["features"] = models.TermsCSV(features)
+		}
+		err := DB.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Model(&r).Association("CuisineTerms").Replace(cuisines); err != nil {
+				return err
+			}
+			if err := tx.Model(&r).Association("FeatureTerms").Replace(features); err != nil {
+				return err
+			}
+			return tx.Model(&models.Restaurant{}).Where("id = ?", r.ID).UpdateColumns(columns).Error
+		})
+		if err != nil {
+			log.Fatalf("failed to backfill taxonomy for restaurant %s: %v", r.ID, err)
+		}
+	}
+
+	var items []models.MenuItem
+	if err := DB.Select("id", "dietary_labels").
+		Where("taxonomy_backfilled = ? AND dietary_labels <> ''", false).
+		Where("NOT EXISTS (SELECT 1 FROM menu_item_dietary_labels md WHERE md.menu_item_id = menu_items.id)").
+		Find(&items).Error; err != nil {
+		log.Fatalf("failed to load menu items for taxonomy backfill: %v", err)
+	}
+	for _, m := range items {
+		dietary, unknown := backfillTerms(idx, models.KindDietary, m.DietaryLabels, "menu item "+m.ID)
+		err := DB.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Model(&m).Association("DietaryTerms").Replace(dietary); err != nil {
+				return err
+			}
+			columns := map[string]any{"taxonomy_backfilled": true}
+			if !unknown {
+				columns["dietary_labels"] = models.TermsCSV(dietary)
+			}
+			return tx.Model(&models.MenuItem{}).Where("id = ?", m.ID).UpdateColumns(columns).Error
+		})
+		if err != nil {
+			log.Fatalf("failed to backfill taxonomy for menu item %s: %v", m.ID, err)
+		}
+	}
+
+	if len(restaurants)+len(items) > 0 {
+		log.Printf("Linked %d restaurants and %d menu items to the taxonomy", len(restaurants), len(items))
+	}
+}
+
+// backfillTerms resolves a comma-separated column to terms, creating
+// cuisines that are not in the vocabulary yet. unknown reports whether any
+// other value wasn't in the vocabulary; each one is logged and resolves to
+// the kind's "other" term instead.
+func backfillTerms(idx *models.TaxonomyIndex, kind models.TaxonomyKind, csv, owner string) (out []models.TaxonomyTerm, unknown bool) {
+	seen := make(map[uint]bool)
+	for _, v := range strings.Split(csv, ",") {
+		v = strings.TrimSpace(v)
+		if models.TaxonomyKey(v) == "" {
+			continue
+		}
+		term := idx.Lookup(kind, v)
+		if term == nil && kind == models.KindCuisine {
+			term = &models.TaxonomyTerm{Kind: kind, Slug: models.TaxonomyKey(v), Label: v}
+			if err := DB.Create(term).Error; err != nil {
+				log.Fatalf("failed to add cuisine %q: %v", v, err)
+			}
+			if err := addSynonyms(DB, term, []string{v}); err != nil {
+				log.Fatalf("failed to add cuisine %q: %v", v, err)
+			}
+			idx.Add(term)
+			log.Printf("Added cuisine %q to the taxonomy", v)
+		}
+		if term == nil {
+			term = otherTerm(idx, kind)
+			log.Printf("Unknown %s %q on %s was linked to %q; keeping the original value", kind, v, owner, term.Slug)
+			unknown = true
+		}
+		if !seen[term.ID] {
+			seen[term.ID] = true
+			out = append(out, term.Bare())
+		}
+	}
+	return out, unknown
+}
+
+// otherTerm returns the kind's "other" term, adding it to the database and
+// idx the first time a backfill needs it.
+func otherTerm(idx *models.TaxonomyIndex, kind models.TaxonomyKind) *models.TaxonomyTerm {
+	if term := idx.Lookup(kind, "other"); term != nil {
+		return term
+	}
+	term := &models.TaxonomyTerm{Kind: kind, Slug: "other", Label: "Other"}
+	if err := DB.Where("kind = ? AND slug = ?", kind, term.Slug).FirstOrCreate(term).Error; err != nil {
+		log.Fatalf("failed to add %s %q: %v", kind, term.Slug, err)
+	}
+	idx.Add(term)
+	return term
+}
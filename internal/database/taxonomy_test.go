@@ -0,0 +1,57 @@
+package database
+
+import (
+	"bytes"
+	"log"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/agenteats/agenteats/internal/config"
+	"github.com/agenteats/agenteats/internal/models"
+)
+
+func TestBackfillTaxonomyLinksUnknownsToOtherOnce(t *testing.T) {
+	Init(&config.Config{DatabaseURL: filepath.Join(t.TempDir(), "test.db")})
+
+	r := models.Restaurant{ID: models.NewID(), Name: "Legacy", Address: "1 Main St", City: "Boston", Features: "moat"}
+	if err := DB.Create(&r).Error; err != nil {
+		t.Fatal(err)
+	}
+	m := models.MenuItem{ID: models.NewID(), RestaurantID: r.ID, Name: "Stew", Price: 12, DietaryLabels: "carnivore"}
+	if err := DB.Create(&m).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	BackfillTaxonomy()
+
+	var got models.Restaurant
+	if err := DB.Preload("FeatureTerms").First(&got, "id = ?", r.ID).Error; err != nil {
+		t.Fatal(err)
+	}
+	if len(got.FeatureTerms) != 1 || got.FeatureTerms[0].Slug != "other" {
+		t.Errorf("feature terms = %+v, want [other]", got.FeatureTerms)
+	}
+	if got.Features != "moat" || !got.TaxonomyBackfilled {
+		t.Errorf("features = %q, backfilled = %v; want the original value, marked", got.Features, got.TaxonomyBackfilled)
+	}
+	var item models.MenuItem
+	if err := DB.Preload("DietaryTerms").First(&item, "id = ?", m.ID).Error; err != nil {
+		t.Fatal(err)
+	}
+	if len(item.DietaryTerms) != 1 || item.DietaryTerms[0].Slug != "other" {
+		t.Errorf("dietary terms = %+v, want [other]", item.DietaryTerms)
+	}
+	if item.DietaryLabels != "carnivore" || !item.TaxonomyBackfilled {
+		t.Errorf("dietary labels = %q, backfilled = %v; want the original value, marked", item.DietaryLabels, item.TaxonomyBackfilled)
+	}
+
+	// A second run finds nothing left to do, so it logs nothing.
+	var buf bytes.Buffer
+	log.SetOutput(&buf)
+	t.Cleanup(func() { log.SetOutput(os.Stderr) })
+	BackfillTaxonomy()
+	if buf.Len() > 0 {
+		t.Errorf("second backfill logged %q", buf.String())
+	}
+}
@@ -1,26 +1,32 @@
 package dto
 
+import "encoding/json"
+
 // --- Request DTOs ---
 
 // RestaurantIn is the payload for creating/updating a restaurant.
 type RestaurantIn struct {
-	Name        string           `json:"name"`
-	Description string           `json:"description,omitempty"`
-	Cuisines    []string         `json:"cuisines"`
-	PriceRange  string           `json:"price_range"`
-	Address     string           `json:"address"`
-	City        string           `json:"city"`
-	State       string           `json:"state,omitempty"`
-	ZipCode     string           `json:"zip_code,omitempty"`
-	Country     string           `json:"country"`
-	Latitude    *float64         `json:"latitude,omitempty"`
-	Longitude   *float64         `json:"longitude,omitempty"`
-	Phone       string           `json:"phone,omitempty"`
-	Email       string           `json:"email,omitempty"`
-	Website     string           `json:"website,omitempty"`
-	Features    []string         `json:"features"`
-	TotalSeats  int              `json:"total_seats"`
-	Hours       []OperatingHoursIn `json:"hours"`
+	Name               string             `json:"name"`
+	Description        string             `json:"description,omitempty"`
+	Cuisines           []string           `json:"cuisines"`
+	PriceRange         string             `json:"price_range"`
+	Address            string             `json:"address"`
+	City               string             `json:"city"`
+	State              string             `json:"state,omitempty"`
+	ZipCode            string             `json:"zip_code,omitempty"`
+	Country            string             `json:"country"`
+	Timezone           string             `json:"timezone,omitempty"` // IANA name; derived from country/state if empty
+	Latitude           *float64           `json:"latitude,omitempty"`
+	Longitude          *float64           `json:"longitude,omitempty"`
+	Phone              string             `json:"phone,omitempty"`
+	Email              string             `json:"email,omitempty"`
+	Website            string             `json:"website,omitempty"`
+	Features           []string           `json:"features"`
+	TotalSeats         int                `json:"total_seats"`
+	LastSeatingMinutes int                `json:"last_seating_minutes,omitempty"` // default 30
+	TurnMinutes        int                `json:"turn_minutes,omitempty"`         // default 90
+	TurnTimes          []TurnTimeIn       `json:"turn_times,omitempty"`
+	Hours              []OperatingHoursIn `json:"hours"`
 }
 
 type OperatingHoursIn struct {
@@ -30,17 +36,112 @@ type OperatingHoursIn struct {
 	IsClosed  bool   `json:"is_closed"`
 }
 
+// TurnTimeIn sets the dining duration for a band of party sizes.
+// Omit max_party_size (or set 0) for an open-ended band, e.g. "7 and up".
+type TurnTimeIn struct {
+	MinPartySize int `json:"min_party_size"`
+	MaxPartySize int `json:"max_party_size,omitempty"`
+	Minutes      int `json:"minutes"`
+}
+
 type MenuItemIn struct {
+	ExternalID    string   `json:"external_id,omitempty"` // your own ID for the item, e.g. a POS SKU
 	Category      string   `json:"category"`
 	Name          string   `json:"name"`
 	Description   string   `json:"description,omitempty"`
 	Price         float64  `json:"price"`
 	Currency      string   `json:"currency"`
 	DietaryLabels []string `json:"dietary_labels"`
-	IsAvailable   bool     `json:"is_available"`
+	IsAvailable   *bool    `json:"is_available,omitempty"` // omit to keep the current value; new items are available
 	IsPopular     bool     `json:"is_popular"`
 	ImageURL      string   `json:"image_url,omitempty"`
 	Calories      *int     `json:"calories,omitempty"`
+	SortOrder     *int     `json:"sort_order,omitempty"` // position in its category; omit to add at the end
+
+	// Allergens the dish contains and may contain traces of. Sending either
+	// list, even empty, declares the dish's allergens; leaving both out (or
+	// null) means they're unknown.
+	Allergens           []string `json:"allergens"`
+	MayContainAllergens []string `json:"may_contain_allergens"`
+
+	OptionGroups []MenuOptionGroupIn `json:"option_groups,omitempty"` // sizes, add-ons and other choices
+}
+
+// MenuOptionGroupIn is a choice a guest makes when ordering an item, such as
+// its size or toppings. Required groups need at least one selection;
+// MaxSelections 0 means no limit, so use 1 for pick-one groups like sizes.
+type MenuOptionGroupIn struct {
+	Name          string         `json:"name"`
+	Required      bool           `json:"required"`
+	MinSelections int            `json:"min_selections,omitempty"`
+	MaxSelections int            `json:"max_selections,omitempty"`
+	Options       []MenuOptionIn `json:"options"`
+}
+
+// MenuOptionIn is one choice in an option group. PriceDelta is added to the
+// item's price when it is chosen and may be negative.
+type MenuOptionIn struct {
+	Name          string   `json:"name"`
+	PriceDelta    float64  `json:"price_delta"`
+	DietaryLabels []string `json:"dietary_labels,omitempty"`
+	Allergens     []string `json:"allergens,omitempty"` // allergens choosing it adds to the dish
+}
+
+// MenuItemPatchIn changes some of a menu item's fields. Omitted fields keep
+// their current value.
+type MenuItemPatchIn struct {
+	ExternalID    *string   `json:"external_id,omitempty"`
+	Category      *string   `json:"category,omitempty"`
+	Name          *string   `json:"name,omitempty"`
+	Description   *string   `json:"description,omitempty"`
+	Price         *float64  `json:"price,omitempty"`
+	Currency      *string   `json:"currency,omitempty"`
+	DietaryLabels *[]string `json:"dietary_labels,omitempty"`
+	IsAvailable   *bool     `json:"is_available,omitempty"`
+	IsPopular     *bool     `json:"is_popular,omitempty"`
+	ImageURL      *string   `json:"image_url,omitempty"`
+	Calories      *int      `json:"calories,omitempty"`
+	SortOrder     *int      `json:"sort_order,omitempty"`
+
+	Allergens           *[]string `json:"allergens,omitempty"`
+	MayContainAllergens *[]string `json:"may_contain_allergens,omitempty"`
+
+	OptionGroups *[]MenuOptionGroupIn `json:"option_groups,omitempty"` // replaces all of the item's groups; [] removes them
+}
+
+// MenuAvailabilityIn marks a menu item available or sold out.
+type MenuAvailabilityIn struct {
+	IsAvailable *bool `json:"is_available"`
+}
+
+// MenuOrderIn sets the display order of a menu. Categories lists category
+// names first to last; Items lists item IDs, and each item takes its
+// position among the listed items of its category. Unlisted categories and
+// items keep their relative order after the listed ones.
+type MenuOrderIn struct {
+	Categories []string `json:"categories,omitempty"`
+	Items      []string `json:"items,omitempty"`
+}
+
+// HoursOverrideIn is the payload for creating/updating a date-specific
+// hours override. OpenTime and CloseTime are required for "hours" and
+// "blackout" and ignored for "closed".
+type HoursOverrideIn struct {
+	Date      string `json:"date"` // YYYY-MM-DD
+	Kind      string `json:"kind"` // closed, hours or blackout
+	OpenTime  string `json:"open_time,omitempty"`
+	CloseTime string `json:"close_time,omitempty"`
+	Note      string `json:"note,omitempty"`
+}
+
+// TableIn is the payload for creating/updating a dining table.
+type TableIn struct {
+	Name         string `json:"name"`
+	MinCapacity  int    `json:"min_capacity"`
+	MaxCapacity  int    `json:"max_capacity"`
+	Section      string `json:"section,omitempty"`
+	IsCombinable bool   `json:"is_combinable"`
+	IsActive     *bool  `json:"is_active,omitempty"` // default true
 }
 
 type ReservationIn struct {
@@ -53,8 +154,128 @@ type ReservationIn struct {
 	SpecialRequests string `json:"special_requests,omitempty"`
 }
 
+// ReservationUpdateIn changes an existing reservation. Omitted fields keep
+// their current value.
+type ReservationUpdateIn struct {
+	PartySize       *int    `json:"party_size,omitempty"`
+	Date            *string `json:"date,omitempty"`
+	Time            *string `json:"time,omitempty"`
+	SpecialRequests *string `json:"special_requests,omitempty"`
+}
+
+// ReservationStatusIn moves a reservation to a new lifecycle status.
+type ReservationStatusIn struct {
+	Status string `json:"status"` // seated, completed, no_show or cancelled
+}
+
+// WaitlistIn joins a restaurant's waitlist for any seating time between
+// TimeFrom and TimeTo (inclusive). TimeTo defaults to TimeFrom.
+type WaitlistIn struct {
+	CustomerName    string `json:"customer_name"`
+	CustomerEmail   string `json:"customer_email,omitempty"`
+	CustomerPhone   string `json:"customer_phone,omitempty"`
+	PartySize       int    `json:"party_size"`
+	Date            string `json:"date"`
+	TimeFrom        string `json:"time_from"`
+	TimeTo          string `json:"time_to,omitempty"`
+	SpecialRequests string `json:"special_requests,omitempty"`
+}
+
+// WaitlistPromoteIn lets an owner pick the seating time when promoting a
+// waitlist entry. Without a time, the first slot in the entry's window that
+// fits is used.
+type WaitlistPromoteIn struct {
+	Time string `json:"time,omitempty"`
+}
+
+// ReviewIn is a guest's review of a completed reservation.
+type ReviewIn struct {
+	Rating  int    `json:"rating"` // 1–5 stars
+	Comment string `json:"comment,omitempty"`
+}
+
+// ReviewReplyIn sets the owner's public reply to a review. An empty reply
+// removes it.
+type ReviewReplyIn struct {
+	Reply string `json:"reply"`
+}
+
+// ReviewStatusIn publishes or hides a review. A note is required when
+// hiding.
+type ReviewStatusIn struct {
+	Status string `json:"status"` // published or hidden
+	Note   string `json:"note,omitempty"`
+}
+
+// RestaurantQuery filters and pages a restaurant search. Zero values mean
+// "no filter".
+type RestaurantQuery struct {
+	Q          string
+	City       string
+	Cuisine    string
+	PriceRange string
+	Features   []string
+	OpenNow    bool     // only restaurants open at the time of the request, in their own time zone
+	Latitude   *float64 // with Longitude, only restaurants near this point, nearest first
+	Longitude  *float64
+	RadiusKm   float64 // search radius around Latitude/Longitude; 0 means the default
+	Limit      int
+	Offset     int
+	Cursor     string // from a previous page's next_cursor; takes precedence over Offset
+}
+
+// DishQuery filters and pages a menu item search across restaurants. Zero
+// values mean "no filter".
+type DishQuery struct {
+	Q                string // free text over dish name, description and category
+	City             string
+	Category         string
+	Dietary          []string // every label must be present
+	ExcludeAllergens []string // leave out dishes that contain or may contain any of these
+	MinPrice         *float64
+	MaxPrice         *float64
+	MaxCalories      *int
+	PriceRange       string // the restaurant's price level
+	Limit            int
+	Offset           int
+	Cursor           string // from a previous page's next_cursor; takes precedence over Offset
+}
+
+// ReservationQuery filters, sorts and pages an owner's reservation listing.
+// Zero values mean "no filter".
+type ReservationQuery struct {
+	DateFrom     string   // YYYY-MM-DD, inclusive
+	DateTo       string   // YYYY-MM-DD, inclusive
+	Statuses     []string // e.g. confirmed, cancelled
+	MinPartySize int
+	MaxPartySize int
+	Sort         string // date, -date, party_size, -party_size, created_at, -created_at
+	Limit        int
+	Offset       int
+	Cursor       string // from a previous page's next_cursor; takes precedence over Offset
+}
+
+// ReviewQuery pages a restaurant's reviews, newest first.
+type ReviewQuery struct {
+	Status string // published (default) or hidden; hidden needs the owner
+	Limit  int
+	Offset int
+	Cursor string // from a previous page's next_cursor; takes precedence over Offset
+}
+
 // --- Response DTOs ---
 
+// Page is one page of a list. Pass NextCursor back as the cursor parameter
+// to get the next page; it is empty on the last page. TotalEstimate is the
+// number of matching items across all pages; it can be approximate for
+// free-text and location searches, which consider a bounded number of
+// matches, and for open_now listings, which count closed restaurants too.
+type Page[T any] struct {
+	Items         []T    `json:"items"`
+	NextCursor    string `json:"next_cursor,omitempty"`
+	TotalEstimate int64  `json:"total_estimate"`
+}
+
 type RestaurantSummary struct {
 	ID          string   `json:"id"`
 	Name        string   `json:"name"`
@@ -65,6 +286,9 @@ type RestaurantSummary struct {
 	ReviewCount int      `json:"review_count"`
 	Address     string   `json:"address"`
 	Features    []string `json:"features"`
+	DistanceKm  *float64 `json:"distance_km,omitempty"`
+	MatchedIn   string   `json:"matched_in,omitempty"` // field that matched a free-text query
+	Highlight   string   `json:"highlight,omitempty"`  // snippet of that field with matches in **bold**
 }
 
 type OperatingHoursOut struct {
@@ -74,32 +298,53 @@ type OperatingHoursOut struct {
 	IsClosed  bool   `json:"is_closed"`
 }
 
+type HoursOverrideOut struct {
+	ID        string `json:"id"`
+	Date      string `json:"date"`
+	Kind      string `json:"kind"`
+	OpenTime  string `json:"open_time,omitempty"`
+	CloseTime string `json:"close_time,omitempty"`
+	Note      string `json:"note,omitempty"`
+}
+
+type TurnTimeOut struct {
+	MinPartySize int `json:"min_party_size"`
+	MaxPartySize int `json:"max_party_size,omitempty"`
+	Minutes      int `json:"minutes"`
+}
+
 type RestaurantDetail struct {
-	ID          string              `json:"id"`
-	Name        string              `json:"name"`
-	Description string              `json:"description,omitempty"`
-	Cuisines    []string            `json:"cuisines"`
-	PriceRange  string              `json:"price_range"`
-	Address     string              `json:"address"`
-	City        string              `json:"city"`
-	State       string              `json:"state,omitempty"`
-	ZipCode     string              `json:"zip_code,omitempty"`
-	Country     string              `json:"country"`
-	Latitude    *float64            `json:"latitude,omitempty"`
-	Longitude   *float64            `json:"longitude,omitempty"`
-	Phone       string              `json:"phone,omitempty"`
-	Email       string              `json:"email,omitempty"`
-	Website     string              `json:"website,omitempty"`
-	Features    []string            `json:"features"`
-	TotalSeats  int                 `json:"total_seats"`
-	Rating      *float64            `json:"rating"`
-	ReviewCount int                 `json:"review_count"`
-	IsActive    bool                `json:"is_active"`
-	Hours       []OperatingHoursOut `json:"hours"`
+	ID                 string              `json:"id"`
+	Name               string              `json:"name"`
+	Description        string              `json:"description,omitempty"`
+	Cuisines           []string            `json:"cuisines"`
+	PriceRange         string              `json:"price_range"`
+	Address            string              `json:"address"`
+	City               string              `json:"city"`
+	State              string              `json:"state,omitempty"`
+	ZipCode            string              `json:"zip_code,omitempty"`
+	Country            string              `json:"country"`
+	Timezone           string              `json:"timezone"`
+	Latitude           *float64            `json:"latitude,omitempty"`
+	Longitude          *float64            `json:"longitude,omitempty"`
+	Phone              string              `json:"phone,omitempty"`
+	Email              string              `json:"email,omitempty"`
+	Website            string              `json:"website,omitempty"`
+	Features           []string            `json:"features"`
+	TotalSeats         int                 `json:"total_seats"`
+	LastSeatingMinutes int                 `json:"last_seating_minutes"`
+	TurnMinutes        int                 `json:"turn_minutes"`
+	TurnTimes          []TurnTimeOut       `json:"turn_times,omitempty"`
+	Rating             *float64            `json:"rating"`
+	ReviewCount        int                 `json:"review_count"`
+	IsActive           bool                `json:"is_active"`
+	Hours              []OperatingHoursOut `json:"hours"`
+	SpecialHours       []HoursOverrideOut  `json:"special_hours,omitempty"` // upcoming closures and changed hours
 }
 
 type MenuItemOut struct {
 	ID            string   `json:"id"`
+	ExternalID    string   `json:"external_id,omitempty"`
 	Category      string   `json:"category"`
 	Name          string   `json:"name"`
 	Description   string   `json:"description,omitempty"`
@@ -110,16 +355,73 @@ type MenuItemOut struct {
 	IsPopular     bool     `json:"is_popular"`
 	ImageURL      string   `json:"image_url,omitempty"`
 	Calories      *int     `json:"calories,omitempty"`
+	SortOrder     int      `json:"sort_order"`
+
+	// Allergens and MayContainAllergens are null until AllergensDeclared.
+	AllergensDeclared   bool     `json:"allergens_declared"`
+	Allergens           []string `json:"allergens"`
+	MayContainAllergens []string `json:"may_contain_allergens"`
+
+	OptionGroups []MenuOptionGroupOut `json:"option_groups,omitempty"`
+}
+
+type MenuOptionGroupOut struct {
+	ID            string          `json:"id"`
+	Name          string          `json:"name"`
+	Required      bool            `json:"required"`
+	MinSelections int             `json:"min_selections"`
+	MaxSelections int             `json:"max_selections"` // 0 means no limit
+	Options       []MenuOptionOut `json:"options"`
+}
+
+type MenuOptionOut struct {
+	ID            string   `json:"id"`
+	Name          string   `json:"name"`
+	PriceDelta    float64  `json:"price_delta"`
+	DietaryLabels []string `json:"dietary_labels"`
+	Allergens     []string `json:"allergens"`
+}
+
+// DishResult is a menu item matching a dish search, with the restaurant
+// that serves it.
+type DishResult struct {
+	Item       MenuItemOut       `json:"item"`
+	Restaurant RestaurantSummary `json:"restaurant"`
+	Highlight  string            `json:"highlight,omitempty"` // matched words in **bold**
 }
 
 type MenuOut struct {
-	RestaurantID   string                  `json:"restaurant_id"`
-	RestaurantName string                  `json:"restaurant_name"`
-	Currency       string                  `json:"currency"`
-	Categories     map[string][]MenuItemOut `json:"categories"`
+	RestaurantID   string                   `json:"restaurant_id"`
+	RestaurantName string                   `json:"restaurant_name"`
+	Currency       string                   `json:"currency"`
+	CategoryOrder  []string                 `json:"category_order"` // category names in display order
+	Categories     map[string][]MenuItemOut `json:"categories"`     // items in display order
 }
 
 type ReservationOut struct {
+	ID              string   `json:"id"`
+	RestaurantID    string   `json:"restaurant_id"`
+	RestaurantName  string   `json:"restaurant_name,omitempty"`
+	CustomerName    string   `json:"customer_name"`
+	CustomerEmail   string   `json:"customer_email,omitempty"`
+	CustomerPhone   string   `json:"customer_phone,omitempty"`
+	PartySize       int      `json:"party_size"`
+	Date            string   `json:"date"`
+	Time            string   `json:"time"`
+	Status          string   `json:"status"`
+	SpecialRequests string   `json:"special_requests,omitempty"`
+	Tables          []string `json:"tables,omitempty"`       // assigned table names (owner views only)
+	ManageToken     string   `json:"manage_token,omitempty"` // only returned on creation
+	SeatedAt        string   `json:"seated_at,omitempty"`
+	CompletedAt     string   `json:"completed_at,omitempty"`
+	NoShowAt        string   `json:"no_show_at,omitempty"`
+	CancelledAt     string   `json:"cancelled_at,omitempty"`
+	CreatedAt       string   `json:"created_at"`
+
+	Previous *ReservationChangeOut `json:"previous,omitempty"` // only returned on modification
+}
+
+type WaitlistOut struct {
 	ID              string `json:"id"`
 	RestaurantID    string `json:"restaurant_id"`
 	RestaurantName  string `json:"restaurant_name,omitempty"`
@@ -128,18 +430,70 @@ type ReservationOut struct {
 	CustomerPhone   string `json:"customer_phone,omitempty"`
 	PartySize       int    `json:"party_size"`
 	Date            string `json:"date"`
-	Time            string `json:"time"`
-	Status          string `json:"status"`
+	TimeFrom        string `json:"time_from"`
+	TimeTo          string `json:"time_to"`
 	SpecialRequests string `json:"special_requests,omitempty"`
+	Status          string `json:"status"`
+	Position        int    `json:"position,omitempty"`       // place in line while waiting
+	ReservationID   string `json:"reservation_id,omitempty"` // set once promoted
+	ReservationTime string `json:"reservation_time,omitempty"`
+	ManageToken     string `json:"manage_token,omitempty"` // only returned on joining
 	CreatedAt       string `json:"created_at"`
 }
 
+// GuestNoShowOut is how often one guest has missed a reservation at a
+// restaurant.
+type GuestNoShowOut struct {
+	CustomerName   string `json:"customer_name"`
+	CustomerEmail  string `json:"customer_email,omitempty"`
+	CustomerPhone  string `json:"customer_phone,omitempty"`
+	NoShows        int    `json:"no_shows"`
+	LastNoShowDate string `json:"last_no_show_date"`
+}
+
+// ReservationChangeOut is a reservation's booking details before a change.
+type ReservationChangeOut struct {
+	PartySize       int    `json:"party_size"`
+	Date            string `json:"date"`
+	Time            string `json:"time"`
+	SpecialRequests string `json:"special_requests,omitempty"`
+	ChangedBy       string `json:"changed_by"`
+	ChangedAt       string `json:"changed_at"`
+}
+
+type TableOut struct {
+	ID           string `json:"id"`
+	Name         string `json:"name"`
+	MinCapacity  int    `json:"min_capacity"`
+	MaxCapacity  int    `json:"max_capacity"`
+	Section      string `json:"section,omitempty"`
+	IsCombinable bool   `json:"is_combinable"`
+	IsActive     bool   `json:"is_active"`
+}
+
 type AvailabilityOut struct {
 	RestaurantID   string   `json:"restaurant_id"`
 	RestaurantName string   `json:"restaurant_name"`
 	Date           string   `json:"date"`
 	AvailableTimes []string `json:"available_times"`
 	MaxPartySize   int      `json:"max_party_size"`
+	Reason         string   `json:"reason,omitempty"` // why no times are offered, e.g. closed that day
+}
+
+// SlotOccupancyOut is the anonymized load on a restaurant at one seating time.
+type SlotOccupancyOut struct {
+	Time         string `json:"time"`
+	SeatsBooked  int    `json:"seats_booked"`
+	Reservations int    `json:"reservations"`
+}
+
+// OccupancyOut is the public, PII-free view of a restaurant's bookings for a day.
+type OccupancyOut struct {
+	RestaurantID   string             `json:"restaurant_id"`
+	RestaurantName string             `json:"restaurant_name"`
+	Date           string             `json:"date"`
+	Capacity       int                `json:"capacity"`
+	Slots          []SlotOccupancyOut `json:"slots"`
 }
 
 type RecommendationOut struct {
@@ -148,6 +502,39 @@ type RecommendationOut struct {
 	RelevanceScore float64           `json:"relevance_score"`
 }
 
+// ReviewOut is a guest review. ModerationNote is only set for hidden
+// reviews, which only the restaurant's owner can see.
+type ReviewOut struct {
+	ID             string `json:"id"`
+	RestaurantID   string `json:"restaurant_id"`
+	ReservationID  string `json:"reservation_id,omitempty"`
+	AuthorName     string `json:"author_name"`
+	Rating         int    `json:"rating"`
+	Comment        string `json:"comment,omitempty"`
+	VisitDate      string `json:"visit_date"`
+	Status         string `json:"status"`
+	ModerationNote string `json:"moderation_note,omitempty"`
+	OwnerReply     string `json:"owner_reply,omitempty"`
+	RepliedAt      string `json:"replied_at,omitempty"`
+	CreatedAt      string `json:"created_at"`
+}
+
+// TaxonomyTermOut is one canonical value and the spellings accepted for it.
+type TaxonomyTermOut struct {
+	Slug     string   `json:"slug"`
+	Label    string   `json:"label"`
+	Synonyms []string `json:"synonyms"`
+}
+
+// TaxonomyOut lists the controlled vocabularies for cuisines, features,
+// dietary labels and allergens.
+type TaxonomyOut struct {
+	Cuisines      []TaxonomyTermOut `json:"cuisines"`
+	Features      []TaxonomyTermOut `json:"features"`
+	DietaryLabels []TaxonomyTermOut `json:"dietary_labels"`
+	Allergens     []TaxonomyTermOut `json:"allergens"`
+}
+
 type HealthOut struct {
 	Status  string `json:"status"`
 	Version string `json:"version"`
@@ -188,13 +575,122 @@ type OwnerOut struct {
 // BulkMenuImportIn is the payload for bulk menu import.
 type BulkMenuImportIn struct {
 	// Strategy: "replace" deletes all existing items first; "merge" adds/updates.
-	Strategy string       `json:"strategy"` // "replace" (default) or "merge"
-	Items    []MenuItemIn `json:"items"`
+	// Merge matches items by external_id, or by category and name.
+	Strategy      string       `json:"strategy"` // "replace" (default) or "merge"
+	Items         []MenuItemIn `json:"items"`
+	RemoveMissing bool         `json:"remove_missing,omitempty"` // merge only: delete items not in Items
+	DryRun        bool         `json:"dry_run,omitempty"`        // report the changes without making them
 }
 
-// BulkMenuImportOut is the response after a bulk import.
+// BulkMenuImportOut is the response after a bulk import. If any row has an
+// error, nothing is imported.
 type BulkMenuImportOut struct {
-	RestaurantID string `json:"restaurant_id"`
-	Imported     int    `json:"imported"`
-	Strategy     string `json:"strategy"`
+	RestaurantID string             `json:"restaurant_id"`
+	Imported     int                `json:"imported"` // items created or updated
+	Strategy     string             `json:"strategy"`
+	DryRun       bool               `json:"dry_run"`
+	Created      int                `json:"created"`
+	Updated      int                `json:"updated"`
+	Unchanged    int                `json:"unchanged"`
+	Removed      int                `json:"removed"`
+	Errors       int                `json:"errors"`
+	Rows         []MenuImportRowOut `json:"rows"`
+}
+
+// MenuImportRowOut reports what a bulk import did with one item.
+type MenuImportRowOut struct {
+	Row        int      `json:"row,omitempty"` // 1-based position in items; absent for removed items
+	Action     string   `json:"action"`        // created, updated, unchanged, removed or error
+	ItemID     string   `json:"item_id,omitempty"`
+	ExternalID string   `json:"external_id,omitempty"`
+	Category   string   `json:"category"`
+	Name       string   `json:"name"`
+	Changes    []string `json:"changes,omitempty"` // fields that changed, for updated items
+	Error      string   `json:"error,omitempty"`
+}
+
+// --- Webhook DTOs ---
+
+// WebhookIn creates or replaces a webhook subscription. Events must name at
+// least one event type. Secret is generated when left empty on creation;
+// on update, a non-empty Secret rotates it.
+type WebhookIn struct {
+	URL      string   `json:"url"`
+	Events   []string `json:"events"`
+	Secret   string   `json:"secret,omitempty"`
+	IsActive *bool    `json:"is_active,omitempty"` // defaults to true on creation, unchanged on update
+}
+
+// WebhookOut is a webhook subscription. The signing secret is only
+// returned when it is set: on creation and when rotated.
+type WebhookOut struct {
+	ID        string   `json:"id"`
+	URL       string   `json:"url"`
+	Events    []string `json:"events"`
+	IsActive  bool     `json:"is_active"`
+	Secret    string   `json:"secret,omitempty"`
+	CreatedAt string   `json:"created_at"`
+	UpdatedAt string   `json:"updated_at"`
+}
+
+// DeliveryQuery filters and pages a webhook's delivery log, newest first.
+type DeliveryQuery struct {
+	Status string // pending, delivered or failed
+	Event  string // e.g. reservation.created
+	Limit  int
+	Offset int
+	Cursor string // from a previous page's next_cursor; takes precedence over Offset
+}
+
+// WebhookDeliveryOut is one attempt, or series of retried attempts, to
+// deliver an event to a webhook. Payload is the exact body sent.
+type WebhookDeliveryOut struct {
+	ID             string          `json:"id"`
+	WebhookID      string          `json:"webhook_id"`
+	EventID        string          `json:"event_id"`
+	Event          string          `json:"event"`
+	Status         string          `json:"status"`
+	Attempts       int             `json:"attempts"`
+	NextAttemptAt  string          `json:"next_attempt_at,omitempty"` // pending deliveries only
+	LastAttemptAt  string          `json:"last_attempt_at,omitempty"`
+	ResponseStatus int             `json:"response_status,omitempty"`
+	LastError      string          `json:"last_error,omitempty"`
+	DeliveredAt    string          `json:"delivered_at,omitempty"`
+	ReplayOf       string          `json:"replay_of,omitempty"`
+	CreatedAt      string          `json:"created_at"`
+	Payload        json.RawMessage `json:"payload"`
+}
+
+// --- Notification DTOs ---
+
+// NotificationTemplateIn customizes one kind of guest message for a
+// restaurant. Subject and Body are Go text/template sources.
+type NotificationTemplateIn struct {
+	Subject string `json:"subject"`
+	Body    string `json:"body"`
+}
+
+// NotificationTemplateOut is the template a restaurant uses for one kind
+// of message, with a preview rendered from sample data.
+type NotificationTemplateOut struct {
+	Kind    string                 `json:"kind"`
+	Subject string                 `json:"subject"`
+	Body    string                 `json:"body"`
+	Custom  bool                   `json:"custom"` // false when the default is used
+	Preview NotificationPreviewOut `json:"preview"`
+}
+
+// NotificationPreviewOut is a rendered message.
+type NotificationPreviewOut struct {
+	Subject string `json:"subject"`
+	Body    string `json:"body"`
+}
+
+// --- Calendar DTOs ---
+
+// CalendarFeedOut is a restaurant's calendar feed subscription URL. It
+// embeds the feed token, so it is only shown when the feed is created.
+type CalendarFeedOut struct {
+	URL   string `json:"url"`
+	Token string `json:"token"`
 }
@@ -3,15 +3,20 @@ package handlers
 import (
 	"encoding/json"
 	"errors"
+	"fmt"
+	"mime"
 	"net/http"
+	"net/url"
 	"strconv"
 	"strings"
 
 	"github.com/go-chi/chi/v5"
+	"gorm.io/gorm"
 
 	"github.com/agenteats/agenteats/internal/database"
 	"github.com/agenteats/agenteats/internal/dto"
 	authmw "github.com/agenteats/agenteats/internal/middleware"
+	"github.com/agenteats/agenteats/internal/models"
 	"github.com/agenteats/agenteats/internal/services"
 )
 
@@ -27,6 +32,71 @@ func writeError(w http.ResponseWriter, status int, msg string) {
 	writeJSON(w, status, dto.ErrorOut{Error: msg})
 }
 
+// writeReservationError maps reservation validation errors from the
+// services package to HTTP status codes.
+func writeReservationError(w http.ResponseWriter, err error, notFound string) {
+	switch {
+	case errors.Is(err, services.ErrInvalidInput):
+		writeError(w, http.StatusBadRequest, err.Error())
+	case errors.Is(err, services.ErrSlotUnavailable),
+		errors.Is(err, services.ErrInvalidTransition),
+		errors.Is(err, services.ErrNotReviewable):
+		writeError(w, http.StatusConflict, err.Error())
+	case errors.Is(err, services.ErrPartyTooLarge),
+		errors.Is(err, services.ErrRestaurantClosed),
+		errors.Is(err, services.ErrRestaurantInactive):
+		writeError(w, http.StatusUnprocessableEntity, err.Error())
+	case errors.Is(err, services.ErrNotAuthorized):
+		writeError(w, http.StatusForbidden, err.Error())
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		writeError(w, http.StatusNotFound, notFound)
+	default:
+		writeError(w, http.StatusInternalServerError, "Failed to save reservation")
+	}
+}
+
+// manageToken returns the reservation manage token from the X-Manage-Token
+// header, falling back to the token query parameter.
+func manageToken(r *http.Request) string {
+	if t := r.Header.Get("X-Manage-Token"); t != "" {
+		return t
+	}
+	return r.URL.Query().Get("token")
+}
+
+// TrustForwardedHost makes baseURL use the X-Forwarded-Host header. Only
+// set it, from the TRUST_FORWARDED_HOST setting, behind a reverse proxy
+// that sets or strips the header; otherwise any client could choose the
+// host of the links it is sent.
+var TrustForwardedHost bool
+
+// baseURL returns the scheme and host the client used to reach the API,
+// honoring X-Forwarded-Proto from a reverse proxy, and X-Forwarded-Host
+// when TrustForwardedHost is set.
+func baseURL(r *http.Request) string {
+	scheme := "http"
+	if r.TLS != nil || strings.EqualFold(r.Header.Get("X-Forwarded-Proto"), "https") {
+		scheme = "https"
+	}
+	host := r.Host
+	if fwd := r.Header.Get("X-Forwarded-Host"); TrustForwardedHost && fwd != "" {
+		host = fwd
+	}
+	return scheme + "://" + host
+}
+
+// writeCalendar sends an iCalendar file. With a filename it is offered as a
+// download rather than shown inline.
+func writeCalendar(w http.ResponseWriter, body []byte, filename string) {
+	w.Header().Set("Content-Type", "text/calendar; charset=utf-8")
+	if filename != "" {
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	}
+	w.Header().Set("Cache-Control", "private, no-cache")
+	w.WriteHeader(http.StatusOK)
+	w.Write(body)
+}
+
 func parseCSV(s string) []string {
 	if s == "" {
 		return nil
@@ -41,6 +111,20 @@ func parseCSV(s string) []string {
 	return result
 }
 
+// parseFloatParam reads an optional numeric query parameter, returning nil
+// when it is absent.
+func parseFloatParam(params url.Values, name string) (*float64, error) {
+	v := params.Get(name)
+	if v == "" {
+		return nil, nil
+	}
+	f, err := strconv.ParseFloat(v, 64)
+	if err != nil {
+		return nil, fmt.Errorf("%s must be a number", name)
+	}
+	return &f, nil
+}
+
 // --- Health ---
 
 func Health(w http.ResponseWriter, r *http.Request) {
@@ -51,25 +135,65 @@ func Health(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
+// GetTaxonomy lists the accepted cuisines, features and dietary labels.
+func GetTaxonomy(w http.ResponseWriter, r *http.Request) {
+	result, err := services.GetTaxonomy(database.DB)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "Failed to load taxonomy")
+		return
+	}
+	writeJSON(w, http.StatusOK, result)
+}
+
 // --- Restaurants ---
 
 func SearchRestaurants(w http.ResponseWriter, r *http.Request) {
-	q := r.URL.Query().Get("q")
-	city := r.URL.Query().Get("city")
-	cuisine := r.URL.Query().Get("cuisine")
-	priceRange := r.URL.Query().Get("price_range")
-	features := parseCSV(r.URL.Query().Get("features"))
+	params := r.URL.Query()
+	q := dto.RestaurantQuery{
+		Q:          params.Get("q"),
+		City:       params.Get("city"),
+		Cuisine:    params.Get("cuisine"),
+		PriceRange: params.Get("price_range"),
+		Features:   parseCSV(params.Get("features")),
+		Cursor:     params.Get("cursor"),
+		Limit:      20,
+	}
+	q.OpenNow, _ = strconv.ParseBool(params.Get("open_now"))
 
-	limit := 20
-	if l, err := strconv.Atoi(r.URL.Query().Get("limit")); err == nil && l > 0 && l <= 100 {
-		limit = l
+	var err error
+	if q.Latitude, err = parseFloatParam(params, "lat"); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if q.Longitude, err = parseFloatParam(params, "lng"); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	radius, err := parseFloatParam(params, "radius_km")
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if radius != nil {
+		q.RadiusKm = *radius
+	}
+
+	if l, err := strconv.Atoi(params.Get("limit")); err == nil && l > 0 && l <= 100 {
+		q.Limit = l
 	}
-	offset := 0
-	if o, err := strconv.Atoi(r.URL.Query().Get("offset")); err == nil && o >= 0 {
-		offset = o
+	if o, err := strconv.Atoi(params.Get("offset")); err == nil && o >= 0 {
+		q.Offset = o
 	}
 
-	results := services.ListRestaurants(database.DB, q, city, cuisine, priceRange, features, limit, offset)
+	results, err := services.ListRestaurants(database.DB, q)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidInput) {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		writeError(w, http.StatusInternalServerError, "Failed to search restaurants")
+		return
+	}
 	writeJSON(w, http.StatusOK, results)
 }
 
@@ -95,6 +219,10 @@ func CreateRestaurant(w http.ResponseWriter, r *http.Request) {
 			writeError(w, http.StatusConflict, err.Error())
 			return
 		}
+		if errors.Is(err, services.ErrInvalidInput) {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
 		writeError(w, http.StatusInternalServerError, "Failed to create restaurant")
 		return
 	}
@@ -110,6 +238,10 @@ func UpdateRestaurant(w http.ResponseWriter, r *http.Request) {
 	}
 	result, err := services.UpdateRestaurant(database.DB, id, in)
 	if err != nil {
+		if errors.Is(err, services.ErrInvalidInput) {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
 		writeError(w, http.StatusNotFound, "Restaurant not found")
 		return
 	}
@@ -120,14 +252,69 @@ func UpdateRestaurant(w http.ResponseWriter, r *http.Request) {
 
 func GetMenu(w http.ResponseWriter, r *http.Request) {
 	id := chi.URLParam(r, "restaurantID")
-	result, err := services.GetMenu(database.DB, id)
+	result, err := services.GetMenu(database.DB, id, parseCSV(r.URL.Query().Get("exclude_allergens")))
 	if err != nil {
+		if errors.Is(err, services.ErrInvalidInput) {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
 		writeError(w, http.StatusNotFound, "Restaurant not found")
 		return
 	}
 	writeJSON(w, http.StatusOK, result)
 }
 
+// SearchDishes finds menu items across restaurants.
+func SearchDishes(w http.ResponseWriter, r *http.Request) {
+	params := r.URL.Query()
+	q := dto.DishQuery{
+		Q:                params.Get("q"),
+		City:             params.Get("city"),
+		Category:         params.Get("category"),
+		Dietary:          parseCSV(params.Get("dietary")),
+		ExcludeAllergens: parseCSV(params.Get("exclude_allergens")),
+		PriceRange:       params.Get("price_range"),
+		Cursor:           params.Get("cursor"),
+		Limit:            20,
+	}
+
+	var err error
+	if q.MinPrice, err = parseFloatParam(params, "min_price"); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if q.MaxPrice, err = parseFloatParam(params, "max_price"); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if v := params.Get("max_calories"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "max_calories must be a whole number")
+			return
+		}
+		q.MaxCalories = &n
+	}
+
+	if l, err := strconv.Atoi(params.Get("limit")); err == nil && l > 0 && l <= 100 {
+		q.Limit = l
+	}
+	if o, err := strconv.Atoi(params.Get("offset")); err == nil && o >= 0 {
+		q.Offset = o
+	}
+
+	results, err := services.SearchDishes(database.DB, q)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidInput) {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		writeError(w, http.StatusInternalServerError, "Failed to search dishes")
+		return
+	}
+	writeJSON(w, http.StatusOK, results)
+}
+
 func AddMenuItem(w http.ResponseWriter, r *http.Request) {
 	id := chi.URLParam(r, "restaurantID")
 	var in dto.MenuItemIn
@@ -137,6 +324,10 @@ func AddMenuItem(w http.ResponseWriter, r *http.Request) {
 	}
 	result, err := services.AddMenuItem(database.DB, id, in)
 	if err != nil {
+		if errors.Is(err, services.ErrInvalidInput) {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
 		writeError(w, http.StatusNotFound, "Restaurant not found")
 		return
 	}
@@ -159,6 +350,10 @@ func CheckAvailability(w http.ResponseWriter, r *http.Request) {
 
 	result, err := services.CheckAvailability(database.DB, id, date, partySize)
 	if err != nil {
+		if errors.Is(err, services.ErrInvalidInput) {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
 		writeError(w, http.StatusNotFound, "Restaurant not found")
 		return
 	}
@@ -174,126 +369,239 @@ func MakeReservation(w http.ResponseWriter, r *http.Request) {
 	}
 	result, err := services.MakeReservation(database.DB, id, in)
 	if err != nil {
-		writeError(w, http.StatusNotFound, "Restaurant not found")
+		writeReservationError(w, err, "Restaurant not found")
 		return
 	}
 	writeJSON(w, http.StatusCreated, result)
 }
 
-func ListReservations(w http.ResponseWriter, r *http.Request) {
+// GetOccupancy is the public, anonymized view of a restaurant's bookings.
+func GetOccupancy(w http.ResponseWriter, r *http.Request) {
 	id := chi.URLParam(r, "restaurantID")
 	date := r.URL.Query().Get("date")
-	results := services.ListReservations(database.DB, id, date)
-	writeJSON(w, http.StatusOK, results)
-}
-
-func CancelReservation(w http.ResponseWriter, r *http.Request) {
-	id := chi.URLParam(r, "reservationID")
-	result, err := services.CancelReservation(database.DB, id)
+	if date == "" {
+		writeError(w, http.StatusBadRequest, "date parameter is required")
+		return
+	}
+	result, err := services.GetOccupancy(database.DB, id, date)
 	if err != nil {
-		writeError(w, http.StatusNotFound, "Reservation not found")
+		if errors.Is(err, services.ErrInvalidInput) {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		writeError(w, http.StatusNotFound, "Restaurant not found")
 		return
 	}
 	writeJSON(w, http.StatusOK, result)
 }
 
-// --- Recommendations ---
-
-func GetRecommendations(w http.ResponseWriter, r *http.Request) {
-	cuisine := r.URL.Query().Get("cuisine")
-	city := r.URL.Query().Get("city")
-	priceRange := r.URL.Query().Get("price_range")
-	features := parseCSV(r.URL.Query().Get("features"))
-	dietaryNeeds := parseCSV(r.URL.Query().Get("dietary_needs"))
-	occasion := r.URL.Query().Get("occasion")
+// ListOwnedReservations lists guest bookings for a restaurant the caller owns.
+func ListOwnedReservations(w http.ResponseWriter, r *http.Request) {
+	owner := authmw.OwnerFromContext(r.Context())
+	if owner == nil {
+		writeError(w, http.StatusUnauthorized, "not authenticated")
+		return
+	}
+	id := chi.URLParam(r, "restaurantID")
+	if !services.RestaurantBelongsToOwner(database.DB, id, owner.ID) {
+		writeError(w, http.StatusForbidden, "you do not own this restaurant")
+		return
+	}
 
-	limit := 5
-	if l, err := strconv.Atoi(r.URL.Query().Get("limit")); err == nil && l > 0 && l <= 20 {
-		limit = l
+	params := r.URL.Query()
+	q := dto.ReservationQuery{
+		DateFrom: params.Get("date_from"),
+		DateTo:   params.Get("date_to"),
+		Statuses: parseCSV(params.Get("status")),
+		Sort:     params.Get("sort"),
+		Cursor:   params.Get("cursor"),
+		Limit:    50,
+	}
+	if date := params.Get("date"); date != "" {
+		q.DateFrom, q.DateTo = date, date
+	}
+	if n, err := strconv.Atoi(params.Get("min_party_size")); err == nil && n > 0 {
+		q.MinPartySize = n
+	}
+	if n, err := strconv.Atoi(params.Get("max_party_size")); err == nil && n > 0 {
+		q.MaxPartySize = n
+	}
+	if l, err := strconv.Atoi(params.Get("limit")); err == nil && l > 0 && l <= 200 {
+		q.Limit = l
+	}
+	if o, err := strconv.Atoi(params.Get("offset")); err == nil && o >= 0 {
+		q.Offset = o
 	}
 
-	results := services.GetRecommendations(database.DB, cuisine, city, priceRange, features, dietaryNeeds, occasion, limit)
+	results, err := services.ListReservations(database.DB, id, q)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
 	writeJSON(w, http.StatusOK, results)
 }
 
-// --- Owner Registration ---
-
-func RegisterOwner(w http.ResponseWriter, r *http.Request) {
-	var in dto.RegisterOwnerIn
-	if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
-		writeError(w, http.StatusBadRequest, "Invalid request body")
+// UpdateReservationStatus moves a reservation through its lifecycle
+// (seated, completed, no_show, cancelled). Owner only.
+func UpdateReservationStatus(w http.ResponseWriter, r *http.Request) {
+	owner := authmw.OwnerFromContext(r.Context())
+	if owner == nil {
+		writeError(w, http.StatusUnauthorized, "not authenticated")
 		return
 	}
-	if in.Name == "" || in.Email == "" {
-		writeError(w, http.StatusBadRequest, "name and email are required")
+	id := chi.URLParam(r, "reservationID")
+	var in dto.ReservationStatusIn
+	if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid request body")
 		return
 	}
-	result, err := services.RegisterOwner(database.DB, in)
+	result, err := services.UpdateReservationStatus(database.DB, id, owner.ID, models.ReservationStatus(in.Status))
 	if err != nil {
-		writeError(w, http.StatusConflict, err.Error())
+		writeReservationError(w, err, "Reservation not found")
 		return
 	}
-	writeJSON(w, http.StatusCreated, result)
+	writeJSON(w, http.StatusOK, result)
 }
 
-func RotateKey(w http.ResponseWriter, r *http.Request) {
+// ListNoShows returns per-guest no-show counts for an owned restaurant,
+// optionally narrowed to one guest by email or phone.
+func ListNoShows(w http.ResponseWriter, r *http.Request) {
 	owner := authmw.OwnerFromContext(r.Context())
 	if owner == nil {
 		writeError(w, http.StatusUnauthorized, "not authenticated")
 		return
 	}
-	newKey, err := services.RotateAPIKey(database.DB, owner.ID)
+	id := chi.URLParam(r, "restaurantID")
+	if !services.RestaurantBelongsToOwner(database.DB, id, owner.ID) {
+		writeError(w, http.StatusForbidden, "you do not own this restaurant")
+		return
+	}
+	email := r.URL.Query().Get("email")
+	phone := r.URL.Query().Get("phone")
+	writeJSON(w, http.StatusOK, services.ListNoShows(database.DB, id, email, phone))
+}
+
+// ModifyReservation changes a reservation's party size, date, time or
+// special requests. Like cancelling, it needs the manage token or the owner's
+// API key.
+func ModifyReservation(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "reservationID")
+	var in dto.ReservationUpdateIn
+	if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	ownerID := ""
+	if owner := authmw.OwnerFromContext(r.Context()); owner != nil {
+		ownerID = owner.ID
+	}
+	result, err := services.ModifyReservation(database.DB, id, manageToken(r), ownerID, in)
 	if err != nil {
-		writeError(w, http.StatusInternalServerError, "failed to rotate key")
+		writeReservationError(w, err, "Reservation not found")
 		return
 	}
-	writeJSON(w, http.StatusOK, map[string]string{"api_key": newKey})
+	writeJSON(w, http.StatusOK, result)
 }
 
-// --- Authenticated Restaurant Management ---
+// CancelReservation cancels a booking. Guests authenticate with the manage
+// token from MakeReservation (X-Manage-Token header or ?token=); owners may
+// use their API key instead.
+func CancelReservation(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "reservationID")
+	ownerID := ""
+	if owner := authmw.OwnerFromContext(r.Context()); owner != nil {
+		ownerID = owner.ID
+	}
+	result, err := services.CancelReservation(database.DB, id, manageToken(r), ownerID)
+	if err != nil {
+		writeReservationError(w, err, "Reservation not found")
+		return
+	}
+	writeJSON(w, http.StatusOK, result)
+}
 
-func ListOwnedRestaurants(w http.ResponseWriter, r *http.Request) {
-	owner := authmw.OwnerFromContext(r.Context())
-	if owner == nil {
-		writeError(w, http.StatusUnauthorized, "not authenticated")
+// GetReservation shows a reservation to the guest holding its manage token
+// or to the restaurant's owner.
+func GetReservation(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "reservationID")
+	ownerID := ""
+	if owner := authmw.OwnerFromContext(r.Context()); owner != nil {
+		ownerID = owner.ID
+	}
+	result, err := services.GetReservation(database.DB, id, manageToken(r), ownerID)
+	if err != nil {
+		writeReservationError(w, err, "Reservation not found")
 		return
 	}
-	results := services.ListOwnerRestaurants(database.DB, owner.ID)
-	writeJSON(w, http.StatusOK, results)
+	writeJSON(w, http.StatusOK, result)
 }
 
-func CreateOwnedRestaurant(w http.ResponseWriter, r *http.Request) {
-	owner := authmw.OwnerFromContext(r.Context())
-	if owner == nil {
-		writeError(w, http.StatusUnauthorized, "not authenticated")
+// GetReservationCalendar downloads a reservation as an .ics file to add to
+// a calendar. Needs the manage token or the owner's API key.
+func GetReservationCalendar(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "reservationID")
+	ownerID := ""
+	if owner := authmw.OwnerFromContext(r.Context()); owner != nil {
+		ownerID = owner.ID
+	}
+	body, err := services.ReservationCalendar(database.DB, id, manageToken(r), ownerID)
+	if err != nil {
+		writeReservationError(w, err, "Reservation not found")
 		return
 	}
-	var in dto.RestaurantIn
+	writeCalendar(w, body, "reservation-"+id+".ics")
+}
+
+// --- Waitlist ---
+
+func JoinWaitlist(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "restaurantID")
+	var in dto.WaitlistIn
 	if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
 		writeError(w, http.StatusBadRequest, "Invalid request body")
 		return
 	}
-	if in.Name == "" {
-		writeError(w, http.StatusBadRequest, "name is required")
+	result, err := services.JoinWaitlist(database.DB, id, in)
+	if err != nil {
+		writeReservationError(w, err, "Restaurant not found")
 		return
 	}
-	if in.Address == "" || in.City == "" {
-		writeError(w, http.StatusBadRequest, "address and city are required")
+	writeJSON(w, http.StatusCreated, result)
+}
+
+// GetWaitlistEntry shows a waitlist entry's place in line, or its
+// reservation once promoted. Needs the manage token or the owner's API key.
+func GetWaitlistEntry(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "entryID")
+	ownerID := ""
+	if owner := authmw.OwnerFromContext(r.Context()); owner != nil {
+		ownerID = owner.ID
+	}
+	result, err := services.GetWaitlistEntry(database.DB, id, manageToken(r), ownerID)
+	if err != nil {
+		writeReservationError(w, err, "Waitlist entry not found")
 		return
 	}
-	result, err := services.CreateRestaurantForOwner(database.DB, owner.ID, in)
+	writeJSON(w, http.StatusOK, result)
+}
+
+func LeaveWaitlist(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "entryID")
+	ownerID := ""
+	if owner := authmw.OwnerFromContext(r.Context()); owner != nil {
+		ownerID = owner.ID
+	}
+	result, err := services.LeaveWaitlist(database.DB, id, manageToken(r), ownerID)
 	if err != nil {
-		if errors.Is(err, services.ErrDuplicateRestaurant) {
-			writeError(w, http.StatusConflict, err.Error())
-			return
-		}
-		writeError(w, http.StatusInternalServerError, "Failed to create restaurant")
+		writeReservationError(w, err, "Waitlist entry not found")
 		return
 	}
-	writeJSON(w, http.StatusCreated, result)
+	writeJSON(w, http.StatusOK, result)
 }
 
-func UpdateOwnedRestaurant(w http.ResponseWriter, r *http.Request) {
+// ListOwnedWaitlist returns an owned restaurant's waitlist, optionally
+// filtered by date and status.
+func ListOwnedWaitlist(w http.ResponseWriter, r *http.Request) {
 	owner := authmw.OwnerFromContext(r.Context())
 	if owner == nil {
 		writeError(w, http.StatusUnauthorized, "not authenticated")
@@ -304,67 +612,963 @@ func UpdateOwnedRestaurant(w http.ResponseWriter, r *http.Request) {
 		writeError(w, http.StatusForbidden, "you do not own this restaurant")
 		return
 	}
-	var in dto.RestaurantIn
-	if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
-		writeError(w, http.StatusBadRequest, "Invalid request body")
-		return
-	}
-	result, err := services.UpdateRestaurant(database.DB, id, in)
+	results, err := services.ListWaitlist(database.DB, id, r.URL.Query().Get("date"), r.URL.Query().Get("status"))
 	if err != nil {
-		writeError(w, http.StatusNotFound, "Restaurant not found")
+		writeReservationError(w, err, "Restaurant not found")
 		return
 	}
-	writeJSON(w, http.StatusOK, result)
+	writeJSON(w, http.StatusOK, results)
 }
 
-func AddOwnedMenuItem(w http.ResponseWriter, r *http.Request) {
+// PromoteWaitlistEntry books a waiting party. The body is optional; without
+// a time the first slot in the entry's window that fits is used.
+func PromoteWaitlistEntry(w http.ResponseWriter, r *http.Request) {
 	owner := authmw.OwnerFromContext(r.Context())
 	if owner == nil {
 		writeError(w, http.StatusUnauthorized, "not authenticated")
 		return
 	}
+	id := chi.URLParam(r, "entryID")
+	var in dto.WaitlistPromoteIn
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+			writeError(w, http.StatusBadRequest, "Invalid request body")
+			return
+		}
+	}
+	result, err := services.PromoteWaitlistEntry(database.DB, id, owner.ID, in)
+	if err != nil {
+		writeReservationError(w, err, "Waitlist entry not found")
+		return
+	}
+	writeJSON(w, http.StatusCreated, result)
+}
+
+// --- Reviews ---
+
+// ListReviews returns a restaurant's published reviews, newest first. The
+// restaurant's owner can pass status=hidden to see hidden ones.
+func ListReviews(w http.ResponseWriter, r *http.Request) {
 	id := chi.URLParam(r, "restaurantID")
-	if !services.RestaurantBelongsToOwner(database.DB, id, owner.ID) {
-		writeError(w, http.StatusForbidden, "you do not own this restaurant")
+	ownerID := ""
+	if owner := authmw.OwnerFromContext(r.Context()); owner != nil {
+		ownerID = owner.ID
+	}
+	params := r.URL.Query()
+	q := dto.ReviewQuery{
+		Status: params.Get("status"),
+		Cursor: params.Get("cursor"),
+		Limit:  20,
+	}
+	if l, err := strconv.Atoi(params.Get("limit")); err == nil && l > 0 && l <= 100 {
+		q.Limit = l
+	}
+	if o, err := strconv.Atoi(params.Get("offset")); err == nil && o >= 0 {
+		q.Offset = o
+	}
+	results, err := services.ListReviews(database.DB, id, q, ownerID)
+	if err != nil {
+		writeReservationError(w, err, "Restaurant not found")
 		return
 	}
-	var in dto.MenuItemIn
+	writeJSON(w, http.StatusOK, results)
+}
+
+// SubmitReview reviews a completed reservation. Needs the reservation's
+// manage token.
+func SubmitReview(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "reservationID")
+	var in dto.ReviewIn
 	if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
 		writeError(w, http.StatusBadRequest, "Invalid request body")
 		return
 	}
-	result, err := services.AddMenuItem(database.DB, id, in)
+	result, err := services.SubmitReview(database.DB, id, manageToken(r), in)
 	if err != nil {
-		writeError(w, http.StatusNotFound, "Restaurant not found")
+		writeReservationError(w, err, "Reservation not found")
 		return
 	}
 	writeJSON(w, http.StatusCreated, result)
 }
 
-func BulkImportMenu(w http.ResponseWriter, r *http.Request) {
+// ReplyToReview sets or removes the owner's public reply to a review of
+// one of their restaurants.
+func ReplyToReview(w http.ResponseWriter, r *http.Request) {
 	owner := authmw.OwnerFromContext(r.Context())
 	if owner == nil {
 		writeError(w, http.StatusUnauthorized, "not authenticated")
 		return
 	}
-	id := chi.URLParam(r, "restaurantID")
-	if !services.RestaurantBelongsToOwner(database.DB, id, owner.ID) {
-		writeError(w, http.StatusForbidden, "you do not own this restaurant")
-		return
-	}
-	var in dto.BulkMenuImportIn
+	id := chi.URLParam(r, "reviewID")
+	var in dto.ReviewReplyIn
 	if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
 		writeError(w, http.StatusBadRequest, "Invalid request body")
 		return
 	}
-	if len(in.Items) == 0 {
-		writeError(w, http.StatusBadRequest, "items array is required and must not be empty")
-		return
-	}
-	result, err := services.BulkImportMenu(database.DB, id, in)
+	result, err := services.ReplyToReview(database.DB, id, owner.ID, in)
 	if err != nil {
-		writeError(w, http.StatusInternalServerError, err.Error())
+		writeReservationError(w, err, "Review not found")
 		return
 	}
 	writeJSON(w, http.StatusOK, result)
 }
+
+// ModerateReview publishes or hides a review of one of the owner's
+// restaurants.
+func ModerateReview(w http.ResponseWriter, r *http.Request) {
+	owner := authmw.OwnerFromContext(r.Context())
+	if owner == nil {
+		writeError(w, http.StatusUnauthorized, "not authenticated")
+		return
+	}
+	id := chi.URLParam(r, "reviewID")
+	var in dto.ReviewStatusIn
+	if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	result, err := services.ModerateReview(database.DB, id, owner.ID, in)
+	if err != nil {
+		writeReservationError(w, err, "Review not found")
+		return
+	}
+	writeJSON(w, http.StatusOK, result)
+}
+
+// --- Recommendations ---
+
+func GetRecommendations(w http.ResponseWriter, r *http.Request) {
+	cuisine := r.URL.Query().Get("cuisine")
+	city := r.URL.Query().Get("city")
+	priceRange := r.URL.Query().Get("price_range")
+	features := parseCSV(r.URL.Query().Get("features"))
+	dietaryNeeds := parseCSV(r.URL.Query().Get("dietary_needs"))
+	excludeAllergens := parseCSV(r.URL.Query().Get("exclude_allergens"))
+	occasion := r.URL.Query().Get("occasion")
+
+	limit := 5
+	if l, err := strconv.Atoi(r.URL.Query().Get("limit")); err == nil && l > 0 && l <= 20 {
+		limit = l
+	}
+
+	results, err := services.GetRecommendations(database.DB, cuisine, city, priceRange, features, dietaryNeeds, excludeAllergens, occasion, limit)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidInput) {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		writeError(w, http.StatusInternalServerError, "Failed to get recommendations")
+		return
+	}
+	writeJSON(w, http.StatusOK, results)
+}
+
+// --- Owner Registration ---
+
+func RegisterOwner(w http.ResponseWriter, r *http.Request) {
+	var in dto.RegisterOwnerIn
+	if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if in.Name == "" || in.Email == "" {
+		writeError(w, http.StatusBadRequest, "name and email are required")
+		return
+	}
+	result, err := services.RegisterOwner(database.DB, in)
+	if err != nil {
+		writeError(w, http.StatusConflict, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusCreated, result)
+}
+
+func RotateKey(w http.ResponseWriter, r *http.Request) {
+	owner := authmw.OwnerFromContext(r.Context())
+	if owner == nil {
+		writeError(w, http.StatusUnauthorized, "not authenticated")
+		return
+	}
+	newKey, err := services.RotateAPIKey(database.DB, owner.ID)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to rotate key")
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]string{"api_key": newKey})
+}
+
+// --- Authenticated Restaurant Management ---
+
+func ListOwnedRestaurants(w http.ResponseWriter, r *http.Request) {
+	owner := authmw.OwnerFromContext(r.Context())
+	if owner == nil {
+		writeError(w, http.StatusUnauthorized, "not authenticated")
+		return
+	}
+	params := r.URL.Query()
+	limit := 50
+	if l, err := strconv.Atoi(params.Get("limit")); err == nil && l > 0 && l <= 200 {
+		limit = l
+	}
+	results, err := services.ListOwnerRestaurants(database.DB, owner.ID, params.Get("cursor"), limit)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, results)
+}
+
+func CreateOwnedRestaurant(w http.ResponseWriter, r *http.Request) {
+	owner := authmw.OwnerFromContext(r.Context())
+	if owner == nil {
+		writeError(w, http.StatusUnauthorized, "not authenticated")
+		return
+	}
+	var in dto.RestaurantIn
+	if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if in.Name == "" {
+		writeError(w, http.StatusBadRequest, "name is required")
+		return
+	}
+	if in.Address == "" || in.City == "" {
+		writeError(w, http.StatusBadRequest, "address and city are required")
+		return
+	}
+	result, err := services.CreateRestaurantForOwner(database.DB, owner.ID, in)
+	if err != nil {
+		if errors.Is(err, services.ErrDuplicateRestaurant) {
+			writeError(w, http.StatusConflict, err.Error())
+			return
+		}
+		if errors.Is(err, services.ErrInvalidInput) {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		writeError(w, http.StatusInternalServerError, "Failed to create restaurant")
+		return
+	}
+	writeJSON(w, http.StatusCreated, result)
+}
+
+func UpdateOwnedRestaurant(w http.ResponseWriter, r *http.Request) {
+	owner := authmw.OwnerFromContext(r.Context())
+	if owner == nil {
+		writeError(w, http.StatusUnauthorized, "not authenticated")
+		return
+	}
+	id := chi.URLParam(r, "restaurantID")
+	if !services.RestaurantBelongsToOwner(database.DB, id, owner.ID) {
+		writeError(w, http.StatusForbidden, "you do not own this restaurant")
+		return
+	}
+	var in dto.RestaurantIn
+	if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	result, err := services.UpdateRestaurant(database.DB, id, in)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidInput) {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		writeError(w, http.StatusNotFound, "Restaurant not found")
+		return
+	}
+	writeJSON(w, http.StatusOK, result)
+}
+
+func AddOwnedMenuItem(w http.ResponseWriter, r *http.Request) {
+	owner := authmw.OwnerFromContext(r.Context())
+	if owner == nil {
+		writeError(w, http.StatusUnauthorized, "not authenticated")
+		return
+	}
+	id := chi.URLParam(r, "restaurantID")
+	if !services.RestaurantBelongsToOwner(database.DB, id, owner.ID) {
+		writeError(w, http.StatusForbidden, "you do not own this restaurant")
+		return
+	}
+	var in dto.MenuItemIn
+	if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	result, err := services.AddMenuItem(database.DB, id, in)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidInput) {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		writeError(w, http.StatusNotFound, "Restaurant not found")
+		return
+	}
+	writeJSON(w, http.StatusCreated, result)
+}
+
+// maxMenuCSVBytes limits the size of an uploaded CSV menu.
+const maxMenuCSVBytes = 5 << 20
+
+// BulkImportMenu replaces or merges a restaurant's menu and reports what
+// happened to each item. If any row is invalid nothing is imported and the
+// report, with the errors, comes back as 422. A text/csv body is read as a
+// spreadsheet, with the options in the query string.
+func BulkImportMenu(w http.ResponseWriter, r *http.Request) {
+	owner := authmw.OwnerFromContext(r.Context())
+	if owner == nil {
+		writeError(w, http.StatusUnauthorized, "not authenticated")
+		return
+	}
+	id := chi.URLParam(r, "restaurantID")
+	if !services.RestaurantBelongsToOwner(database.DB, id, owner.ID) {
+		writeError(w, http.StatusForbidden, "you do not own this restaurant")
+		return
+	}
+	var result *dto.BulkMenuImportOut
+	var err error
+	if mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type")); mediaType == "text/csv" {
+		params := r.URL.Query()
+		in := dto.BulkMenuImportIn{Strategy: params.Get("strategy")}
+		in.RemoveMissing, _ = strconv.ParseBool(params.Get("remove_missing"))
+		in.DryRun, _ = strconv.ParseBool(params.Get("dry_run"))
+		result, err = services.ImportMenuCSV(database.DB, id, http.MaxBytesReader(w, r.Body, maxMenuCSVBytes), in)
+	} else {
+		var in dto.BulkMenuImportIn
+		if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+			writeError(w, http.StatusBadRequest, "Invalid request body")
+			return
+		}
+		if len(in.Items) == 0 {
+			writeError(w, http.StatusBadRequest, "items array is required and must not be empty")
+			return
+		}
+		result, err = services.BulkImportMenu(database.DB, id, in)
+	}
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			writeError(w, http.StatusRequestEntityTooLarge, fmt.Sprintf("CSV files are limited to %d MB", maxMenuCSVBytes>>20))
+			return
+		}
+		if errors.Is(err, services.ErrInvalidInput) {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if result.Errors > 0 {
+		writeJSON(w, http.StatusUnprocessableEntity, result)
+		return
+	}
+	writeJSON(w, http.StatusOK, result)
+}
+
+// ExportMenu downloads the menu as CSV (?format=csv) or as JSON ready to
+// send back to BulkImportMenu.
+func ExportMenu(w http.ResponseWriter, r *http.Request) {
+	owner := authmw.OwnerFromContext(r.Context())
+	if owner == nil {
+		writeError(w, http.StatusUnauthorized, "not authenticated")
+		return
+	}
+	id := chi.URLParam(r, "restaurantID")
+	if !services.RestaurantBelongsToOwner(database.DB, id, owner.ID) {
+		writeError(w, http.StatusForbidden, "you do not own this restaurant")
+		return
+	}
+	format := r.URL.Query().Get("format")
+	if format != "" && format != "json" && format != "csv" {
+		writeError(w, http.StatusBadRequest, "format must be json or csv")
+		return
+	}
+	result, err := services.ExportMenu(database.DB, id)
+	if err != nil {
+		writeError(w, http.StatusNotFound, "Restaurant not found")
+		return
+	}
+	if format == "csv" {
+		w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+		w.Header().Set("Content-Disposition", `attachment; filename="menu.csv"`)
+		services.WriteMenuCSV(w, result.Items)
+		return
+	}
+	writeJSON(w, http.StatusOK, result)
+}
+
+// --- Menu Management ---
+
+// UpdateOwnedMenuItem replaces a menu item's details.
+func UpdateOwnedMenuItem(w http.ResponseWriter, r *http.Request) {
+	owner := authmw.OwnerFromContext(r.Context())
+	if owner == nil {
+		writeError(w, http.StatusUnauthorized, "not authenticated")
+		return
+	}
+	id := chi.URLParam(r, "restaurantID")
+	if !services.RestaurantBelongsToOwner(database.DB, id, owner.ID) {
+		writeError(w, http.StatusForbidden, "you do not own this restaurant")
+		return
+	}
+	var in dto.MenuItemIn
+	if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	result, err := services.UpdateMenuItem(database.DB, id, chi.URLParam(r, "itemID"), in)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidInput) {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		writeError(w, http.StatusNotFound, "Menu item not found")
+		return
+	}
+	writeJSON(w, http.StatusOK, result)
+}
+
+// PatchOwnedMenuItem changes only the fields sent, e.g. just the price.
+func PatchOwnedMenuItem(w http.ResponseWriter, r *http.Request) {
+	owner := authmw.OwnerFromContext(r.Context())
+	if owner == nil {
+		writeError(w, http.StatusUnauthorized, "not authenticated")
+		return
+	}
+	id := chi.URLParam(r, "restaurantID")
+	if !services.RestaurantBelongsToOwner(database.DB, id, owner.ID) {
+		writeError(w, http.StatusForbidden, "you do not own this restaurant")
+		return
+	}
+	var in dto.MenuItemPatchIn
+	if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	result, err := services.PatchMenuItem(database.DB, id, chi.URLParam(r, "itemID"), in)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidInput) {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		writeError(w, http.StatusNotFound, "Menu item not found")
+		return
+	}
+	writeJSON(w, http.StatusOK, result)
+}
+
+// SetMenuItemAvailability marks a dish sold out ("86'd") or back on.
+func SetMenuItemAvailability(w http.ResponseWriter, r *http.Request) {
+	owner := authmw.OwnerFromContext(r.Context())
+	if owner == nil {
+		writeError(w, http.StatusUnauthorized, "not authenticated")
+		return
+	}
+	id := chi.URLParam(r, "restaurantID")
+	if !services.RestaurantBelongsToOwner(database.DB, id, owner.ID) {
+		writeError(w, http.StatusForbidden, "you do not own this restaurant")
+		return
+	}
+	var in dto.MenuAvailabilityIn
+	if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if in.IsAvailable == nil {
+		writeError(w, http.StatusBadRequest, "is_available is required")
+		return
+	}
+	result, err := services.SetMenuItemAvailability(database.DB, id, chi.URLParam(r, "itemID"), *in.IsAvailable)
+	if err != nil {
+		writeError(w, http.StatusNotFound, "Menu item not found")
+		return
+	}
+	writeJSON(w, http.StatusOK, result)
+}
+
+func DeleteOwnedMenuItem(w http.ResponseWriter, r *http.Request) {
+	owner := authmw.OwnerFromContext(r.Context())
+	if owner == nil {
+		writeError(w, http.StatusUnauthorized, "not authenticated")
+		return
+	}
+	id := chi.URLParam(r, "restaurantID")
+	if !services.RestaurantBelongsToOwner(database.DB, id, owner.ID) {
+		writeError(w, http.StatusForbidden, "you do not own this restaurant")
+		return
+	}
+	if err := services.DeleteMenuItem(database.DB, id, chi.URLParam(r, "itemID")); err != nil {
+		writeError(w, http.StatusNotFound, "Menu item not found")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ReorderMenu sets the order categories and items appear in on the menu.
+func ReorderMenu(w http.ResponseWriter, r *http.Request) {
+	owner := authmw.OwnerFromContext(r.Context())
+	if owner == nil {
+		writeError(w, http.StatusUnauthorized, "not authenticated")
+		return
+	}
+	id := chi.URLParam(r, "restaurantID")
+	if !services.RestaurantBelongsToOwner(database.DB, id, owner.ID) {
+		writeError(w, http.StatusForbidden, "you do not own this restaurant")
+		return
+	}
+	var in dto.MenuOrderIn
+	if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	result, err := services.ReorderMenu(database.DB, id, in)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidInput) {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		writeError(w, http.StatusNotFound, "Restaurant not found")
+		return
+	}
+	writeJSON(w, http.StatusOK, result)
+}
+
+// --- Table Management ---
+
+func ListOwnedTables(w http.ResponseWriter, r *http.Request) {
+	owner := authmw.OwnerFromContext(r.Context())
+	if owner == nil {
+		writeError(w, http.StatusUnauthorized, "not authenticated")
+		return
+	}
+	id := chi.URLParam(r, "restaurantID")
+	if !services.RestaurantBelongsToOwner(database.DB, id, owner.ID) {
+		writeError(w, http.StatusForbidden, "you do not own this restaurant")
+		return
+	}
+	writeJSON(w, http.StatusOK, services.ListTables(database.DB, id))
+}
+
+func CreateOwnedTable(w http.ResponseWriter, r *http.Request) {
+	owner := authmw.OwnerFromContext(r.Context())
+	if owner == nil {
+		writeError(w, http.StatusUnauthorized, "not authenticated")
+		return
+	}
+	id := chi.URLParam(r, "restaurantID")
+	if !services.RestaurantBelongsToOwner(database.DB, id, owner.ID) {
+		writeError(w, http.StatusForbidden, "you do not own this restaurant")
+		return
+	}
+	var in dto.TableIn
+	if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	result, err := services.CreateTable(database.DB, id, in)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidInput) {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		writeError(w, http.StatusNotFound, "Restaurant not found")
+		return
+	}
+	writeJSON(w, http.StatusCreated, result)
+}
+
+func UpdateOwnedTable(w http.ResponseWriter, r *http.Request) {
+	owner := authmw.OwnerFromContext(r.Context())
+	if owner == nil {
+		writeError(w, http.StatusUnauthorized, "not authenticated")
+		return
+	}
+	id := chi.URLParam(r, "restaurantID")
+	if !services.RestaurantBelongsToOwner(database.DB, id, owner.ID) {
+		writeError(w, http.StatusForbidden, "you do not own this restaurant")
+		return
+	}
+	var in dto.TableIn
+	if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	result, err := services.UpdateTable(database.DB, id, chi.URLParam(r, "tableID"), in)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidInput) {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		writeError(w, http.StatusNotFound, "Table not found")
+		return
+	}
+	writeJSON(w, http.StatusOK, result)
+}
+
+func DeleteOwnedTable(w http.ResponseWriter, r *http.Request) {
+	owner := authmw.OwnerFromContext(r.Context())
+	if owner == nil {
+		writeError(w, http.StatusUnauthorized, "not authenticated")
+		return
+	}
+	id := chi.URLParam(r, "restaurantID")
+	if !services.RestaurantBelongsToOwner(database.DB, id, owner.ID) {
+		writeError(w, http.StatusForbidden, "you do not own this restaurant")
+		return
+	}
+	if err := services.DeleteTable(database.DB, id, chi.URLParam(r, "tableID")); err != nil {
+		writeError(w, http.StatusNotFound, "Table not found")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// --- Hours Overrides ---
+
+// ListOwnedHoursOverrides lists closures, special hours and blackouts.
+// Pass ?from=YYYY-MM-DD to skip past dates.
+func ListOwnedHoursOverrides(w http.ResponseWriter, r *http.Request) {
+	owner := authmw.OwnerFromContext(r.Context())
+	if owner == nil {
+		writeError(w, http.StatusUnauthorized, "not authenticated")
+		return
+	}
+	id := chi.URLParam(r, "restaurantID")
+	if !services.RestaurantBelongsToOwner(database.DB, id, owner.ID) {
+		writeError(w, http.StatusForbidden, "you do not own this restaurant")
+		return
+	}
+	results, err := services.ListHoursOverrides(database.DB, id, r.URL.Query().Get("from"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, results)
+}
+
+func CreateOwnedHoursOverride(w http.ResponseWriter, r *http.Request) {
+	owner := authmw.OwnerFromContext(r.Context())
+	if owner == nil {
+		writeError(w, http.StatusUnauthorized, "not authenticated")
+		return
+	}
+	id := chi.URLParam(r, "restaurantID")
+	if !services.RestaurantBelongsToOwner(database.DB, id, owner.ID) {
+		writeError(w, http.StatusForbidden, "you do not own this restaurant")
+		return
+	}
+	var in dto.HoursOverrideIn
+	if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	result, err := services.CreateHoursOverride(database.DB, id, in)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidInput) {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		writeError(w, http.StatusNotFound, "Restaurant not found")
+		return
+	}
+	writeJSON(w, http.StatusCreated, result)
+}
+
+func UpdateOwnedHoursOverride(w http.ResponseWriter, r *http.Request) {
+	owner := authmw.OwnerFromContext(r.Context())
+	if owner == nil {
+		writeError(w, http.StatusUnauthorized, "not authenticated")
+		return
+	}
+	id := chi.URLParam(r, "restaurantID")
+	if !services.RestaurantBelongsToOwner(database.DB, id, owner.ID) {
+		writeError(w, http.StatusForbidden, "you do not own this restaurant")
+		return
+	}
+	var in dto.HoursOverrideIn
+	if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	result, err := services.UpdateHoursOverride(database.DB, id, chi.URLParam(r, "overrideID"), in)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidInput) {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		writeError(w, http.StatusNotFound, "Hours override not found")
+		return
+	}
+	writeJSON(w, http.StatusOK, result)
+}
+
+func DeleteOwnedHoursOverride(w http.ResponseWriter, r *http.Request) {
+	owner := authmw.OwnerFromContext(r.Context())
+	if owner == nil {
+		writeError(w, http.StatusUnauthorized, "not authenticated")
+		return
+	}
+	id := chi.URLParam(r, "restaurantID")
+	if !services.RestaurantBelongsToOwner(database.DB, id, owner.ID) {
+		writeError(w, http.StatusForbidden, "you do not own this restaurant")
+		return
+	}
+	if err := services.DeleteHoursOverride(database.DB, id, chi.URLParam(r, "overrideID")); err != nil {
+		writeError(w, http.StatusNotFound, "Hours override not found")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// --- Webhooks ---
+
+// ListWebhooks lists the authenticated owner's webhook subscriptions.
+func ListWebhooks(w http.ResponseWriter, r *http.Request) {
+	owner := authmw.OwnerFromContext(r.Context())
+	if owner == nil {
+		writeError(w, http.StatusUnauthorized, "not authenticated")
+		return
+	}
+	results, err := services.ListWebhooks(database.DB, owner.ID)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "Failed to list webhooks")
+		return
+	}
+	writeJSON(w, http.StatusOK, results)
+}
+
+// CreateWebhook subscribes a URL to the owner's events. The response
+// carries the signing secret, which is not shown again.
+func CreateWebhook(w http.ResponseWriter, r *http.Request) {
+	owner := authmw.OwnerFromContext(r.Context())
+	if owner == nil {
+		writeError(w, http.StatusUnauthorized, "not authenticated")
+		return
+	}
+	var in dto.WebhookIn
+	if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	result, err := services.CreateWebhook(database.DB, owner.ID, in)
+	if err != nil {
+		writeReservationError(w, err, "Webhook not found")
+		return
+	}
+	writeJSON(w, http.StatusCreated, result)
+}
+
+func UpdateWebhook(w http.ResponseWriter, r *http.Request) {
+	owner := authmw.OwnerFromContext(r.Context())
+	if owner == nil {
+		writeError(w, http.StatusUnauthorized, "not authenticated")
+		return
+	}
+	var in dto.WebhookIn
+	if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	result, err := services.UpdateWebhook(database.DB, owner.ID, chi.URLParam(r, "webhookID"), in)
+	if err != nil {
+		writeReservationError(w, err, "Webhook not found")
+		return
+	}
+	writeJSON(w, http.StatusOK, result)
+}
+
+func DeleteWebhook(w http.ResponseWriter, r *http.Request) {
+	owner := authmw.OwnerFromContext(r.Context())
+	if owner == nil {
+		writeError(w, http.StatusUnauthorized, "not authenticated")
+		return
+	}
+	if err := services.DeleteWebhook(database.DB, owner.ID, chi.URLParam(r, "webhookID")); err != nil {
+		writeReservationError(w, err, "Webhook not found")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ListWebhookDeliveries pages through a webhook's delivery log, newest
+// first. Filter with ?status=pending|delivered|failed and ?event=.
+func ListWebhookDeliveries(w http.ResponseWriter, r *http.Request) {
+	owner := authmw.OwnerFromContext(r.Context())
+	if owner == nil {
+		writeError(w, http.StatusUnauthorized, "not authenticated")
+		return
+	}
+	params := r.URL.Query()
+	q := dto.DeliveryQuery{
+		Status: params.Get("status"),
+		Event:  params.Get("event"),
+		Cursor: params.Get("cursor"),
+		Limit:  20,
+	}
+	if l, err := strconv.Atoi(params.Get("limit")); err == nil && l > 0 && l <= 100 {
+		q.Limit = l
+	}
+	if o, err := strconv.Atoi(params.Get("offset")); err == nil && o >= 0 {
+		q.Offset = o
+	}
+	results, err := services.ListWebhookDeliveries(database.DB, owner.ID, chi.URLParam(r, "webhookID"), q)
+	if err != nil {
+		writeReservationError(w, err, "Webhook not found")
+		return
+	}
+	writeJSON(w, http.StatusOK, results)
+}
+
+// ReplayWebhookDelivery queues a delivered or failed delivery again.
+func ReplayWebhookDelivery(w http.ResponseWriter, r *http.Request) {
+	owner := authmw.OwnerFromContext(r.Context())
+	if owner == nil {
+		writeError(w, http.StatusUnauthorized, "not authenticated")
+		return
+	}
+	result, err := services.ReplayWebhookDelivery(database.DB, owner.ID,
+		chi.URLParam(r, "webhookID"), chi.URLParam(r, "deliveryID"))
+	if err != nil {
+		writeReservationError(w, err, "Delivery not found")
+		return
+	}
+	writeJSON(w, http.StatusAccepted, result)
+}
+
+// --- Guest Notifications ---
+
+// ListNotificationTemplates shows the confirmation, modification,
+// cancellation and reminder messages the restaurant sends guests.
+func ListNotificationTemplates(w http.ResponseWriter, r *http.Request) {
+	owner := authmw.OwnerFromContext(r.Context())
+	if owner == nil {
+		writeError(w, http.StatusUnauthorized, "not authenticated")
+		return
+	}
+	id := chi.URLParam(r, "restaurantID")
+	if !services.RestaurantBelongsToOwner(database.DB, id, owner.ID) {
+		writeError(w, http.StatusForbidden, "you do not own this restaurant")
+		return
+	}
+	results, err := services.ListNotificationTemplates(database.DB, id)
+	if err != nil {
+		writeError(w, http.StatusNotFound, "Restaurant not found")
+		return
+	}
+	writeJSON(w, http.StatusOK, results)
+}
+
+func SetNotificationTemplate(w http.ResponseWriter, r *http.Request) {
+	owner := authmw.OwnerFromContext(r.Context())
+	if owner == nil {
+		writeError(w, http.StatusUnauthorized, "not authenticated")
+		return
+	}
+	id := chi.URLParam(r, "restaurantID")
+	if !services.RestaurantBelongsToOwner(database.DB, id, owner.ID) {
+		writeError(w, http.StatusForbidden, "you do not own this restaurant")
+		return
+	}
+	var in dto.NotificationTemplateIn
+	if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	result, err := services.SetNotificationTemplate(database.DB, id, chi.URLParam(r, "kind"), in)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidInput) {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		writeError(w, http.StatusNotFound, "Restaurant not found")
+		return
+	}
+	writeJSON(w, http.StatusOK, result)
+}
+
+// DeleteNotificationTemplate goes back to the default message.
+func DeleteNotificationTemplate(w http.ResponseWriter, r *http.Request) {
+	owner := authmw.OwnerFromContext(r.Context())
+	if owner == nil {
+		writeError(w, http.StatusUnauthorized, "not authenticated")
+		return
+	}
+	id := chi.URLParam(r, "restaurantID")
+	if !services.RestaurantBelongsToOwner(database.DB, id, owner.ID) {
+		writeError(w, http.StatusForbidden, "you do not own this restaurant")
+		return
+	}
+	if err := services.DeleteNotificationTemplate(database.DB, id, chi.URLParam(r, "kind")); err != nil {
+		if errors.Is(err, services.ErrInvalidInput) {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		writeError(w, http.StatusNotFound, "No custom template for this message")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// --- Calendar Feed ---
+
+// RestaurantCalendar is the restaurant's iCalendar feed of upcoming
+// confirmed reservations. Calendar apps subscribe with the feed token in
+// ?token=; the owner's API key also works.
+func RestaurantCalendar(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "restaurantID")
+	ownerID := ""
+	if owner := authmw.OwnerFromContext(r.Context()); owner != nil {
+		ownerID = owner.ID
+	}
+	body, err := services.RestaurantCalendar(database.DB, id, r.URL.Query().Get("token"), ownerID)
+	if err != nil {
+		writeReservationError(w, err, "Restaurant not found")
+		return
+	}
+	writeCalendar(w, body, "")
+}
+
+// CreateCalendarFeed issues the restaurant's calendar feed URL. Calling it
+// again replaces the URL; the old one stops working.
+func CreateCalendarFeed(w http.ResponseWriter, r *http.Request) {
+	owner := authmw.OwnerFromContext(r.Context())
+	if owner == nil {
+		writeError(w, http.StatusUnauthorized, "not authenticated")
+		return
+	}
+	id := chi.URLParam(r, "restaurantID")
+	if !services.RestaurantBelongsToOwner(database.DB, id, owner.ID) {
+		writeError(w, http.StatusForbidden, "you do not own this restaurant")
+		return
+	}
+	token, err := services.CreateCalendarFeed(database.DB, id)
+	if err != nil {
+		writeError(w, http.StatusNotFound, "Restaurant not found")
+		return
+	}
+	writeJSON(w, http.StatusCreated, dto.CalendarFeedOut{
+		URL:   baseURL(r) + "/restaurants/" + id + "/calendar.ics?token=" + url.QueryEscape(token),
+		Token: token,
+	})
+}
+
+// DeleteCalendarFeed turns the restaurant's calendar feed off.
+func DeleteCalendarFeed(w http.ResponseWriter, r *http.Request) {
+	owner := authmw.OwnerFromContext(r.Context())
+	if owner == nil {
+		writeError(w, http.StatusUnauthorized, "not authenticated")
+		return
+	}
+	id := chi.URLParam(r, "restaurantID")
+	if !services.RestaurantBelongsToOwner(database.DB, id, owner.ID) {
+		writeError(w, http.StatusForbidden, "you do not own this restaurant")
+		return
+	}
+	if err := services.DeleteCalendarFeed(database.DB, id); err != nil {
+		writeError(w, http.StatusNotFound, "Restaurant not found")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,36 @@
+package handlers
+
+import (
+	"net/http/httptest"
+	"testing"
+)
+
+func TestBaseURL(t *testing.T) {
+	tests := []struct {
+		name    string
+		trust   bool
+		headers map[string]string
+		want    string
+	}{
+		{name: "direct", want: "http://api.example.com"},
+		{name: "https proxy", headers: map[string]string{"X-Forwarded-Proto": "https"}, want: "https://api.example.com"},
+		{name: "forwarded host ignored", headers: map[string]string{"X-Forwarded-Host": "evil.example"}, want: "http://api.example.com"},
+		{name: "forwarded host trusted", trust: true, headers: map[string]string{"X-Forwarded-Host": "agenteats.dev", "X-Forwarded-Proto": "https"}, want: "https://agenteats.dev"},
+		{name: "trusted without header", trust: true, want: "http://api.example.com"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			old := TrustForwardedHost
+			t.Cleanup(func() { TrustForwardedHost = old })
+			TrustForwardedHost = tt.trust
+
+			r := httptest.NewRequest("GET", "http://api.example.com/restaurants", nil)
+			for k, v := range tt.headers {
+				r.Header.Set(k, v)
+			}
+			if got := baseURL(r); got != tt.want {
+				t.Fatalf("baseURL = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -3,11 +3,13 @@ package mcpserver
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"strings"
 
 	"github.com/mark3labs/mcp-go/mcp"
 	"github.com/mark3labs/mcp-go/server"
+	"gorm.io/gorm"
 
 	"github.com/agenteats/agenteats/internal/database"
 	"github.com/agenteats/agenteats/internal/dto"
@@ -33,13 +35,21 @@ func NewServer() *server.MCPServer {
 	s.AddTool(searchRestaurantsTool(), handleSearchRestaurants)
 	s.AddTool(getRestaurantDetailsTool(), handleGetRestaurantDetails)
 	s.AddTool(getMenuTool(), handleGetMenu)
+	s.AddTool(searchDishesTool(), handleSearchDishes)
 	s.AddTool(getRecommendationsTool(), handleGetRecommendations)
 	s.AddTool(checkAvailabilityTool(), handleCheckAvailability)
 	s.AddTool(makeReservationTool(), handleMakeReservation)
+	s.AddTool(modifyReservationTool(), handleModifyReservation)
 	s.AddTool(cancelReservationTool(), handleCancelReservation)
+	s.AddTool(joinWaitlistTool(), handleJoinWaitlist)
+	s.AddTool(checkWaitlistTool(), handleCheckWaitlist)
+	s.AddTool(leaveWaitlistTool(), handleLeaveWaitlist)
+	s.AddTool(getReviewsTool(), handleGetReviews)
+	s.AddTool(submitReviewTool(), handleSubmitReview)
 
 	// Register resource
 	s.AddResource(serviceInfoResource(), handleServiceInfo)
+	s.AddResource(taxonomyResource(), handleTaxonomy)
 
 	return s
 }
@@ -49,20 +59,25 @@ func NewServer() *server.MCPServer {
 func searchRestaurantsTool() mcp.Tool {
 	return mcp.NewTool(
 		"search_restaurants",
-		mcp.WithDescription("Search for restaurants by name, cuisine, city, price range, or features. Returns a list of matching restaurants with id, name, cuisines, price_range, city, rating, and features."),
-		mcp.WithString("query", mcp.Description("Free-text search (searches name, description, cuisines)")),
+		mcp.WithDescription("Search for restaurants by name, cuisine, city, price range, or features. Pass latitude and longitude for \"near me\" searches. Returns a list of matching restaurants with id, name, cuisines, price_range, city, rating, and features."),
+		mcp.WithString("query", mcp.Description("Free-text search over name, cuisines, description and menu dishes; typo-tolerant. Results include matched_in and a highlight explaining the match")),
 		mcp.WithString("city", mcp.Description("Filter by city name")),
-		mcp.WithString("cuisine", mcp.Description("Filter by cuisine type (Italian, Japanese, Mexican, etc.)")),
+		mcp.WithString("cuisine", mcp.Description("Filter by cuisine (Italian, Japanese, Mexican, etc.). Synonyms like bbq or szechuan are accepted; see the agenteats://taxonomy resource")),
 		mcp.WithString("price_range", mcp.Description("Filter by price level: \"$\" (budget), \"$$\" (moderate), \"$$$\" (upscale), \"$$$$\" (fine dining)")),
-		mcp.WithString("features", mcp.Description("Comma-separated features: outdoor_seating, wifi, live_music, parking, delivery, takeout, wheelchair_accessible, pet_friendly")),
+		mcp.WithString("features", mcp.Description("Comma-separated features: outdoor_seating, wifi, live_music, parking, delivery, takeout, wheelchair_accessible, pet_friendly, private_dining, bar, brunch")),
+		mcp.WithBoolean("open_now", mcp.Description("Only return restaurants that are open right now, in their local time zone. A page may then hold fewer than limit results, even none; keep following next_cursor while it is present")),
+		mcp.WithNumber("latitude", mcp.Description("Latitude of the user's location; with longitude, returns nearby restaurants nearest first with distance_km")),
+		mcp.WithNumber("longitude", mcp.Description("Longitude of the user's location")),
+		mcp.WithNumber("radius_km", mcp.Description("Search radius around latitude/longitude in km (default 5, max 100)")),
 		mcp.WithNumber("limit", mcp.Description("Max results to return (1–20, default 10)")),
+		mcp.WithString("cursor", mcp.Description("next_cursor from a previous call, to get the next page of results")),
 	)
 }
 
 func getRestaurantDetailsTool() mcp.Tool {
 	return mcp.NewTool(
 		"get_restaurant_details",
-		mcp.WithDescription("Get complete details for a restaurant including description, full address, contact info, operating hours, and features."),
+		mcp.WithDescription("Get complete details for a restaurant including description, full address, contact info, operating hours, and features. special_hours lists upcoming holiday closures, changed hours and private-event blackouts that override the weekly hours — check it before suggesting a date."),
 		mcp.WithString("restaurant_id", mcp.Required(), mcp.Description("The restaurant's unique ID (obtained from search_restaurants)")),
 	)
 }
@@ -70,8 +85,27 @@ func getRestaurantDetailsTool() mcp.Tool {
 func getMenuTool() mcp.Tool {
 	return mcp.NewTool(
 		"get_menu",
-		mcp.WithDescription("Get the full menu for a restaurant, organized by category. Each item includes name, description, price, dietary labels (vegetarian, vegan, gluten_free, etc.), and availability."),
+		mcp.WithDescription("Get the full menu for a restaurant, organized by category. Each item includes name, description, price, dietary labels (vegetarian, vegan, gluten_free, etc.), allergens it contains and may contain, and availability. allergens_declared is false (and the allergen lists null) when the owner hasn't declared a dish's allergens, meaning they are unknown, not absent; remind guests with allergies to confirm with the restaurant. Items with sizes or add-ons list option_groups: each says whether a choice is required, how many options can be picked (max_selections 0 means no limit), and each option's price_delta to add to the item price and its own dietary labels and allergens."),
 		mcp.WithString("restaurant_id", mcp.Required(), mcp.Description("The restaurant's unique ID")),
+		mcp.WithString("exclude_allergens", mcp.Description("Comma-separated allergens the guest must avoid; the menu leaves out dishes that contain or may contain any of them, have an option containing one, or have undeclared allergens. Values: milk, eggs, fish, shellfish, tree_nuts, peanuts, wheat, soy, sesame, gluten, celery, mustard, lupin, mollusks, sulfites")),
+	)
+}
+
+func searchDishesTool() mcp.Tool {
+	return mcp.NewTool(
+		"search_dishes",
+		mcp.WithDescription("Search menu items across all restaurants, e.g. \"vegan ramen under $20\". Returns each matching dish with its price, dietary labels, allergens and calories, plus a summary of the restaurant serving it. Use this instead of calling get_menu on many restaurants."),
+		mcp.WithString("query", mcp.Description("Free-text search over dish name, description and category; typo-tolerant")),
+		mcp.WithString("city", mcp.Description("Filter by the restaurant's city")),
+		mcp.WithString("category", mcp.Description("Menu category: Appetizer, Main, Dessert, Drink, Side, etc.")),
+		mcp.WithString("dietary", mcp.Description("Comma-separated dietary labels the dish must all have: vegetarian, vegan, gluten_free, dairy_free, nut_free, halal, kosher, spicy, raw, organic")),
+		mcp.WithString("exclude_allergens", mcp.Description("Comma-separated allergens the guest must avoid; results leave out dishes that contain or may contain any of them, have an option containing one, or have undeclared allergens. Values: milk, eggs, fish, shellfish, tree_nuts, peanuts, wheat, soy, sesame, gluten, celery, mustard, lupin, mollusks, sulfites")),
+		mcp.WithNumber("min_price", mcp.Description("Minimum dish price")),
+		mcp.WithNumber("max_price", mcp.Description("Maximum dish price")),
+		mcp.WithNumber("max_calories", mcp.Description("Maximum calories (dishes without a calorie count are excluded)")),
+		mcp.WithString("price_range", mcp.Description("Filter by the restaurant's price level: \"$\", \"$$\", \"$$$\", \"$$$$\"")),
+		mcp.WithNumber("limit", mcp.Description("Max results to return (1–20, default 10)")),
+		mcp.WithString("cursor", mcp.Description("next_cursor from a previous call, to get the next page of results")),
 	)
 }
 
@@ -84,6 +118,7 @@ func getRecommendationsTool() mcp.Tool {
 		mcp.WithString("price_range", mcp.Description("Budget level: \"$\", \"$$\", \"$$$\", or \"$$$$\"")),
 		mcp.WithString("features", mcp.Description("Desired features (comma-separated): outdoor_seating, wifi, live_music, parking, delivery, takeout")),
 		mcp.WithString("dietary_needs", mcp.Description("Dietary requirements (comma-separated): vegetarian, vegan, gluten_free, dairy_free, nut_free, halal, kosher")),
+		mcp.WithString("exclude_allergens", mcp.Description("Comma-separated allergens the guest must avoid; only restaurants with dishes declared free of them, options included, are recommended. Values: milk, eggs, fish, shellfish, tree_nuts, peanuts, wheat, soy, sesame, gluten, celery, mustard, lupin, mollusks, sulfites")),
 		mcp.WithString("occasion", mcp.Description("Type of occasion: date_night, business, family, casual, celebration")),
 		mcp.WithNumber("limit", mcp.Description("Number of recommendations (1–20, default 5)")),
 	)
@@ -92,7 +127,7 @@ func getRecommendationsTool() mcp.Tool {
 func checkAvailabilityTool() mcp.Tool {
 	return mcp.NewTool(
 		"check_availability",
-		mcp.WithDescription("Check available reservation time slots at a restaurant for a given date and party size."),
+		mcp.WithDescription("Check available reservation time slots at a restaurant for a given date and party size. Slots follow the restaurant's operating hours for that day; if it is closed or fully booked, available_times is empty and reason explains why. When fully booked, offer join_waitlist."),
 		mcp.WithString("restaurant_id", mcp.Required(), mcp.Description("The restaurant's unique ID")),
 		mcp.WithString("date", mcp.Required(), mcp.Description("Date to check availability (YYYY-MM-DD format)")),
 		mcp.WithNumber("party_size", mcp.Description("Number of guests (1–20, default 2)")),
@@ -114,11 +149,80 @@ func makeReservationTool() mcp.Tool {
 	)
 }
 
+func modifyReservationTool() mcp.Tool {
+	return mcp.NewTool(
+		"modify_reservation",
+		mcp.WithDescription("Change the party size, date, time or special requests of an existing reservation without losing it. Only the fields you pass are changed. If the new slot is full the original booking is kept. Confirm the new details with the user before calling this."),
+		mcp.WithString("reservation_id", mcp.Required(), mcp.Description("The reservation's unique ID (from make_reservation)")),
+		mcp.WithString("manage_token", mcp.Required(), mcp.Description("The reservation's secret manage token (from make_reservation)")),
+		mcp.WithNumber("party_size", mcp.Description("New number of guests")),
+		mcp.WithString("date", mcp.Description("New date (YYYY-MM-DD)")),
+		mcp.WithString("time", mcp.Description("New time (HH:MM, 24-hour format)")),
+		mcp.WithString("special_requests", mcp.Description("New notes, replacing the existing ones")),
+	)
+}
+
 func cancelReservationTool() mcp.Tool {
 	return mcp.NewTool(
 		"cancel_reservation",
-		mcp.WithDescription("Cancel an existing reservation."),
+		mcp.WithDescription("Cancel an existing reservation. Requires the manage_token that make_reservation returned."),
+		mcp.WithString("reservation_id", mcp.Required(), mcp.Description("The reservation's unique ID (from make_reservation)")),
+		mcp.WithString("manage_token", mcp.Required(), mcp.Description("The reservation's secret manage token (from make_reservation)")),
+	)
+}
+
+func joinWaitlistTool() mcp.Tool {
+	return mcp.NewTool(
+		"join_waitlist",
+		mcp.WithDescription("Put the user on a restaurant's waitlist when check_availability shows no table. If a reservation in the time window is cancelled and the party fits, they are booked automatically. Confirm the details with the user before calling this."),
+		mcp.WithString("restaurant_id", mcp.Required(), mcp.Description("The restaurant's unique ID")),
+		mcp.WithString("customer_name", mcp.Required(), mcp.Description("Full name for the reservation")),
+		mcp.WithNumber("party_size", mcp.Required(), mcp.Description("Number of guests")),
+		mcp.WithString("date", mcp.Required(), mcp.Description("Desired date (YYYY-MM-DD)")),
+		mcp.WithString("time_from", mcp.Required(), mcp.Description("Earliest acceptable time (HH:MM, 24-hour format)")),
+		mcp.WithString("time_to", mcp.Description("Latest acceptable time (HH:MM); defaults to time_from")),
+		mcp.WithString("customer_email", mcp.Description("Optional email")),
+		mcp.WithString("customer_phone", mcp.Description("Optional phone number")),
+		mcp.WithString("special_requests", mcp.Description("Optional notes (allergies, high chair, birthday, etc.)")),
+	)
+}
+
+func checkWaitlistTool() mcp.Tool {
+	return mcp.NewTool(
+		"check_waitlist",
+		mcp.WithDescription("Check a waitlist entry: its place in line while waiting, or the reservation it was promoted to. A promoted reservation is managed with the same manage_token."),
+		mcp.WithString("waitlist_id", mcp.Required(), mcp.Description("The waitlist entry's ID (from join_waitlist)")),
+		mcp.WithString("manage_token", mcp.Required(), mcp.Description("The entry's secret manage token (from join_waitlist)")),
+	)
+}
+
+func leaveWaitlistTool() mcp.Tool {
+	return mcp.NewTool(
+		"leave_waitlist",
+		mcp.WithDescription("Take the user off a restaurant's waitlist. If the entry was already promoted, use cancel_reservation instead."),
+		mcp.WithString("waitlist_id", mcp.Required(), mcp.Description("The waitlist entry's ID (from join_waitlist)")),
+		mcp.WithString("manage_token", mcp.Required(), mcp.Description("The entry's secret manage token (from join_waitlist)")),
+	)
+}
+
+func getReviewsTool() mcp.Tool {
+	return mcp.NewTool(
+		"get_reviews",
+		mcp.WithDescription("Read a restaurant's guest reviews, newest first. Every review comes from a completed reservation. Each has a 1–5 star rating, optional comment, visit date and the owner's reply if any."),
+		mcp.WithString("restaurant_id", mcp.Required(), mcp.Description("The restaurant's unique ID")),
+		mcp.WithNumber("limit", mcp.Description("Max reviews to return (1–20, default 10)")),
+		mcp.WithString("cursor", mcp.Description("next_cursor from a previous call, to get the next page of reviews")),
+	)
+}
+
+func submitReviewTool() mcp.Tool {
+	return mcp.NewTool(
+		"submit_review",
+		mcp.WithDescription("Post the user's review of a past visit. Only reservations the restaurant has marked completed can be reviewed, once each. Requires the manage_token that make_reservation returned. Confirm the rating and wording with the user before calling this; reviews are public."),
 		mcp.WithString("reservation_id", mcp.Required(), mcp.Description("The reservation's unique ID (from make_reservation)")),
+		mcp.WithString("manage_token", mcp.Required(), mcp.Description("The reservation's secret manage token (from make_reservation)")),
+		mcp.WithNumber("rating", mcp.Required(), mcp.Description("Stars from 1 (poor) to 5 (excellent)")),
+		mcp.WithString("comment", mcp.Description("Optional review text (up to 2000 characters)")),
 	)
 }
 
@@ -131,6 +235,15 @@ func serviceInfoResource() mcp.Resource {
 	)
 }
 
+func taxonomyResource() mcp.Resource {
+	return mcp.NewResource(
+		"agenteats://taxonomy",
+		"AgentEats Taxonomy",
+		mcp.WithResourceDescription("The accepted cuisines, features, dietary labels and allergens, with the synonyms each one also accepts"),
+		mcp.WithMIMEType("application/json"),
+	)
+}
+
 // --- Tool Handlers ---
 
 func toJSON(v any) string {
@@ -138,6 +251,24 @@ func toJSON(v any) string {
 	return string(b)
 }
 
+// pageResult renders one page of a search, telling the agent how to get
+// the next page when there is one.
+func pageResult[T any](page *dto.Page[T], emptyMsg string) *mcp.CallToolResult {
+	out := map[string]any{
+		"count":          len(page.Items),
+		"items":          page.Items,
+		"total_estimate": page.TotalEstimate,
+	}
+	switch {
+	case len(page.Items) == 0:
+		out["message"] = emptyMsg
+	case page.NextCursor != "":
+		out["next_cursor"] = page.NextCursor
+		out["message"] = "More results are available: call again with the same arguments and cursor set to next_cursor."
+	}
+	return mcp.NewToolResultText(toJSON(out))
+}
+
 func splitCSVParam(s string) []string {
 	if s == "" {
 		return nil
@@ -151,26 +282,77 @@ func splitCSVParam(s string) []string {
 	return out
 }
 
+// reservationError converts a reservation service error into a tool error
+// whose structured content carries a machine-readable code, so agents can
+// tell "pick another time" apart from "fix the request".
+func reservationError(err error, notFound string) *mcp.CallToolResult {
+	code, msg := "internal_error", "Something went wrong saving the reservation. Please try again."
+	switch {
+	case errors.Is(err, services.ErrInvalidInput):
+		code, msg = "invalid_input", err.Error()
+	case errors.Is(err, services.ErrInvalidTransition):
+		code, msg = "invalid_transition", err.Error()
+	case errors.Is(err, services.ErrNotReviewable):
+		code, msg = "not_reviewable", err.Error()
+	case errors.Is(err, services.ErrSlotUnavailable):
+		code, msg = "slot_unavailable", err.Error()+". Use check_availability to find another time, or join_waitlist to be booked automatically if a table frees up."
+	case errors.Is(err, services.ErrPartyTooLarge):
+		code, msg = "party_too_large", err.Error()
+	case errors.Is(err, services.ErrRestaurantClosed):
+		code, msg = "restaurant_closed", err.Error()
+	case errors.Is(err, services.ErrRestaurantInactive):
+		code, msg = "restaurant_inactive", err.Error()
+	case errors.Is(err, services.ErrNotAuthorized):
+		code, msg = "not_authorized", "The manage_token does not match this reservation or waitlist entry."
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		code, msg = "not_found", notFound
+	}
+
+	result := mcp.NewToolResultStructured(map[string]any{
+		"error":   code,
+		"message": msg,
+	}, msg)
+	result.IsError = true
+	return result
+}
+
 func handleSearchRestaurants(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
 	query := request.GetString("query", "")
 	city := request.GetString("city", "")
 	cuisine := request.GetString("cuisine", "")
 	priceRange := request.GetString("price_range", "")
 	features := splitCSVParam(request.GetString("features", ""))
-	limit := request.GetInt("limit", 10)
-
-	results := services.ListRestaurants(database.DB, query, city, cuisine, priceRange, features, limit, 0)
-	if len(results) == 0 {
-		return mcp.NewToolResultText(toJSON(map[string]any{
-			"message": "No restaurants found matching your criteria.",
-			"results": []any{},
-		})), nil
+	limit := min(max(request.GetInt("limit", 10), 1), 20)
+
+	q := dto.RestaurantQuery{
+		Q:          query,
+		City:       city,
+		Cuisine:    cuisine,
+		PriceRange: priceRange,
+		Features:   features,
+		OpenNow:    request.GetBool("open_now", false),
+		RadiusKm:   request.GetFloat("radius_km", 0),
+		Cursor:     request.GetString("cursor", ""),
+		Limit:      limit,
+	}
+	args := request.GetArguments()
+	if _, ok := args["latitude"]; ok {
+		lat := request.GetFloat("latitude", 0)
+		q.Latitude = &lat
+	}
+	if _, ok := args["longitude"]; ok {
+		lng := request.GetFloat("longitude", 0)
+		q.Longitude = &lng
 	}
 
-	return mcp.NewToolResultText(toJSON(map[string]any{
-		"count":   len(results),
-		"results": results,
-	})), nil
+	results, err := services.ListRestaurants(database.DB, q)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidInput) {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+		return mcp.NewToolResultError("Failed to search restaurants"), nil
+	}
+	return pageResult(results, "No restaurants found matching your criteria."), nil
 }
 
 func handleGetRestaurantDetails(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
@@ -184,23 +366,68 @@ func handleGetRestaurantDetails(ctx context.Context, request mcp.CallToolRequest
 
 func handleGetMenu(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
 	id := request.GetString("restaurant_id", "")
-	result, err := services.GetMenu(database.DB, id)
+	result, err := services.GetMenu(database.DB, id, splitCSVParam(request.GetString("exclude_allergens", "")))
 	if err != nil {
+		if errors.Is(err, services.ErrInvalidInput) {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
 		return mcp.NewToolResultError(fmt.Sprintf("Restaurant not found: %s", id)), nil
 	}
 	return mcp.NewToolResultText(toJSON(result)), nil
 }
 
+func handleSearchDishes(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	q := dto.DishQuery{
+		Q:                request.GetString("query", ""),
+		City:             request.GetString("city", ""),
+		Category:         request.GetString("category", ""),
+		Dietary:          splitCSVParam(request.GetString("dietary", "")),
+		ExcludeAllergens: splitCSVParam(request.GetString("exclude_allergens", "")),
+		PriceRange:       request.GetString("price_range", ""),
+		Cursor:           request.GetString("cursor", ""),
+		Limit:            min(max(request.GetInt("limit", 10), 1), 20),
+	}
+	args := request.GetArguments()
+	if _, ok := args["min_price"]; ok {
+		v := request.GetFloat("min_price", 0)
+		q.MinPrice = &v
+	}
+	if _, ok := args["max_price"]; ok {
+		v := request.GetFloat("max_price", 0)
+		q.MaxPrice = &v
+	}
+	if _, ok := args["max_calories"]; ok {
+		v := request.GetInt("max_calories", 0)
+		q.MaxCalories = &v
+	}
+
+	results, err := services.SearchDishes(database.DB, q)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidInput) {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+		return mcp.NewToolResultError("Failed to search dishes"), nil
+	}
+	return pageResult(results, "No dishes found matching your criteria."), nil
+}
+
 func handleGetRecommendations(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
 	cuisine := request.GetString("cuisine", "")
 	city := request.GetString("city", "")
 	priceRange := request.GetString("price_range", "")
 	features := splitCSVParam(request.GetString("features", ""))
 	dietary := splitCSVParam(request.GetString("dietary_needs", ""))
+	allergens := splitCSVParam(request.GetString("exclude_allergens", ""))
 	occasion := request.GetString("occasion", "")
 	limit := request.GetInt("limit", 5)
 
-	results := services.GetRecommendations(database.DB, cuisine, city, priceRange, features, dietary, occasion, limit)
+	results, err := services.GetRecommendations(database.DB, cuisine, city, priceRange, features, dietary, allergens, occasion, limit)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidInput) {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+		return mcp.NewToolResultError("Failed to get recommendations"), nil
+	}
 	if len(results) == 0 {
 		return mcp.NewToolResultText(toJSON(map[string]any{
 			"message": "No recommendations found for your criteria.",
@@ -221,6 +448,9 @@ func handleCheckAvailability(ctx context.Context, request mcp.CallToolRequest) (
 
 	result, err := services.CheckAvailability(database.DB, id, date, partySize)
 	if err != nil {
+		if errors.Is(err, services.ErrInvalidInput) {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
 		return mcp.NewToolResultError(fmt.Sprintf("Restaurant not found: %s", id)), nil
 	}
 	return mcp.NewToolResultText(toJSON(result)), nil
@@ -241,20 +471,52 @@ func handleMakeReservation(ctx context.Context, request mcp.CallToolRequest) (*m
 
 	result, err := services.MakeReservation(database.DB, id, in)
 	if err != nil {
-		return mcp.NewToolResultError(fmt.Sprintf("Restaurant not found: %s", id)), nil
+		return reservationError(err, fmt.Sprintf("Restaurant not found: %s", id)), nil
+	}
+
+	return mcp.NewToolResultText(toJSON(map[string]any{
+		"message":     "Reservation confirmed! Share the manage_token with the user — it is shown only once and is needed to cancel or change the booking.",
+		"reservation": result,
+	})), nil
+}
+
+func handleModifyReservation(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	id := request.GetString("reservation_id", "")
+	token := request.GetString("manage_token", "")
+
+	args := request.GetArguments()
+	var in dto.ReservationUpdateIn
+	if _, ok := args["party_size"]; ok {
+		n := request.GetInt("party_size", 0)
+		in.PartySize = &n
+	}
+	if v, ok := args["date"].(string); ok {
+		in.Date = &v
+	}
+	if v, ok := args["time"].(string); ok {
+		in.Time = &v
+	}
+	if v, ok := args["special_requests"].(string); ok {
+		in.SpecialRequests = &v
+	}
+
+	result, err := services.ModifyReservation(database.DB, id, token, "", in)
+	if err != nil {
+		return reservationError(err, fmt.Sprintf("Reservation not found: %s", id)), nil
 	}
 
 	return mcp.NewToolResultText(toJSON(map[string]any{
-		"message":     "Reservation confirmed!",
+		"message":     "Reservation updated. The reservation ID and manage_token are unchanged.",
 		"reservation": result,
 	})), nil
 }
 
 func handleCancelReservation(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
 	id := request.GetString("reservation_id", "")
-	result, err := services.CancelReservation(database.DB, id)
+	token := request.GetString("manage_token", "")
+	result, err := services.CancelReservation(database.DB, id, token, "")
 	if err != nil {
-		return mcp.NewToolResultError(fmt.Sprintf("Reservation not found: %s", id)), nil
+		return reservationError(err, fmt.Sprintf("Reservation not found: %s", id)), nil
 	}
 
 	return mcp.NewToolResultText(toJSON(map[string]any{
@@ -263,18 +525,104 @@ func handleCancelReservation(ctx context.Context, request mcp.CallToolRequest) (
 	})), nil
 }
 
+func handleJoinWaitlist(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	id := request.GetString("restaurant_id", "")
+
+	in := dto.WaitlistIn{
+		CustomerName:    request.GetString("customer_name", ""),
+		CustomerEmail:   request.GetString("customer_email", ""),
+		CustomerPhone:   request.GetString("customer_phone", ""),
+		PartySize:       request.GetInt("party_size", 2),
+		Date:            request.GetString("date", ""),
+		TimeFrom:        request.GetString("time_from", ""),
+		TimeTo:          request.GetString("time_to", ""),
+		SpecialRequests: request.GetString("special_requests", ""),
+	}
+
+	result, err := services.JoinWaitlist(database.DB, id, in)
+	if err != nil {
+		return reservationError(err, fmt.Sprintf("Restaurant not found: %s", id)), nil
+	}
+
+	return mcp.NewToolResultText(toJSON(map[string]any{
+		"message":  "Added to the waitlist. Share the manage_token with the user — it is shown only once and is needed to check or leave the waitlist, and to manage the reservation if a table frees up.",
+		"waitlist": result,
+	})), nil
+}
+
+func handleCheckWaitlist(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	id := request.GetString("waitlist_id", "")
+	token := request.GetString("manage_token", "")
+	result, err := services.GetWaitlistEntry(database.DB, id, token, "")
+	if err != nil {
+		return reservationError(err, fmt.Sprintf("Waitlist entry not found: %s", id)), nil
+	}
+	return mcp.NewToolResultText(toJSON(result)), nil
+}
+
+func handleLeaveWaitlist(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	id := request.GetString("waitlist_id", "")
+	token := request.GetString("manage_token", "")
+	result, err := services.LeaveWaitlist(database.DB, id, token, "")
+	if err != nil {
+		return reservationError(err, fmt.Sprintf("Waitlist entry not found: %s", id)), nil
+	}
+
+	return mcp.NewToolResultText(toJSON(map[string]any{
+		"message":  "Removed from the waitlist.",
+		"waitlist": result,
+	})), nil
+}
+
+func handleGetReviews(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	id := request.GetString("restaurant_id", "")
+	q := dto.ReviewQuery{
+		Cursor: request.GetString("cursor", ""),
+		Limit:  min(max(request.GetInt("limit", 10), 1), 20),
+	}
+	results, err := services.ListReviews(database.DB, id, q, "")
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidInput) {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+		return mcp.NewToolResultError(fmt.Sprintf("Restaurant not found: %s", id)), nil
+	}
+	return pageResult(results, "This restaurant has no reviews yet."), nil
+}
+
+func handleSubmitReview(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	id := request.GetString("reservation_id", "")
+	token := request.GetString("manage_token", "")
+	in := dto.ReviewIn{
+		Rating:  request.GetInt("rating", 0),
+		Comment: request.GetString("comment", ""),
+	}
+	result, err := services.SubmitReview(database.DB, id, token, in)
+	if err != nil {
+		return reservationError(err, fmt.Sprintf("Reservation not found: %s", id)), nil
+	}
+	return mcp.NewToolResultText(toJSON(map[string]any{
+		"message": "Review published. Thank the user for sharing their experience.",
+		"review":  result,
+	})), nil
+}
+
 func handleServiceInfo(ctx context.Context, request mcp.ReadResourceRequest) ([]mcp.ResourceContents, error) {
 	info := map[string]any{
 		"service":     "AgentEats",
 		"version":     "0.1.0",
 		"description": "AI-agent-first restaurant directory. Search restaurants, browse menus, get personalized recommendations, and make reservations.",
 		"capabilities": []string{
-			"Search restaurants by cuisine, city, price, features",
+			"Search restaurants by free text, cuisine, city, location, price, features",
+			"Search dishes across restaurants by price, dietary labels and calories",
 			"Get full restaurant details and operating hours",
 			"Browse structured menus with dietary labels",
 			"Get personalized recommendations by occasion and preferences",
 			"Check reservation availability",
-			"Make and cancel reservations",
+			"Make, change and cancel reservations",
+			"Join a waitlist when a restaurant is fully booked",
+			"Read guest reviews and review completed visits",
+			"List the accepted cuisines, features, dietary labels and allergens (agenteats://taxonomy)",
 		},
 	}
 
@@ -286,3 +634,17 @@ func handleServiceInfo(ctx context.Context, request mcp.ReadResourceRequest) ([]
 		},
 	}, nil
 }
+
+func handleTaxonomy(ctx context.Context, request mcp.ReadResourceRequest) ([]mcp.ResourceContents, error) {
+	taxonomy, err := services.GetTaxonomy(database.DB)
+	if err != nil {
+		return nil, err
+	}
+	return []mcp.ResourceContents{
+		mcp.TextResourceContents{
+			URI:      request.Params.URI,
+			MIMEType: "application/json",
+			Text:     toJSON(taxonomy),
+		},
+	}, nil
+}
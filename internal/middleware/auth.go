@@ -48,3 +48,17 @@ func RequireAPIKey(next http.Handler) http.Handler {
 		next.ServeHTTP(w, r.WithContext(ctx))
 	})
 }
+
+// OptionalAPIKey attaches the owner to the request context when a valid
+// API key is supplied, but lets anonymous requests through. Routes that
+// accept either an owner or some other credential (such as a reservation
+// manage token) use this and check OwnerFromContext themselves.
+func OptionalAPIKey(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+		RequireAPIKey(next).ServeHTTP(w, r)
+	})
+}
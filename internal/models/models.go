@@ -4,7 +4,9 @@ import (
 	"crypto/rand"
 	"crypto/sha256"
 	"encoding/hex"
+	"strings"
 	"time"
+	"unicode/utf8"
 
 	"github.com/google/uuid"
 )
@@ -24,55 +26,97 @@ type ReservationStatus string
 
 const (
 	StatusConfirmed ReservationStatus = "confirmed"
+	StatusSeated    ReservationStatus = "seated"
 	StatusCancelled ReservationStatus = "cancelled"
 	StatusCompleted ReservationStatus = "completed"
 	StatusNoShow    ReservationStatus = "no_show"
 )
 
+// statusTransitions is the reservation state machine: the statuses each
+// status may move to. Cancelled, completed and no-show are final.
+var statusTransitions = map[ReservationStatus][]ReservationStatus{
+	StatusConfirmed: {StatusSeated, StatusNoShow, StatusCancelled},
+	StatusSeated:    {StatusCompleted},
+}
+
+// Valid reports whether s is a known reservation status.
+func (s ReservationStatus) Valid() bool {
+	switch s {
+	case StatusConfirmed, StatusSeated, StatusCancelled, StatusCompleted, StatusNoShow:
+		return true
+	}
+	return false
+}
+
+// CanTransition reports whether a reservation in status s may move to next.
+func (s ReservationStatus) CanTransition(next ReservationStatus) bool {
+	for _, allowed := range statusTransitions[s] {
+		if allowed == next {
+			return true
+		}
+	}
+	return false
+}
+
+// HoldsSeats reports whether a reservation in status s occupies capacity.
+func (s ReservationStatus) HoldsSeats() bool {
+	return s == StatusConfirmed || s == StatusSeated
+}
+
 // --- Models ---
 
 // Owner represents a restaurant owner with API key authentication.
 type Owner struct {
-	ID           string    `gorm:"primaryKey;size:36" json:"id"`
-	Name         string    `gorm:"size:200;not null" json:"name"`
-	Email        string    `gorm:"size:200;not null;uniqueIndex" json:"email"`
-	APIKeyHash   string    `gorm:"size:64;not null;uniqueIndex" json:"-"`
-	IsActive     bool      `gorm:"not null;default:true" json:"is_active"`
-	CreatedAt    time.Time `json:"created_at"`
-	UpdatedAt    time.Time `json:"updated_at"`
+	ID         string    `gorm:"primaryKey;size:36" json:"id"`
+	Name       string    `gorm:"size:200;not null" json:"name"`
+	Email      string    `gorm:"size:200;not null;uniqueIndex" json:"email"`
+	APIKeyHash string    `gorm:"size:64;not null;uniqueIndex" json:"-"`
+	IsActive   bool      `gorm:"not null;default:true" json:"is_active"`
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
 
 	Restaurants []Restaurant `gorm:"foreignKey:OwnerID;constraint:OnDelete:CASCADE" json:"restaurants,omitempty"`
 }
 
 // Restaurant represents a restaurant listing.
 type Restaurant struct {
-	ID          string     `gorm:"primaryKey;size:36" json:"id"`
-	OwnerID     string     `gorm:"size:36;index" json:"owner_id,omitempty"`
-	Name        string     `gorm:"size:200;not null;index" json:"name"`
-	Description string     `gorm:"type:text" json:"description,omitempty"`
-	Cuisines    string     `gorm:"size:500" json:"cuisines"` // comma-separated
-	PriceRange  PriceRange `gorm:"size:10;not null;default:'$$'" json:"price_range"`
-	Address     string     `gorm:"size:500;not null" json:"address"`
-	City        string     `gorm:"size:100;not null;index" json:"city"`
-	State       string     `gorm:"size:100" json:"state,omitempty"`
-	ZipCode     string     `gorm:"size:20" json:"zip_code,omitempty"`
-	Country     string     `gorm:"size:100;not null;default:'US'" json:"country"`
-	Latitude    *float64   `json:"latitude,omitempty"`
-	Longitude   *float64   `json:"longitude,omitempty"`
-	Phone       string     `gorm:"size:30" json:"phone,omitempty"`
-	Email       string     `gorm:"size:200" json:"email,omitempty"`
-	Website     string     `gorm:"size:500" json:"website,omitempty"`
-	Features    string     `gorm:"size:500" json:"features"` // comma-separated
-	TotalSeats  int        `gorm:"not null;default:50" json:"total_seats"`
-	Rating      *float64   `json:"rating,omitempty"`
-	ReviewCount int        `gorm:"not null;default:0" json:"review_count"`
-	IsActive    bool       `gorm:"not null;default:true" json:"is_active"`
-	CreatedAt   time.Time  `json:"created_at"`
-	UpdatedAt   time.Time  `json:"updated_at"`
-
-	Hours      []OperatingHours `gorm:"foreignKey:RestaurantID;constraint:OnDelete:CASCADE" json:"hours,omitempty"`
-	MenuItems  []MenuItem       `gorm:"foreignKey:RestaurantID;constraint:OnDelete:CASCADE" json:"menu_items,omitempty"`
-	Reservations []Reservation  `gorm:"foreignKey:RestaurantID;constraint:OnDelete:CASCADE" json:"reservations,omitempty"`
+	ID                 string     `gorm:"primaryKey;size:36" json:"id"`
+	OwnerID            string     `gorm:"size:36;index" json:"owner_id,omitempty"`
+	Name               string     `gorm:"size:200;not null;index" json:"name"`
+	Description        string     `gorm:"type:text" json:"description,omitempty"`
+	Cuisines           string     `gorm:"size:500" json:"cuisines"` // comma-separated cuisine labels, mirrors CuisineTerms
+	PriceRange         PriceRange `gorm:"size:10;not null;default:'$$'" json:"price_range"`
+	Address            string     `gorm:"size:500;not null" json:"address"`
+	City               string     `gorm:"size:100;not null;index" json:"city"`
+	State              string     `gorm:"size:100" json:"state,omitempty"`
+	ZipCode            string     `gorm:"size:20" json:"zip_code,omitempty"`
+	Country            string     `gorm:"size:100;not null;default:'US'" json:"country"`
+	Timezone           string     `gorm:"size:64" json:"timezone"` // IANA name, e.g. America/New_York
+	Latitude           *float64   `gorm:"index:idx_restaurants_location" json:"latitude,omitempty"`
+	Longitude          *float64   `gorm:"index:idx_restaurants_location" json:"longitude,omitempty"`
+	Phone              string     `gorm:"size:30" json:"phone,omitempty"`
+	Email              string     `gorm:"size:200" json:"email,omitempty"`
+	Website            string     `gorm:"size:500" json:"website,omitempty"`
+	Features           string     `gorm:"size:500" json:"features"` // comma-separated feature slugs, mirrors FeatureTerms
+	TotalSeats         int        `gorm:"not null;default:50" json:"total_seats"`
+	LastSeatingMinutes int        `gorm:"not null;default:30" json:"last_seating_minutes"` // last table seated this long before close
+	TurnMinutes        int        `gorm:"not null;default:90" json:"turn_minutes"`         // typical dining duration
+	Rating             *float64   `json:"rating,omitempty"`
+	ReviewCount        int        `gorm:"not null;default:0" json:"review_count"`
+	IsActive           bool       `gorm:"not null;default:true" json:"is_active"`
+	CalendarTokenHash  string     `gorm:"size:64" json:"-"`                // SHA-256 of the owner's calendar feed token
+	TaxonomyBackfilled bool       `gorm:"not null;default:false" json:"-"` // set once BackfillTaxonomy has linked the legacy columns
+	CreatedAt          time.Time  `json:"created_at"`
+	UpdatedAt          time.Time  `json:"updated_at"`
+
+	Hours          []OperatingHours `gorm:"foreignKey:RestaurantID;constraint:OnDelete:CASCADE" json:"hours,omitempty"`
+	TurnTimes      []TurnTime       `gorm:"foreignKey:RestaurantID;constraint:OnDelete:CASCADE" json:"turn_times,omitempty"`
+	Tables         []DiningTable    `gorm:"foreignKey:RestaurantID;constraint:OnDelete:CASCADE" json:"tables,omitempty"`
+	HoursOverrides []HoursOverride  `gorm:"foreignKey:RestaurantID;constraint:OnDelete:CASCADE" json:"hours_overrides,omitempty"`
+	MenuItems      []MenuItem       `gorm:"foreignKey:RestaurantID;constraint:OnDelete:CASCADE" json:"menu_items,omitempty"`
+	Reservations   []Reservation    `gorm:"foreignKey:RestaurantID;constraint:OnDelete:CASCADE" json:"reservations,omitempty"`
+	CuisineTerms   []TaxonomyTerm   `gorm:"many2many:restaurant_cuisines" json:"-"`
+	FeatureTerms   []TaxonomyTerm   `gorm:"many2many:restaurant_features" json:"-"`
 }
 
 // OperatingHours represents the hours for one day of the week.
@@ -85,20 +129,119 @@ type OperatingHours struct {
 	IsClosed     bool   `gorm:"not null;default:false" json:"is_closed"`
 }
 
+// TurnTime overrides the restaurant's TurnMinutes for a band of party sizes.
+// A MaxPartySize of 0 means the band has no upper bound.
+type TurnTime struct {
+	ID           uint   `gorm:"primaryKey;autoIncrement" json:"id"`
+	RestaurantID string `gorm:"size:36;not null;index" json:"restaurant_id"`
+	MinPartySize int    `gorm:"not null" json:"min_party_size"`
+	MaxPartySize int    `gorm:"not null;default:0" json:"max_party_size"`
+	Minutes      int    `gorm:"not null" json:"minutes"`
+}
+
+type OverrideKind string
+
+const (
+	OverrideClosed   OverrideKind = "closed"   // closed all day
+	OverrideHours    OverrideKind = "hours"    // open, but with different hours
+	OverrideBlackout OverrideKind = "blackout" // open, but no seatings in a window (e.g. a private event)
+)
+
+// HoursOverride replaces or restricts a restaurant's weekly hours on one
+// date. A date has at most one closed or hours override, plus any number of
+// blackouts. For hours, OpenTime/CloseTime are the day's hours (overnight
+// allowed); for blackouts they bound the window in which no seating starts.
+type HoursOverride struct {
+	ID           string       `gorm:"primaryKey;size:36" json:"id"`
+	RestaurantID string       `gorm:"size:36;not null;index" json:"restaurant_id"`
+	Date         string       `gorm:"size:10;not null;index" json:"date"` // YYYY-MM-DD
+	Kind         OverrideKind `gorm:"size:20;not null" json:"kind"`
+	OpenTime     string       `gorm:"size:5" json:"open_time,omitempty"`  // HH:MM
+	CloseTime    string       `gorm:"size:5" json:"close_time,omitempty"` // HH:MM
+	Note         string       `gorm:"size:200" json:"note,omitempty"`     // e.g. "Christmas Day"
+	CreatedAt    time.Time    `json:"created_at"`
+	UpdatedAt    time.Time    `json:"updated_at"`
+}
+
+// DiningTable is a physical table in a restaurant's floor plan. Restaurants
+// with active tables are booked by table assignment instead of TotalSeats.
+type DiningTable struct {
+	ID           string    `gorm:"primaryKey;size:36" json:"id"`
+	RestaurantID string    `gorm:"size:36;not null;index" json:"restaurant_id"`
+	Name         string    `gorm:"size:50;not null" json:"name"` // e.g. "T4", "Patio 2"
+	MinCapacity  int       `gorm:"not null;default:1" json:"min_capacity"`
+	MaxCapacity  int       `gorm:"not null" json:"max_capacity"`
+	Section      string    `gorm:"size:100" json:"section,omitempty"`
+	IsCombinable bool      `gorm:"not null;default:false" json:"is_combinable"` // can be pushed together with others in its section
+	IsActive     bool      `gorm:"not null;default:true" json:"is_active"`
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
+
 // MenuItem represents a single dish on a restaurant's menu.
 type MenuItem struct {
-	ID            string   `gorm:"primaryKey;size:36" json:"id"`
-	RestaurantID  string   `gorm:"size:36;not null;index" json:"restaurant_id"`
-	Category      string   `gorm:"size:100;not null;default:'Main'" json:"category"`
-	Name          string   `gorm:"size:200;not null" json:"name"`
-	Description   string   `gorm:"type:text" json:"description,omitempty"`
-	Price         float64  `gorm:"not null" json:"price"`
-	Currency      string   `gorm:"size:3;not null;default:'USD'" json:"currency"`
-	DietaryLabels string   `gorm:"size:300" json:"dietary_labels"` // comma-separated
-	IsAvailable   bool     `gorm:"not null;default:true" json:"is_available"`
-	IsPopular     bool     `gorm:"not null;default:false" json:"is_popular"`
-	ImageURL      string   `gorm:"size:500" json:"image_url,omitempty"`
-	Calories      *int     `json:"calories,omitempty"`
+	ID            string  `gorm:"primaryKey;size:36" json:"id"`
+	RestaurantID  string  `gorm:"size:36;not null;index" json:"restaurant_id"`
+	ExternalID    string  `gorm:"size:100;index" json:"external_id,omitempty"` // owner's own ID, e.g. a POS SKU; unique per restaurant when set
+	Category      string  `gorm:"size:100;not null;default:'Main'" json:"category"`
+	Name          string  `gorm:"size:200;not null" json:"name"`
+	Description   string  `gorm:"type:text" json:"description,omitempty"`
+	Price         float64 `gorm:"not null" json:"price"`
+	Currency      string  `gorm:"size:3;not null;default:'USD'" json:"currency"`
+	DietaryLabels string  `gorm:"size:300" json:"dietary_labels"` // comma-separated dietary slugs, mirrors DietaryTerms
+	IsAvailable   bool    `gorm:"not null;default:true" json:"is_available"`
+	IsPopular     bool    `gorm:"not null;default:false" json:"is_popular"`
+	ImageURL      string  `gorm:"size:500" json:"image_url,omitempty"`
+	Calories      *int    `json:"calories,omitempty"`
+	SortOrder     int     `gorm:"not null;default:0" json:"sort_order"` // position within its category; ties sort by name
+
+	Allergens           string `gorm:"size:300" json:"allergens"`                        // comma-separated allergen slugs the dish contains, mirrors AllergenTerms
+	MayContainAllergens string `gorm:"size:300" json:"may_contain_allergens"`            // comma-separated allergen slugs it may contain traces of, mirrors MayContainTerms
+	AllergensDeclared   bool   `gorm:"not null;default:false" json:"allergens_declared"` // whether the owner has declared the dish's allergens; until then, empty lists mean unknown
+	TaxonomyBackfilled  bool   `gorm:"not null;default:false" json:"-"`                  // set once BackfillTaxonomy has linked DietaryLabels
+
+	DietaryTerms    []TaxonomyTerm    `gorm:"many2many:menu_item_dietary_labels" json:"-"`
+	AllergenTerms   []TaxonomyTerm    `gorm:"many2many:menu_item_allergens" json:"-"`
+	MayContainTerms []TaxonomyTerm    `gorm:"many2many:menu_item_may_contain_allergens" json:"-"`
+	OptionGroups    []MenuOptionGroup `gorm:"foreignKey:MenuItemID" json:"option_groups,omitempty"`
+}
+
+// MenuOptionGroup is a choice a guest makes when ordering an item, such as
+// its size or toppings. A guest picks between MinSelections and
+// MaxSelections of its options; MaxSelections 0 means no limit.
+type MenuOptionGroup struct {
+	ID            string `gorm:"primaryKey;size:36" json:"id"`
+	MenuItemID    string `gorm:"size:36;not null;index" json:"menu_item_id"`
+	Name          string `gorm:"size:100;not null" json:"name"`
+	Required      bool   `gorm:"not null;default:false" json:"required"`
+	MinSelections int    `gorm:"not null;default:0" json:"min_selections"`
+	MaxSelections int    `gorm:"not null;default:0" json:"max_selections"`
+	SortOrder     int    `gorm:"not null;default:0" json:"sort_order"`
+
+	Options []MenuOption `gorm:"foreignKey:GroupID" json:"options"`
+}
+
+// MenuOption is one choice in a MenuOptionGroup. PriceDelta is added to the
+// item's price when it is chosen, and may be negative.
+type MenuOption struct {
+	ID            string  `gorm:"primaryKey;size:36" json:"id"`
+	GroupID       string  `gorm:"size:36;not null;index" json:"group_id"`
+	Name          string  `gorm:"size:100;not null" json:"name"`
+	PriceDelta    float64 `gorm:"not null;default:0" json:"price_delta"`
+	DietaryLabels string  `gorm:"size:300" json:"dietary_labels"` // comma-separated dietary slugs
+	Allergens     string  `gorm:"size:300" json:"allergens"`      // comma-separated allergen slugs choosing it adds, mirrors AllergenTerms
+	SortOrder     int     `gorm:"not null;default:0" json:"sort_order"`
+
+	AllergenTerms []TaxonomyTerm `gorm:"many2many:menu_option_allergens" json:"-"`
+}
+
+// MenuCategory records where a menu category appears. Categories without a
+// row come after ordered ones, alphabetically.
+type MenuCategory struct {
+	ID           uint   `gorm:"primaryKey" json:"-"`
+	RestaurantID string `gorm:"size:36;not null;uniqueIndex:idx_menu_categories_name" json:"-"`
+	Name         string `gorm:"size:100;not null;uniqueIndex:idx_menu_categories_name" json:"name"`
+	SortOrder    int    `gorm:"not null" json:"sort_order"`
 }
 
 // Reservation represents a table reservation.
@@ -109,13 +252,122 @@ type Reservation struct {
 	CustomerEmail   string            `gorm:"size:200" json:"customer_email,omitempty"`
 	CustomerPhone   string            `gorm:"size:30" json:"customer_phone,omitempty"`
 	PartySize       int               `gorm:"not null" json:"party_size"`
-	Date            string            `gorm:"size:10;not null" json:"date"`  // YYYY-MM-DD
-	Time            string            `gorm:"size:5;not null" json:"time"`   // HH:MM
+	Date            string            `gorm:"size:10;not null" json:"date"` // YYYY-MM-DD
+	Time            string            `gorm:"size:5;not null" json:"time"`  // HH:MM
 	Status          ReservationStatus `gorm:"size:20;not null;default:'confirmed'" json:"status"`
 	SpecialRequests string            `gorm:"type:text" json:"special_requests,omitempty"`
+	TableIDs        string            `gorm:"size:500" json:"table_ids,omitempty"` // comma-separated DiningTable IDs
+	ManageTokenHash string            `gorm:"size:64;index" json:"-"`              // SHA-256 of the guest's manage token
+	SeatedAt        *time.Time        `json:"seated_at,omitempty"`
+	CompletedAt     *time.Time        `json:"completed_at,omitempty"`
+	NoShowAt        *time.Time        `json:"no_show_at,omitempty"`
+	CancelledAt     *time.Time        `json:"cancelled_at,omitempty"`
+	ReminderSentAt  *time.Time        `json:"reminder_sent_at,omitempty"` // cleared when the date or time changes
 	CreatedAt       time.Time         `json:"created_at"`
 }
 
+// ReservationStatusChange records a reservation status transition and who
+// made it.
+type ReservationStatusChange struct {
+	ID            uint              `gorm:"primaryKey;autoIncrement" json:"id"`
+	ReservationID string            `gorm:"size:36;not null;index" json:"reservation_id"`
+	FromStatus    ReservationStatus `gorm:"size:20;not null" json:"from_status"`
+	ToStatus      ReservationStatus `gorm:"size:20;not null" json:"to_status"`
+	ChangedBy     string            `gorm:"size:20;not null" json:"changed_by"` // guest or owner
+	OwnerID       string            `gorm:"size:36" json:"owner_id,omitempty"`  // set when changed by the owner
+	CreatedAt     time.Time         `json:"created_at"`
+}
+
+// ReservationChange records a reservation's booking details before a
+// modification, so owners can see what a guest changed and when.
+type ReservationChange struct {
+	ID                      uint      `gorm:"primaryKey;autoIncrement" json:"id"`
+	ReservationID           string    `gorm:"size:36;not null;index" json:"reservation_id"`
+	ChangedBy               string    `gorm:"size:20;not null" json:"changed_by"` // guest or owner
+	PreviousPartySize       int       `gorm:"not null" json:"previous_party_size"`
+	PreviousDate            string    `gorm:"size:10;not null" json:"previous_date"`
+	PreviousTime            string    `gorm:"size:5;not null" json:"previous_time"`
+	PreviousSpecialRequests string    `gorm:"type:text" json:"previous_special_requests,omitempty"`
+	PreviousTableIDs        string    `gorm:"size:500" json:"previous_table_ids,omitempty"`
+	CreatedAt               time.Time `json:"created_at"`
+}
+
+type ReviewStatus string
+
+const (
+	ReviewPublished ReviewStatus = "published"
+	ReviewHidden    ReviewStatus = "hidden"
+)
+
+// Valid reports whether s is a known review status.
+func (s ReviewStatus) Valid() bool {
+	return s == ReviewPublished || s == ReviewHidden
+}
+
+// Review is a guest's rating of a completed reservation. There is at most
+// one per reservation. Only published reviews are shown publicly and count
+// towards the restaurant's Rating and ReviewCount; the owner can hide a
+// review, giving a ModerationNote, and reply to it.
+type Review struct {
+	ID             string       `gorm:"primaryKey;size:36" json:"id"`
+	RestaurantID   string       `gorm:"size:36;not null;index" json:"restaurant_id"`
+	ReservationID  string       `gorm:"size:36;not null;uniqueIndex" json:"reservation_id"`
+	AuthorName     string       `gorm:"size:200;not null" json:"author_name"` // first name and last initial
+	Rating         int          `gorm:"not null" json:"rating"`               // 1–5 stars
+	Comment        string       `gorm:"type:text" json:"comment,omitempty"`
+	VisitDate      string       `gorm:"size:10;not null" json:"visit_date"` // YYYY-MM-DD
+	Status         ReviewStatus `gorm:"size:20;not null;default:'published';index" json:"status"`
+	ModerationNote string       `gorm:"type:text" json:"moderation_note,omitempty"`
+	OwnerReply     string       `gorm:"type:text" json:"owner_reply,omitempty"`
+	RepliedAt      *time.Time   `json:"replied_at,omitempty"`
+	CreatedAt      time.Time    `json:"created_at"`
+	UpdatedAt      time.Time    `json:"updated_at"`
+}
+
+// ReviewAuthorName shortens a customer name to the first name and last
+// initial shown on reviews, e.g. "Alice Johnson" to "Alice J.".
+func ReviewAuthorName(name string) string {
+	parts := strings.Fields(name)
+	switch len(parts) {
+	case 0:
+		return "Guest"
+	case 1:
+		return parts[0]
+	}
+	last, _ := utf8.DecodeRuneInString(parts[len(parts)-1])
+	return parts[0] + " " + string(last) + "."
+}
+
+type WaitlistStatus string
+
+const (
+	WaitlistWaiting  WaitlistStatus = "waiting"
+	WaitlistPromoted WaitlistStatus = "promoted"
+	WaitlistLeft     WaitlistStatus = "left"
+)
+
+// WaitlistEntry is a party waiting for a table to free up at a restaurant on
+// a date, at any seating time between TimeFrom and TimeTo. When it is
+// promoted, ReservationID points at the confirmed reservation, which shares
+// the entry's manage token.
+type WaitlistEntry struct {
+	ID              string         `gorm:"primaryKey;size:36" json:"id"`
+	RestaurantID    string         `gorm:"size:36;not null;index" json:"restaurant_id"`
+	CustomerName    string         `gorm:"size:200;not null" json:"customer_name"`
+	CustomerEmail   string         `gorm:"size:200" json:"customer_email,omitempty"`
+	CustomerPhone   string         `gorm:"size:30" json:"customer_phone,omitempty"`
+	PartySize       int            `gorm:"not null" json:"party_size"`
+	Date            string         `gorm:"size:10;not null;index" json:"date"` // YYYY-MM-DD
+	TimeFrom        string         `gorm:"size:5;not null" json:"time_from"`   // HH:MM
+	TimeTo          string         `gorm:"size:5;not null" json:"time_to"`     // HH:MM
+	SpecialRequests string         `gorm:"type:text" json:"special_requests,omitempty"`
+	Status          WaitlistStatus `gorm:"size:20;not null;default:'waiting'" json:"status"`
+	ReservationID   string         `gorm:"size:36" json:"reservation_id,omitempty"`
+	ManageTokenHash string         `gorm:"size:64;index" json:"-"`
+	CreatedAt       time.Time      `json:"created_at"`
+	UpdatedAt       time.Time      `json:"updated_at"`
+}
+
 // NewID generates a new UUID string.
 func NewID() string {
 	return uuid.New().String()
@@ -133,6 +385,31 @@ func GenerateAPIKey() (raw string, hash string) {
 	return
 }
 
+// GenerateManageToken creates a random secret that lets a guest cancel or
+// modify their reservation. Like API keys, only the hash is stored.
+func GenerateManageToken() (raw string, hash string) {
+	b := make([]byte, 24)
+	if _, err := rand.Read(b); err != nil {
+		panic("crypto/rand failed: " + err.Error())
+	}
+	raw = "rm_" + hex.EncodeToString(b) // rm_ = reservation manage
+	hash = HashAPIKey(raw)
+	return
+}
+
+// GenerateCalendarToken creates the secret in a restaurant's calendar feed
+// URL. Calendar apps can't send an API key, so the feed URL carries this
+// token instead; only its hash is stored.
+func GenerateCalendarToken() (raw string, hash string) {
+	b := make([]byte, 24)
+	if _, err := rand.Read(b); err != nil {
+		panic("crypto/rand failed: " + err.Error())
+	}
+	raw = "cal_" + hex.EncodeToString(b)
+	hash = HashAPIKey(raw)
+	return
+}
+
 // HashAPIKey returns the SHA-256 hex digest of an API key.
 func HashAPIKey(key string) string {
 	h := sha256.Sum256([]byte(key))
@@ -0,0 +1,39 @@
+package models
+
+import "testing"
+
+func TestCanTransition(t *testing.T) {
+	statuses := []ReservationStatus{StatusConfirmed, StatusSeated, StatusCancelled, StatusCompleted, StatusNoShow}
+	allowed := map[[2]ReservationStatus]bool{
+		{StatusConfirmed, StatusSeated}:    true,
+		{StatusConfirmed, StatusNoShow}:    true,
+		{StatusConfirmed, StatusCancelled}: true,
+		{StatusSeated, StatusCompleted}:    true,
+	}
+	for _, from := range statuses {
+		for _, to := range statuses {
+			want := allowed[[2]ReservationStatus{from, to}]
+			if got := from.CanTransition(to); got != want {
+				t.Errorf("%s -> %s: CanTransition = %v, want %v", from, to, got, want)
+			}
+		}
+		if from.CanTransition("unknown") {
+			t.Errorf("%s -> unknown is allowed", from)
+		}
+	}
+}
+
+func TestHoldsSeats(t *testing.T) {
+	tests := map[ReservationStatus]bool{
+		StatusConfirmed: true,
+		StatusSeated:    true,
+		StatusCancelled: false,
+		StatusCompleted: false,
+		StatusNoShow:    false,
+	}
+	for s, want := range tests {
+		if got := s.HoldsSeats(); got != want {
+			t.Errorf("%s: HoldsSeats = %v, want %v", s, got, want)
+		}
+	}
+}
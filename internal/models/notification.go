@@ -0,0 +1,74 @@
+package models
+
+import "time"
+
+// NotificationKind is a message AgentEats sends a guest about their
+// reservation.
+type NotificationKind string
+
+const (
+	NotifyConfirmation NotificationKind = "confirmation"
+	NotifyModification NotificationKind = "modification"
+	NotifyCancellation NotificationKind = "cancellation"
+	NotifyReminder     NotificationKind = "reminder"     // the day before the visit
+	NotifyManageToken  NotificationKind = "manage_token" // a token issued to a reservation booked before tokens existed
+)
+
+// NotificationKinds lists every kind, in the order they are documented.
+var NotificationKinds = []NotificationKind{
+	NotifyConfirmation,
+	NotifyModification,
+	NotifyCancellation,
+	NotifyReminder,
+	NotifyManageToken,
+}
+
+// Valid reports whether k is a known notification kind.
+func (k NotificationKind) Valid() bool {
+	for _, known := range NotificationKinds {
+		if k == known {
+			return true
+		}
+	}
+	return false
+}
+
+// NotificationTemplate replaces the default subject and body of one kind
+// of message for a restaurant. Both are text/template sources rendered with
+// notify.TemplateData.
+type NotificationTemplate struct {
+	ID           uint             `gorm:"primaryKey;autoIncrement" json:"id"`
+	RestaurantID string           `gorm:"size:36;not null;uniqueIndex:idx_notification_templates_kind" json:"restaurant_id"`
+	Kind         NotificationKind `gorm:"size:20;not null;uniqueIndex:idx_notification_templates_kind" json:"kind"`
+	Subject      string           `gorm:"size:500;not null" json:"subject"`
+	Body         string           `gorm:"type:text;not null" json:"body"`
+	CreatedAt    time.Time        `json:"created_at"`
+	UpdatedAt    time.Time        `json:"updated_at"`
+}
+
+type NotificationStatus string
+
+const (
+	NotificationPending NotificationStatus = "pending"
+	NotificationSent    NotificationStatus = "sent"
+	NotificationFailed  NotificationStatus = "failed"
+)
+
+// Notification is a rendered message to a guest. Like webhook deliveries,
+// rows are written in the same transaction as the reservation change and
+// sent afterwards by a dispatcher, which retries failures.
+type Notification struct {
+	ID            string             `gorm:"primaryKey;size:36" json:"id"`
+	RestaurantID  string             `gorm:"size:36;not null;index" json:"restaurant_id"`
+	ReservationID string             `gorm:"size:36;not null;index" json:"reservation_id"`
+	Kind          NotificationKind   `gorm:"size:20;not null" json:"kind"`
+	Recipient     string             `gorm:"size:200;not null" json:"recipient"` // guest's email address
+	Subject       string             `gorm:"size:500;not null" json:"subject"`
+	Body          string             `gorm:"type:text;not null" json:"body"`
+	Status        NotificationStatus `gorm:"size:20;not null;default:'pending';index:idx_notifications_due,priority:1" json:"status"`
+	Attempts      int                `gorm:"not null;default:0" json:"attempts"`
+	NextAttemptAt time.Time          `gorm:"index:idx_notifications_due,priority:2" json:"next_attempt_at"`
+	LastError     string             `gorm:"type:text" json:"last_error,omitempty"`
+	SentAt        *time.Time         `json:"sent_at,omitempty"`
+	CreatedAt     time.Time          `json:"created_at"`
+}
@@ -0,0 +1,222 @@
+package models
+
+import (
+	"strings"
+	"unicode"
+)
+
+// TaxonomyKind is one of the controlled vocabularies.
+type TaxonomyKind string
+
+const (
+	KindCuisine  TaxonomyKind = "cuisine"
+	KindFeature  TaxonomyKind = "feature"
+	KindDietary  TaxonomyKind = "dietary"
+	KindAllergen TaxonomyKind = "allergen"
+)
+
+// TaxonomyTerm is a canonical value in a controlled vocabulary, such as the
+// cuisine "Italian" or the dietary label "gluten_free". Restaurants and
+// menu items link to terms through join tables; their comma-separated
+// Cuisines, Features, DietaryLabels and allergen columns mirror those links
+// for display. Cuisines show the term's Label, the other kinds its Slug.
+type TaxonomyTerm struct {
+	ID       uint              `gorm:"primaryKey" json:"-"`
+	Kind     TaxonomyKind      `gorm:"size:20;not null;uniqueIndex:idx_taxonomy_kind_slug" json:"kind"`
+	Slug     string            `gorm:"size:100;not null;uniqueIndex:idx_taxonomy_kind_slug" json:"slug"`
+	Label    string            `gorm:"size:100;not null" json:"label"`
+	Synonyms []TaxonomySynonym `gorm:"foreignKey:TermID;constraint:OnDelete:CASCADE" json:"-"`
+}
+
+// Display returns the form stored in the comma-separated columns.
+func (t *TaxonomyTerm) Display() string {
+	if t.Kind == KindCuisine {
+		return t.Label
+	}
+	return t.Slug
+}
+
+// Bare returns a copy of t without its synonyms, for use in associations
+// where GORM would otherwise try to save them too.
+func (t *TaxonomyTerm) Bare() TaxonomyTerm {
+	return TaxonomyTerm{ID: t.ID, Kind: t.Kind, Slug: t.Slug, Label: t.Label}
+}
+
+// TermsCSV joins the terms' display forms for the comma-separated columns.
+func TermsCSV(terms []TaxonomyTerm) string {
+	out := make([]string, len(terms))
+	for i := range terms {
+		out[i] = terms[i].Display()
+	}
+	return strings.Join(out, ",")
+}
+
+// TaxonomySynonym maps an alternative spelling to a term. Key is the
+// synonym run through TaxonomyKey, and is unique within a kind.
+type TaxonomySynonym struct {
+	ID     uint         `gorm:"primaryKey"`
+	TermID uint         `gorm:"not null;index"`
+	Kind   TaxonomyKind `gorm:"size:20;not null;uniqueIndex:idx_taxonomy_synonym_key"`
+	Key    string       `gorm:"size:100;not null;uniqueIndex:idx_taxonomy_synonym_key"`
+}
+
+// TaxonomyKey normalizes a value for lookup: lowercase, with runs of
+// spaces, hyphens and other punctuation collapsed to single underscores,
+// so "Gluten-Free", "gluten free" and "gluten_free" are the same key.
+// "&" is kept as "and".
+func TaxonomyKey(s string) string {
+	s = strings.ReplaceAll(strings.ToLower(strings.TrimSpace(s)), "&", " and ")
+	var b strings.Builder
+	sep := false
+	for _, r := range s {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			if sep && b.Len() > 0 {
+				b.WriteByte('_')
+			}
+			b.WriteRune(r)
+			sep = false
+		} else if r != '\'' {
+			sep = true
+		}
+	}
+	return b.String()
+}
+
+// DefaultTerm describes a built-in vocabulary entry.
+type DefaultTerm struct {
+	Kind     TaxonomyKind
+	Label    string
+	Synonyms []string
+}
+
+// Slug returns the term's canonical slug, derived from its label.
+func (d DefaultTerm) Slug() string {
+	return TaxonomyKey(d.Label)
+}
+
+// DefaultTaxonomy is the vocabulary installed on every database. Owners
+// can only use values from it (or their synonyms); the list grows here.
+var DefaultTaxonomy = []DefaultTerm{
+	// Cuisines
+	{KindCuisine, "African", nil},
+	{KindCuisine, "American", []string{"usa", "new american"}},
+	{KindCuisine, "Barbecue", []string{"bbq", "barbeque", "smokehouse"}},
+	{KindCuisine, "Brazilian", nil},
+	{KindCuisine, "Breakfast", []string{"brunch food", "diner"}},
+	{KindCuisine, "Burgers", []string{"burger", "hamburgers"}},
+	{KindCuisine, "Cafe", []string{"café", "coffee", "coffee shop"}},
+	{KindCuisine, "Cajun", []string{"creole", "louisiana"}},
+	{KindCuisine, "Cantonese", []string{"dim sum", "hong kong"}},
+	{KindCuisine, "Caribbean", []string{"jamaican", "cuban"}},
+	{KindCuisine, "Chinese", nil},
+	{KindCuisine, "Ethiopian", nil},
+	{KindCuisine, "European", nil},
+	{KindCuisine, "Filipino", nil},
+	{KindCuisine, "French", []string{"bistro", "brasserie"}},
+	{KindCuisine, "German", nil},
+	{KindCuisine, "Greek", nil},
+	{KindCuisine, "Health Food", []string{"healthy", "health"}},
+	{KindCuisine, "Indian", []string{"north indian", "south indian"}},
+	{KindCuisine, "Indonesian", nil},
+	{KindCuisine, "Italian", []string{"trattoria", "osteria"}},
+	{KindCuisine, "Japanese", nil},
+	{KindCuisine, "Korean", []string{"korean bbq"}},
+	{KindCuisine, "Latin American", []string{"latin", "latino", "south american"}},
+	{KindCuisine, "Lebanese", nil},
+	{KindCuisine, "Mediterranean", nil},
+	{KindCuisine, "Mexican", []string{"tex mex", "tacos"}},
+	{KindCuisine, "Middle Eastern", []string{"arabic", "levantine"}},
+	{KindCuisine, "Moroccan", nil},
+	{KindCuisine, "Nepalese", []string{"nepali", "himalayan"}},
+	{KindCuisine, "Peruvian", nil},
+	{KindCuisine, "Pizza", []string{"pizzeria"}},
+	{KindCuisine, "Ramen", nil},
+	{KindCuisine, "Seafood", []string{"fish", "oyster bar"}},
+	{KindCuisine, "Sichuan", []string{"szechuan", "szechwan"}},
+	{KindCuisine, "South Asian", nil},
+	{KindCuisine, "Southern", []string{"soul food"}},
+	{KindCuisine, "Spanish", []string{"tapas"}},
+	{KindCuisine, "Steakhouse", []string{"steak", "steaks"}},
+	{KindCuisine, "Sushi", []string{"sashimi", "omakase"}},
+	{KindCuisine, "Thai", nil},
+	{KindCuisine, "Turkish", nil},
+	{KindCuisine, "Vegan", []string{"plant based", "plant-based"}},
+	{KindCuisine, "Vegetarian", []string{"veggie"}},
+	{KindCuisine, "Vietnamese", []string{"pho"}},
+
+	// Features
+	{KindFeature, "outdoor_seating", []string{"outdoor", "patio", "terrace", "al fresco"}},
+	{KindFeature, "wifi", []string{"wi-fi", "wireless", "free wifi"}},
+	{KindFeature, "live_music", []string{"music", "live band"}},
+	{KindFeature, "parking", []string{"car park", "valet", "valet parking"}},
+	{KindFeature, "delivery", nil},
+	{KindFeature, "takeout", []string{"take out", "take-away", "takeaway", "to go"}},
+	{KindFeature, "wheelchair_accessible", []string{"accessible", "wheelchair", "step free"}},
+	{KindFeature, "pet_friendly", []string{"dog friendly", "dogs allowed", "pets allowed"}},
+	{KindFeature, "private_dining", []string{"private room", "private events"}},
+	{KindFeature, "bar", []string{"full bar", "cocktails"}},
+	{KindFeature, "brunch", []string{"weekend brunch"}},
+
+	// Dietary labels
+	{KindDietary, "vegetarian", []string{"veggie", "v"}},
+	{KindDietary, "vegan", []string{"plant based", "vg"}},
+	{KindDietary, "gluten_free", []string{"gf", "no gluten", "coeliac", "celiac"}},
+	{KindDietary, "dairy_free", []string{"df", "no dairy", "lactose free"}},
+	{KindDietary, "nut_free", []string{"no nuts", "peanut free"}},
+	{KindDietary, "halal", nil},
+	{KindDietary, "kosher", nil},
+	{KindDietary, "spicy", []string{"hot"}},
+	{KindDietary, "raw", nil},
+	{KindDietary, "organic", nil},
+
+	// Allergens: the US major food allergens and the rest of the EU's 14.
+	{KindAllergen, "milk", []string{"dairy", "lactose", "cows milk"}},
+	{KindAllergen, "eggs", []string{"egg"}},
+	{KindAllergen, "fish", nil},
+	{KindAllergen, "shellfish", []string{"crustaceans", "crustacean shellfish", "shrimp", "prawns", "crab", "lobster"}},
+	{KindAllergen, "tree_nuts", []string{"nuts", "tree nut", "almonds", "cashews", "hazelnuts", "pecans", "pistachios", "walnuts"}},
+	{KindAllergen, "peanuts", []string{"peanut", "groundnuts"}},
+	{KindAllergen, "wheat", nil},
+	{KindAllergen, "soy", []string{"soya", "soybeans", "soybean"}},
+	{KindAllergen, "sesame", []string{"sesame seeds", "tahini"}},
+	{KindAllergen, "gluten", []string{"cereals containing gluten", "barley", "rye", "oats"}},
+	{KindAllergen, "celery", []string{"celeriac"}},
+	{KindAllergen, "mustard", nil},
+	{KindAllergen, "lupin", []string{"lupine"}},
+	{KindAllergen, "mollusks", []string{"molluscs", "clams", "mussels", "oysters", "scallops", "squid"}},
+	{KindAllergen, "sulfites", []string{"sulphites", "sulfur dioxide", "sulphur dioxide"}},
+}
+
+// TaxonomyIndex resolves free-form values to terms by slug, label or
+// synonym.
+type TaxonomyIndex struct {
+	byKey map[TaxonomyKind]map[string]*TaxonomyTerm
+}
+
+// NewTaxonomyIndex indexes terms loaded with their Synonyms.
+func NewTaxonomyIndex(terms []TaxonomyTerm) *TaxonomyIndex {
+	idx := &TaxonomyIndex{byKey: make(map[TaxonomyKind]map[string]*TaxonomyTerm)}
+	for i := range terms {
+		idx.Add(&terms[i])
+	}
+	return idx
+}
+
+// Add indexes t under its slug, label and synonyms.
+func (idx *TaxonomyIndex) Add(t *TaxonomyTerm) {
+	keys := idx.byKey[t.Kind]
+	if keys == nil {
+		keys = make(map[string]*TaxonomyTerm)
+		idx.byKey[t.Kind] = keys
+	}
+	keys[t.Slug] = t
+	keys[TaxonomyKey(t.Label)] = t
+	for _, s := range t.Synonyms {
+		keys[s.Key] = t
+	}
+}
+
+// Lookup returns the term value resolves to, or nil.
+func (idx *TaxonomyIndex) Lookup(kind TaxonomyKind, value string) *TaxonomyTerm {
+	return idx.byKey[kind][TaxonomyKey(value)]
+}
@@ -0,0 +1,93 @@
+package models
+
+import "strings"
+
+// usStateTimezones maps US state codes to the IANA zone covering most of
+// the state.
+var usStateTimezones = map[string]string{
+	"CT": "America/New_York", "DE": "America/New_York", "DC": "America/New_York",
+	"FL": "America/New_York", "GA": "America/New_York", "KY": "America/New_York",
+	"ME": "America/New_York", "MD": "America/New_York", "MA": "America/New_York",
+	"NH": "America/New_York", "NJ": "America/New_York", "NY": "America/New_York",
+	"NC": "America/New_York", "OH": "America/New_York", "PA": "America/New_York",
+	"RI": "America/New_York", "SC": "America/New_York", "VT": "America/New_York",
+	"VA": "America/New_York", "WV": "America/New_York",
+	"MI": "America/Detroit",
+	"IN": "America/Indiana/Indianapolis",
+	"AL": "America/Chicago", "AR": "America/Chicago", "IL": "America/Chicago",
+	"IA": "America/Chicago", "KS": "America/Chicago", "LA": "America/Chicago",
+	"MN": "America/Chicago", "MS": "America/Chicago", "MO": "America/Chicago",
+	"NE": "America/Chicago", "ND": "America/Chicago", "OK": "America/Chicago",
+	"SD": "America/Chicago", "TN": "America/Chicago", "TX": "America/Chicago",
+	"WI": "America/Chicago",
+	"CO": "America/Denver", "ID": "America/Boise", "MT": "America/Denver",
+	"NM": "America/Denver", "UT": "America/Denver", "WY": "America/Denver",
+	"AZ": "America/Phoenix",
+	"CA": "America/Los_Angeles", "NV": "America/Los_Angeles",
+	"OR": "America/Los_Angeles", "WA": "America/Los_Angeles",
+	"AK": "America/Anchorage",
+	"HI": "Pacific/Honolulu",
+	"PR": "America/Puerto_Rico",
+}
+
+// regionTimezones maps state/province codes of other multi-zone countries.
+var regionTimezones = map[string]map[string]string{
+	"CA": {
+		"BC": "America/Vancouver", "AB": "America/Edmonton", "SK": "America/Regina",
+		"MB": "America/Winnipeg", "ON": "America/Toronto", "QC": "America/Toronto",
+		"NB": "America/Halifax", "NS": "America/Halifax", "PE": "America/Halifax",
+		"NL": "America/St_Johns", "YT": "America/Whitehorse",
+	},
+	"AU": {
+		"NSW": "Australia/Sydney", "ACT": "Australia/Sydney", "VIC": "Australia/Melbourne",
+		"TAS": "Australia/Hobart", "QLD": "Australia/Brisbane", "SA": "Australia/Adelaide",
+		"WA": "Australia/Perth", "NT": "Australia/Darwin",
+	},
+}
+
+// countryTimezones maps countries to their zone, for countries that have a
+// single one (or one that covers nearly all restaurants).
+var countryTimezones = map[string]string{
+	"GB": "Europe/London", "IE": "Europe/Dublin", "FR": "Europe/Paris",
+	"DE": "Europe/Berlin", "IT": "Europe/Rome", "ES": "Europe/Madrid",
+	"PT": "Europe/Lisbon", "NL": "Europe/Amsterdam", "BE": "Europe/Brussels",
+	"CH": "Europe/Zurich", "AT": "Europe/Vienna", "SE": "Europe/Stockholm",
+	"NO": "Europe/Oslo", "DK": "Europe/Copenhagen", "FI": "Europe/Helsinki",
+	"PL": "Europe/Warsaw", "CZ": "Europe/Prague", "GR": "Europe/Athens",
+	"TR": "Europe/Istanbul", "IL": "Asia/Jerusalem", "AE": "Asia/Dubai",
+	"IN": "Asia/Kolkata", "TH": "Asia/Bangkok", "SG": "Asia/Singapore",
+	"HK": "Asia/Hong_Kong", "CN": "Asia/Shanghai", "TW": "Asia/Taipei",
+	"KR": "Asia/Seoul", "JP": "Asia/Tokyo", "PH": "Asia/Manila",
+	"NZ": "Pacific/Auckland", "ZA": "Africa/Johannesburg", "EG": "Africa/Cairo",
+	"MX": "America/Mexico_City", "CO": "America/Bogota", "PE": "America/Lima",
+	"AR": "America/Argentina/Buenos_Aires", "CL": "America/Santiago",
+}
+
+// countryAliases normalizes common spellings of country names to ISO codes.
+var countryAliases = map[string]string{
+	"USA": "US", "UNITED STATES": "US", "UNITED STATES OF AMERICA": "US",
+	"UK": "GB", "UNITED KINGDOM": "GB", "CANADA": "CA", "AUSTRALIA": "AU",
+}
+
+// DefaultTimezone guesses a restaurant's IANA time zone from its country and
+// state. It returns "UTC" when there is no good guess.
+func DefaultTimezone(country, state string) string {
+	country = strings.ToUpper(strings.TrimSpace(country))
+	if alias, ok := countryAliases[country]; ok {
+		country = alias
+	}
+	state = strings.ToUpper(strings.TrimSpace(state))
+
+	if country == "US" || country == "" {
+		if tz, ok := usStateTimezones[state]; ok {
+			return tz
+		}
+	}
+	if tz, ok := regionTimezones[country][state]; ok {
+		return tz
+	}
+	if tz, ok := countryTimezones[country]; ok {
+		return tz
+	}
+	return "UTC"
+}
@@ -0,0 +1,109 @@
+package models
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"strings"
+	"time"
+)
+
+// WebhookEvent is the type of a change that owners can subscribe to.
+type WebhookEvent string
+
+const (
+	EventReservationCreated   WebhookEvent = "reservation.created"
+	EventReservationCancelled WebhookEvent = "reservation.cancelled"
+	EventReservationModified  WebhookEvent = "reservation.modified"
+	EventReviewCreated        WebhookEvent = "review.created"
+)
+
+// WebhookEvents lists every event type, in the order they are documented.
+var WebhookEvents = []WebhookEvent{
+	EventReservationCreated,
+	EventReservationCancelled,
+	EventReservationModified,
+	EventReviewCreated,
+}
+
+// Valid reports whether e is a known event type.
+func (e WebhookEvent) Valid() bool {
+	for _, known := range WebhookEvents {
+		if e == known {
+			return true
+		}
+	}
+	return false
+}
+
+// WebhookSubscription sends an owner's events to a URL. Events is a
+// comma-separated list of WebhookEvent values. Unlike API keys, the secret
+// is stored as is: the dispatcher needs it to sign every delivery.
+type WebhookSubscription struct {
+	ID        string    `gorm:"primaryKey;size:36" json:"id"`
+	OwnerID   string    `gorm:"size:36;not null;index" json:"owner_id"`
+	URL       string    `gorm:"size:2000;not null" json:"url"`
+	Secret    string    `gorm:"size:100;not null" json:"-"`
+	Events    string    `gorm:"size:500;not null" json:"events"`
+	IsActive  bool      `gorm:"default:true" json:"is_active"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// Wants reports whether the subscription is active and includes event.
+func (s *WebhookSubscription) Wants(event WebhookEvent) bool {
+	if !s.IsActive {
+		return false
+	}
+	for _, e := range strings.Split(s.Events, ",") {
+		if WebhookEvent(strings.TrimSpace(e)) == event {
+			return true
+		}
+	}
+	return false
+}
+
+type DeliveryStatus string
+
+const (
+	DeliveryPending   DeliveryStatus = "pending"
+	DeliveryDelivered DeliveryStatus = "delivered"
+	DeliveryFailed    DeliveryStatus = "failed"
+)
+
+// Valid reports whether s is a known delivery status.
+func (s DeliveryStatus) Valid() bool {
+	return s == DeliveryPending || s == DeliveryDelivered || s == DeliveryFailed
+}
+
+// WebhookDelivery is one event on its way to one subscription. Rows are
+// written in the same transaction as the change that caused the event, so
+// the table is both the outbox the dispatcher works through and the
+// delivery log owners can inspect. Payload is the exact JSON body sent;
+// a replay copies it into a new delivery with ReplayOf set.
+type WebhookDelivery struct {
+	ID             string         `gorm:"primaryKey;size:36" json:"id"`
+	SubscriptionID string         `gorm:"size:36;not null;index" json:"subscription_id"`
+	OwnerID        string         `gorm:"size:36;not null;index" json:"owner_id"`
+	EventID        string         `gorm:"size:36;not null;index" json:"event_id"` // shared by every delivery of one event
+	Event          WebhookEvent   `gorm:"size:50;not null" json:"event"`
+	Payload        string         `gorm:"type:text;not null" json:"payload"`
+	Status         DeliveryStatus `gorm:"size:20;not null;default:'pending';index:idx_webhook_deliveries_due,priority:1" json:"status"`
+	Attempts       int            `gorm:"not null;default:0" json:"attempts"`
+	NextAttemptAt  time.Time      `gorm:"index:idx_webhook_deliveries_due,priority:2" json:"next_attempt_at"`
+	LastAttemptAt  *time.Time     `json:"last_attempt_at,omitempty"`
+	ResponseStatus int            `json:"response_status,omitempty"` // HTTP status of the last attempt
+	LastError      string         `gorm:"type:text" json:"last_error,omitempty"`
+	DeliveredAt    *time.Time     `json:"delivered_at,omitempty"`
+	ReplayOf       string         `gorm:"size:36" json:"replay_of,omitempty"`
+	CreatedAt      time.Time      `json:"created_at"`
+}
+
+// GenerateWebhookSecret creates a random signing secret for a webhook
+// subscription.
+func GenerateWebhookSecret() string {
+	b := make([]byte, 24)
+	if _, err := rand.Read(b); err != nil {
+		panic("crypto/rand failed: " + err.Error())
+	}
+	return "whsec_" + hex.EncodeToString(b)
+}
@@ -0,0 +1,144 @@
+package notify
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/agenteats/agenteats/internal/models"
+)
+
+// Dispatcher sends queued notifications through a Notifier. Like the
+// webhook dispatcher, it claims each notification before sending it, so
+// several can share a database.
+type Dispatcher struct {
+	DB       *gorm.DB
+	Notifier Notifier
+
+	Interval    time.Duration // how often Run looks for due notifications
+	BatchSize   int           // notifications claimed per pass
+	MaxAttempts int           // attempts before a notification is marked failed
+	BaseDelay   time.Duration // wait before the first retry; doubles each attempt
+	MaxDelay    time.Duration // longest wait between retries
+	Lease       time.Duration // how long a claimed notification is hidden from other dispatchers
+	Timeout     time.Duration // limit on a single send
+}
+
+// NewDispatcher returns a Dispatcher that makes five attempts, retried
+// after 1, 2, 4 and 8 minutes.
+func NewDispatcher(db *gorm.DB, n Notifier) *Dispatcher {
+	return &Dispatcher{
+		DB:          db,
+		Notifier:    n,
+		Interval:    5 * time.Second,
+		BatchSize:   50,
+		MaxAttempts: 5,
+		BaseDelay:   time.Minute,
+		MaxDelay:    time.Hour,
+		Lease:       2 * time.Minute,
+		Timeout:     30 * time.Second,
+	}
+}
+
+// Run sends due notifications every Interval until ctx is done.
+func (d *Dispatcher) Run(ctx context.Context) {
+	ticker := time.NewTicker(d.Interval)
+	defer ticker.Stop()
+	for {
+		if _, err := d.DispatchDue(ctx); err != nil && ctx.Err() == nil {
+			log.Printf("notify: %v", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// DispatchDue sends every pending notification that is due, up to
+// BatchSize, and returns how many it attempted.
+func (d *Dispatcher) DispatchDue(ctx context.Context) (int, error) {
+	var due []models.Notification
+	if err := d.DB.WithContext(ctx).
+		Where("status = ? AND next_attempt_at <= ?", models.NotificationPending, time.Now().UTC()).
+		Order("next_attempt_at ASC, id ASC").Limit(d.BatchSize).
+		Find(&due).Error; err != nil {
+		return 0, err
+	}
+	sent := 0
+	for i := range due {
+		if ctx.Err() != nil {
+			return sent, ctx.Err()
+		}
+		claimed, err := d.claim(ctx, &due[i])
+		if err != nil {
+			return sent, err
+		}
+		if !claimed {
+			continue
+		}
+		if err := d.send(ctx, &due[i]); err != nil {
+			return sent, err
+		}
+		sent++
+	}
+	return sent, nil
+}
+
+// claim counts an attempt against the notification and pushes its next
+// attempt back by Lease, unless another dispatcher got there first.
+func (d *Dispatcher) claim(ctx context.Context, n *models.Notification) (bool, error) {
+	result := d.DB.WithContext(ctx).Model(&models.Notification{}).
+		Where("id = ? AND status = ? AND attempts = ?", n.ID, models.NotificationPending, n.Attempts).
+		UpdateColumns(map[string]any{
+			"attempts":        n.Attempts + 1,
+			"next_attempt_at": time.Now().UTC().Add(d.Lease),
+		})
+	if result.Error != nil {
+		return false, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return false, nil
+	}
+	n.Attempts++
+	return true, nil
+}
+
+// send hands a claimed notification to the Notifier and records the
+// outcome.
+func (d *Dispatcher) send(ctx context.Context, n *models.Notification) error {
+	sendCtx, cancel := context.WithTimeout(ctx, d.Timeout)
+	err := d.Notifier.Send(sendCtx, Message{To: n.Recipient, Subject: n.Subject, Body: n.Body})
+	cancel()
+
+	updates := map[string]any{}
+	switch {
+	case err == nil:
+		updates["status"] = models.NotificationSent
+		updates["sent_at"] = time.Now().UTC()
+		updates["last_error"] = ""
+	case n.Attempts >= d.MaxAttempts:
+		updates["status"] = models.NotificationFailed
+		updates["last_error"] = err.Error()
+	default:
+		updates["next_attempt_at"] = time.Now().UTC().Add(d.backoff(n.Attempts))
+		updates["last_error"] = err.Error()
+	}
+	return d.DB.WithContext(ctx).Model(&models.Notification{}).Where("id = ?", n.ID).
+		UpdateColumns(updates).Error
+}
+
+// backoff returns the wait after the given number of failed attempts.
+func (d *Dispatcher) backoff(attempts int) time.Duration {
+	delay := d.BaseDelay
+	for i := 1; i < attempts && delay < d.MaxDelay; i++ {
+		delay *= 2
+	}
+	if delay > d.MaxDelay {
+		delay = d.MaxDelay
+	}
+	return delay
+}
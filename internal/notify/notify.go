@@ -0,0 +1,142 @@
+// Package notify sends guests messages about their reservations. Services
+// render a message from a template and queue it as a models.Notification in
+// the same transaction as the reservation change; a Dispatcher hands queued
+// messages to a Notifier — SMTP in production, a log for development.
+package notify
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"mime"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/agenteats/agenteats/internal/config"
+	"github.com/agenteats/agenteats/internal/models"
+)
+
+// Message is a plain-text email to a guest.
+type Message struct {
+	To      string // email address
+	Subject string
+	Body    string
+}
+
+// Notifier delivers messages. Send returns an error if the message may not
+// have been delivered; the dispatcher then retries it.
+type Notifier interface {
+	Send(ctx context.Context, m Message) error
+}
+
+// New returns the Notifier selected by cfg.Notifier.
+func New(cfg *config.Config) (Notifier, error) {
+	switch cfg.Notifier {
+	case "", "log":
+		if cfg.NotifyLogFile == "" {
+			return &LogNotifier{W: os.Stderr}, nil
+		}
+		f, err := os.OpenFile(cfg.NotifyLogFile, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+		if err != nil {
+			return nil, fmt.Errorf("open notification log: %w", err)
+		}
+		return &LogNotifier{W: f}, nil
+	case "smtp":
+		if _, err := mail.ParseAddress(cfg.SMTPFrom); err != nil {
+			return nil, fmt.Errorf("invalid SMTP_FROM %q: %w", cfg.SMTPFrom, err)
+		}
+		return &SMTPNotifier{
+			Host:     cfg.SMTPHost,
+			Port:     cfg.SMTPPort,
+			Username: cfg.SMTPUsername,
+			Password: cfg.SMTPPassword,
+			From:     cfg.SMTPFrom,
+		}, nil
+	default:
+		return nil, fmt.Errorf("unknown NOTIFIER %q (use log or smtp)", cfg.Notifier)
+	}
+}
+
+// LogNotifier writes each message to W instead of sending it, for
+// development and tests.
+type LogNotifier struct {
+	W  io.Writer
+	mu sync.Mutex
+}
+
+func (n *LogNotifier) Send(_ context.Context, m Message) error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	_, err := fmt.Fprintf(n.W, "--- %s\nTo: %s\nSubject: %s\n\n%s\n\n",
+		time.Now().UTC().Format(time.RFC3339), m.To, m.Subject, strings.TrimRight(m.Body, "\n"))
+	return err
+}
+
+// SMTPNotifier sends messages through an SMTP server, upgrading to TLS
+// when the server offers STARTTLS. Without a Username no authentication is
+// attempted, which suits local stand-ins such as MailHog or smtp4dev.
+type SMTPNotifier struct {
+	Host     string
+	Port     int
+	Username string
+	Password string
+	From     string // e.g. "AgentEats <no-reply@example.com>"
+}
+
+func (n *SMTPNotifier) Send(ctx context.Context, m Message) error {
+	from, err := mail.ParseAddress(n.From)
+	if err != nil {
+		return err
+	}
+	to, err := mail.ParseAddress(m.To)
+	if err != nil {
+		return err
+	}
+	var auth smtp.Auth
+	if n.Username != "" {
+		auth = smtp.PlainAuth("", n.Username, n.Password, n.Host)
+	}
+	addr := net.JoinHostPort(n.Host, strconv.Itoa(n.Port))
+
+	// smtp.SendMail has no context; run it so a shutdown isn't held up.
+	done := make(chan error, 1)
+	go func() {
+		done <- smtp.SendMail(addr, auth, from.Address, []string{to.Address}, compose(from, to, m))
+	}()
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// compose formats m as an RFC 5322 message with a UTF-8 plain-text body.
+func compose(from, to *mail.Address, m Message) []byte {
+	var b strings.Builder
+	b.WriteString("From: " + from.String() + "\r\n")
+	b.WriteString("To: " + to.String() + "\r\n")
+	b.WriteString("Subject: " + mime.QEncoding.Encode("utf-8", m.Subject) + "\r\n")
+	b.WriteString("Date: " + time.Now().Format(time.RFC1123Z) + "\r\n")
+	b.WriteString("Message-ID: <" + models.NewID() + "@" + domain(from.Address) + ">\r\n")
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	b.WriteString("Content-Transfer-Encoding: 8bit\r\n\r\n")
+	for _, line := range strings.Split(strings.ReplaceAll(m.Body, "\r\n", "\n"), "\n") {
+		b.WriteString(line + "\r\n")
+	}
+	return []byte(b.String())
+}
+
+func domain(addr string) string {
+	if i := strings.LastIndexByte(addr, '@'); i >= 0 {
+		return addr[i+1:]
+	}
+	return "localhost"
+}
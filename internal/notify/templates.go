@@ -0,0 +1,208 @@
+package notify
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/agenteats/agenteats/internal/models"
+)
+
+// TemplateData is what message templates are rendered with. Dates and
+// times are in the restaurant's local time.
+type TemplateData struct {
+	GuestName         string
+	RestaurantName    string
+	RestaurantAddress string
+	RestaurantPhone   string
+	ReservationID     string
+	Date              string // YYYY-MM-DD
+	DisplayDate       string // e.g. Friday, March 15, 2026
+	Time              string // HH:MM
+	PartySize         int
+	SpecialRequests   string
+	Previous          *PreviousBooking // modification messages only
+	CancelledBy       string           // cancellation messages only: guest or restaurant
+	ManageToken       string           // manage_token messages only
+}
+
+// PreviousBooking is a reservation's details before a modification.
+type PreviousBooking struct {
+	Date        string
+	DisplayDate string
+	Time        string
+	PartySize   int
+}
+
+// DisplayDate formats a YYYY-MM-DD date for people, e.g. "Friday, March
+// 15, 2026". Unparseable dates are returned as is.
+func DisplayDate(date string) string {
+	t, err := time.Parse("2006-01-02", date)
+	if err != nil {
+		return date
+	}
+	return t.Format("Monday, January 2, 2006")
+}
+
+// Template is the source of a message's subject and body.
+type Template struct {
+	Subject string
+	Body    string
+}
+
+// Defaults are the messages sent when a restaurant hasn't customized them.
+var Defaults = map[models.NotificationKind]Template{
+	models.NotifyConfirmation: {
+		Subject: "Your table at {{.RestaurantName}} on {{.DisplayDate}} is confirmed",
+		Body: `Hi {{.GuestName}},
+
+Your reservation at {{.RestaurantName}} is confirmed.
+
+  When:  {{.DisplayDate}} at {{.Time}}
+  Party: {{.PartySize}}
+{{- if .SpecialRequests}}
+  Notes: {{.SpecialRequests}}
+{{- end}}
+  Where: {{.RestaurantAddress}}
+{{if .RestaurantPhone}}
+To reach the restaurant, call {{.RestaurantPhone}}.
+{{end}}
+Reservation ID: {{.ReservationID}}
+`,
+	},
+	models.NotifyModification: {
+		Subject: "Your reservation at {{.RestaurantName}} has changed",
+		Body: `Hi {{.GuestName}},
+
+Your reservation at {{.RestaurantName}} has been updated.
+
+  When:  {{.DisplayDate}} at {{.Time}}
+{{- if and .Previous (or (ne .Previous.Date .Date) (ne .Previous.Time .Time))}} (was {{.Previous.DisplayDate}} at {{.Previous.Time}}){{end}}
+  Party: {{.PartySize}}
+{{- if and .Previous (ne .Previous.PartySize .PartySize)}} (was {{.Previous.PartySize}}){{end}}
+{{- if .SpecialRequests}}
+  Notes: {{.SpecialRequests}}
+{{- end}}
+  Where: {{.RestaurantAddress}}
+
+Reservation ID: {{.ReservationID}}
+`,
+	},
+	models.NotifyCancellation: {
+		Subject: "Your reservation at {{.RestaurantName}} is cancelled",
+		Body: `Hi {{.GuestName}},
+
+{{if eq .CancelledBy "restaurant" -}}
+{{.RestaurantName}} has cancelled your reservation for {{.DisplayDate}} at {{.Time}}.
+{{- if .RestaurantPhone}} Please call {{.RestaurantPhone}} if you have any questions.{{end}}
+{{- else -}}
+Your reservation at {{.RestaurantName}} for {{.DisplayDate}} at {{.Time}} has been cancelled, as you asked.
+{{- end}}
+
+Reservation ID: {{.ReservationID}}
+`,
+	},
+	models.NotifyReminder: {
+		Subject: "See you tomorrow at {{.RestaurantName}}",
+		Body: `Hi {{.GuestName}},
+
+A reminder that you have a table for {{.PartySize}} at {{.RestaurantName}} tomorrow, {{.DisplayDate}}, at {{.Time}}.
+
+  Where: {{.RestaurantAddress}}
+{{if .RestaurantPhone}}
+Can't make it? Please cancel or call {{.RestaurantPhone}} so the table can go to someone else.
+{{else}}
+Can't make it? Please cancel so the table can go to someone else.
+{{end}}
+Reservation ID: {{.ReservationID}}
+`,
+	},
+	models.NotifyManageToken: {
+		Subject: "Manage your reservation at {{.RestaurantName}}",
+		Body: `Hi {{.GuestName}},
+
+You can now change or cancel your reservation at {{.RestaurantName}} on {{.DisplayDate}} at {{.Time}} yourself, or through any assistant that books with AgentEats, instead of calling the restaurant.
+
+  Reservation ID: {{.ReservationID}}
+  Manage token:   {{.ManageToken}}
+
+Keep the token private: anyone who has it can change or cancel the booking.
+`,
+	},
+}
+
+// ErrInvalidTemplate is returned by Validate for templates that don't
+// parse or can't be rendered.
+var ErrInvalidTemplate = errors.New("invalid notification template")
+
+// Render executes t with data. Line breaks in the subject are collapsed so
+// it stays a single header line.
+func Render(t Template, data TemplateData) (Message, error) {
+	subject, err := execute("subject", t.Subject, data)
+	if err != nil {
+		return Message{}, err
+	}
+	body, err := execute("body", t.Body, data)
+	if err != nil {
+		return Message{}, err
+	}
+	return Message{Subject: strings.Join(strings.Fields(subject), " "), Body: body}, nil
+}
+
+func execute(name, src string, data TemplateData) (string, error) {
+	tmpl, err := template.New(name).Option("missingkey=error").Parse(src)
+	if err != nil {
+		return "", err
+	}
+	var b strings.Builder
+	if err := tmpl.Execute(&b, data); err != nil {
+		return "", err
+	}
+	return b.String(), nil
+}
+
+// SampleData is example data for previews and validation.
+func SampleData(kind models.NotificationKind) TemplateData {
+	d := TemplateData{
+		GuestName:         "Alex Guest",
+		RestaurantName:    "Your Restaurant",
+		RestaurantAddress: "123 Main St, Springfield",
+		RestaurantPhone:   "+1 555 0100",
+		ReservationID:     "00000000-0000-0000-0000-000000000000",
+		Date:              "2026-03-15",
+		DisplayDate:       DisplayDate("2026-03-15"),
+		Time:              "19:30",
+		PartySize:         4,
+		SpecialRequests:   "Window table, please",
+	}
+	switch kind {
+	case models.NotifyModification:
+		d.Previous = &PreviousBooking{Date: "2026-03-14", DisplayDate: DisplayDate("2026-03-14"), Time: "19:00", PartySize: 2}
+	case models.NotifyCancellation:
+		d.CancelledBy = "guest"
+	case models.NotifyManageToken:
+		d.ManageToken = sampleManageToken
+	}
+	return d
+}
+
+// sampleManageToken is the token in sample manage_token messages.
+const sampleManageToken = "rm_sample-manage-token"
+
+// Validate checks that t parses and renders for kind, and that its
+// rendered subject isn't empty.
+func Validate(kind models.NotificationKind, t Template) (Message, error) {
+	m, err := Render(t, SampleData(kind))
+	if err != nil {
+		return Message{}, fmt.Errorf("%w: %v", ErrInvalidTemplate, err)
+	}
+	if m.Subject == "" {
+		return Message{}, fmt.Errorf("%w: subject renders empty", ErrInvalidTemplate)
+	}
+	if kind == models.NotifyManageToken && !strings.Contains(m.Body, sampleManageToken) {
+		return Message{}, fmt.Errorf("%w: body must include {{.ManageToken}}", ErrInvalidTemplate)
+	}
+	return m, nil
+}
@@ -0,0 +1,59 @@
+package search
+
+import (
+	"fmt"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// fts5Index keeps documents in an SQLite FTS5 table and ranks with bm25.
+// Its unicode61 tokenizer folds case and diacritics like fold does.
+type fts5Index struct{}
+
+func (fts5Index) Migrate(db *gorm.DB) error {
+	if err := db.Exec(`CREATE VIRTUAL TABLE IF NOT EXISTS search_fts USING fts5(
+		restaurant_id UNINDEXED, name, cuisines, description, menu,
+		tokenize = 'unicode61 remove_diacritics 2', prefix = '2 3')`).Error; err != nil {
+		return err
+	}
+	return db.Exec(`CREATE VIRTUAL TABLE IF NOT EXISTS search_fts_vocab USING fts5vocab(search_fts, 'row')`).Error
+}
+
+func (f fts5Index) Put(db *gorm.DB, doc *Document) error {
+	if err := f.Delete(db, doc.RestaurantID); err != nil {
+		return err
+	}
+	return db.Exec(`INSERT INTO search_fts (restaurant_id, name, cuisines, description, menu) VALUES (?, ?, ?, ?, ?)`,
+		doc.RestaurantID, doc.Name, doc.Cuisines, doc.Description, doc.Menu).Error
+}
+
+func (fts5Index) Delete(db *gorm.DB, restaurantID string) error {
+	return db.Exec(`DELETE FROM search_fts WHERE restaurant_id = ?`, restaurantID).Error
+}
+
+func (fts5Index) Vocabulary(db *gorm.DB) ([]string, error) {
+	var words []string
+	err := db.Raw(`SELECT term FROM search_fts_vocab`).Scan(&words).Error
+	return words, err
+}
+
+func (fts5Index) Query(db *gorm.DB, terms []Term, limit int) ([]Hit, error) {
+	// Terms are folded words of letters and digits, so quoting them is
+	// enough to keep FTS5 from reading them as query syntax.
+	alts := make([]string, 0, len(terms))
+	for _, t := range terms {
+		parts := []string{`"` + t.Text + `"*`}
+		for _, c := range t.Corrections {
+			parts = append(parts, `"`+c+`"`)
+		}
+		alts = append(alts, "("+strings.Join(parts, " OR ")+")")
+	}
+
+	var hits []Hit
+	err := db.Raw(fmt.Sprintf(`SELECT restaurant_id, -bm25(search_fts, 0, %g, %g, %g, %g) AS score
+		FROM search_fts WHERE search_fts MATCH ? ORDER BY score DESC LIMIT ?`,
+		weightName, weightCuisines, weightDescription, weightMenu),
+		strings.Join(alts, " OR "), limit).Scan(&hits).Error
+	return hits, err
+}
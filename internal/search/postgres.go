@@ -0,0 +1,60 @@
+package search
+
+import (
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// postgresIndex keeps a weighted tsvector on search_documents, behind a GIN
+// index, and ranks with ts_rank. The 'simple' configuration doesn't fold
+// accents, so the vector is built from text already run through fold.
+type postgresIndex struct{}
+
+func (postgresIndex) Migrate(db *gorm.DB) error {
+	if err := db.Exec(`ALTER TABLE search_documents ADD COLUMN IF NOT EXISTS tsv tsvector`).Error; err != nil {
+		return err
+	}
+	return db.Exec(`CREATE INDEX IF NOT EXISTS idx_search_documents_tsv ON search_documents USING GIN (tsv)`).Error
+}
+
+func (postgresIndex) Put(db *gorm.DB, doc *Document) error {
+	// ts_rank's default weights for A–D are 1, 0.4, 0.2 and 0.1, in line
+	// with the field weights.
+	return db.Exec(`UPDATE search_documents SET tsv =
+		setweight(to_tsvector('simple', ?), 'A') ||
+		setweight(to_tsvector('simple', ?), 'B') ||
+		setweight(to_tsvector('simple', ?), 'C') ||
+		setweight(to_tsvector('simple', ?), 'D')
+		WHERE restaurant_id = ?`,
+		fold(doc.Name), fold(doc.Cuisines), fold(doc.Description), fold(doc.Menu), doc.RestaurantID).Error
+}
+
+func (postgresIndex) Delete(db *gorm.DB, restaurantID string) error {
+	// The vector lives on the document row, which the caller deletes.
+	return nil
+}
+
+func (postgresIndex) Vocabulary(db *gorm.DB) ([]string, error) {
+	var words []string
+	err := db.Raw(`SELECT word FROM ts_stat('SELECT tsv FROM search_documents')`).Scan(&words).Error
+	return words, err
+}
+
+func (postgresIndex) Query(db *gorm.DB, terms []Term, limit int) ([]Hit, error) {
+	// Terms are folded words of letters and digits, which to_tsquery
+	// accepts as-is.
+	alts := make([]string, 0, len(terms))
+	for _, t := range terms {
+		parts := []string{t.Text + ":*"}
+		parts = append(parts, t.Corrections...)
+		alts = append(alts, "("+strings.Join(parts, " | ")+")")
+	}
+
+	var hits []Hit
+	err := db.Raw(`SELECT restaurant_id, ts_rank(tsv, query) AS score
+		FROM search_documents, to_tsquery('simple', ?) AS query
+		WHERE tsv @@ query ORDER BY score DESC LIMIT ?`,
+		strings.Join(alts, " | "), limit).Scan(&hits).Error
+	return hits, err
+}
@@ -0,0 +1,78 @@
+package search
+
+import (
+	"sort"
+
+	"gorm.io/gorm"
+)
+
+// scanIndex is the fallback for SQLite builds without FTS5: it reads every
+// document and scores it in Go. That is fine for a directory of a few
+// thousand restaurants; build with -tags sqlite_fts5 beyond that.
+type scanIndex struct{}
+
+func (scanIndex) Migrate(db *gorm.DB) error { return nil }
+
+func (scanIndex) Put(db *gorm.DB, doc *Document) error { return nil }
+
+func (scanIndex) Delete(db *gorm.DB, restaurantID string) error { return nil }
+
+func (scanIndex) Vocabulary(db *gorm.DB) ([]string, error) {
+	docs, err := allDocuments(db)
+	if err != nil {
+		return nil, err
+	}
+	texts := make([]string, 0, len(docs)*4)
+	for _, d := range docs {
+		texts = append(texts, d.Name, d.Cuisines, d.Description, d.Menu)
+	}
+	return vocabulary(texts...), nil
+}
+
+func (scanIndex) Query(db *gorm.DB, terms []Term, limit int) ([]Hit, error) {
+	docs, err := allDocuments(db)
+	if err != nil {
+		return nil, err
+	}
+
+	var hits []Hit
+	for _, d := range docs {
+		score := fieldScore(d.Name, terms)*weightName +
+			fieldScore(d.Cuisines, terms)*weightCuisines +
+			fieldScore(d.Description, terms)*weightDescription +
+			fieldScore(d.Menu, terms)*weightMenu
+		if score > 0 {
+			hits = append(hits, Hit{RestaurantID: d.RestaurantID, Score: score})
+		}
+	}
+	sort.Slice(hits, func(i, j int) bool { return hits[i].Score > hits[j].Score })
+	if len(hits) > limit {
+		hits = hits[:limit]
+	}
+	return hits, nil
+}
+
+func allDocuments(db *gorm.DB) ([]Document, error) {
+	var docs []Document
+	err := db.Find(&docs).Error
+	return docs, err
+}
+
+// fieldScore counts the words of s matching any term, relative to the
+// field's length so a short name that matches outranks a long menu.
+func fieldScore(s string, terms []Term) float64 {
+	words := tokenize(s)
+	if len(words) == 0 {
+		return 0
+	}
+	n := 0
+	for _, w := range words {
+		for _, t := range terms {
+			if t.matches(w) {
+				n++
+				break
+			}
+		}
+	}
+	return float64(n) / float64(len(words))
+}
@@ -0,0 +1,332 @@
+// Package search is the full-text restaurant index behind free-text
+// queries. Each restaurant is indexed as one document made of its name,
+// cuisines, description and menu. SQLite databases use FTS5 when the
+// driver was built with it (-tags sqlite_fts5) and fall back to scanning
+// the documents in Go otherwise; Postgres uses a weighted tsvector.
+package search
+
+import (
+	"errors"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/agenteats/agenteats/internal/models"
+)
+
+// Document is the searchable text of one restaurant. The menu holds one
+// "Name: description" line per available item.
+type Document struct {
+	RestaurantID string    `gorm:"primaryKey;size:36"`
+	Name         string    `gorm:"size:200;not null"`
+	Cuisines     string    `gorm:"size:500"`
+	Description  string    `gorm:"type:text"`
+	Menu         string    `gorm:"type:text"`
+	UpdatedAt    time.Time `gorm:"autoUpdateTime"`
+}
+
+func (Document) TableName() string { return "search_documents" }
+
+// Field weights, most to least telling. Backends rank with these so a name
+// match beats a passing mention in the menu.
+const (
+	weightName        = 10.0
+	weightCuisines    = 5.0
+	weightDescription = 2.0
+	weightMenu        = 1.0
+)
+
+// Hit is a restaurant matching a query, with a backend-specific relevance
+// score where higher is better.
+type Hit struct {
+	RestaurantID string
+	Score        float64
+}
+
+// Index stores documents and runs ranked queries over them.
+type Index interface {
+	// Migrate creates the index's tables.
+	Migrate(db *gorm.DB) error
+	// Put adds or replaces the document. The row in search_documents has
+	// already been saved.
+	Put(db *gorm.DB, doc *Document) error
+	// Delete removes a restaurant's document from the index.
+	Delete(db *gorm.DB, restaurantID string) error
+	// Vocabulary returns every indexed word, for spelling correction.
+	Vocabulary(db *gorm.DB) ([]string, error)
+	// Query returns up to limit documents matching any of the terms, best
+	// first.
+	Query(db *gorm.DB, terms []Term, limit int) ([]Hit, error)
+}
+
+// indexes remembers the backend chosen for each database, keyed by its
+// *gorm.Config, which sessions and transactions share.
+var indexes sync.Map
+
+// vocabMaxAge is how long a cached vocabulary is trusted without a reindex
+// in this process, to pick up changes made by other processes.
+const vocabMaxAge = 10 * time.Minute
+
+// vocabCache holds an index's vocabulary between searches, since building
+// it reads every document (or, on Postgres, runs ts_stat over them).
+type vocabCache struct {
+	mu     sync.Mutex
+	words  []string
+	loaded time.Time
+	stale  bool // a document changed since loaded
+}
+
+// vocabularies holds a vocabCache per database, keyed like indexes.
+var vocabularies sync.Map
+
+func vocabFor(db *gorm.DB) *vocabCache {
+	c, _ := vocabularies.LoadOrStore(db.Config, &vocabCache{})
+	return c.(*vocabCache)
+}
+
+// markStale makes the next search that needs a correction reload the
+// vocabulary.
+func (c *vocabCache) markStale() {
+	c.mu.Lock()
+	c.stale = true
+	c.mu.Unlock()
+}
+
+// correctTerms adds spelling corrections to terms. The cached vocabulary
+// is used while it is fresh; otherwise it is reloaded, but only when a
+// term looks misspelled against it, so queries of known words never pay
+// for a reload.
+func correctTerms(db *gorm.DB, idx Index, terms []Term) error {
+	c := vocabFor(db)
+	c.mu.Lock()
+	fresh := !c.loaded.IsZero() && !c.stale && time.Since(c.loaded) < vocabMaxAge
+	if !fresh && misspelled(terms, c.words) {
+		words, err := idx.Vocabulary(db)
+		if err != nil {
+			c.mu.Unlock()
+			return err
+		}
+		c.words, c.loaded, c.stale = words, time.Now(), false
+	}
+	vocab := c.words
+	c.mu.Unlock()
+
+	correct(terms, vocab)
+	return nil
+}
+
+// For returns the index backend for db, setting it up on first use.
+func For(db *gorm.DB) (Index, error) {
+	if idx, ok := indexes.Load(db.Config); ok {
+		return idx.(Index), nil
+	}
+	if err := db.AutoMigrate(&Document{}); err != nil {
+		return nil, err
+	}
+
+	var idx Index
+	switch db.Dialector.Name() {
+	case "postgres":
+		idx = postgresIndex{}
+	default:
+		idx = fts5Index{}
+	}
+	if err := idx.Migrate(db); err != nil {
+		if _, ok := idx.(fts5Index); !ok || !strings.Contains(err.Error(), "no such module") {
+			return nil, err
+		}
+		// The SQLite driver was built without FTS5.
+		idx = scanIndex{}
+		if err := idx.Migrate(db); err != nil {
+			return nil, err
+		}
+	}
+	indexes.Store(db.Config, idx)
+	return idx, nil
+}
+
+// Migrate prepares the search tables and indexes any restaurants that are
+// missing from them, e.g. rows written before search existed or by the
+// seeder.
+func Migrate(db *gorm.DB) error {
+	if _, err := For(db); err != nil {
+		return err
+	}
+
+	var missing []string
+	if err := db.Model(&models.Restaurant{}).
+		Where("is_active = ? AND id NOT IN (?)", true, db.Model(&Document{}).Select("restaurant_id")).
+		Pluck("id", &missing).Error; err != nil {
+		return err
+	}
+	for _, id := range missing {
+		if err := Reindex(db, id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Reindex rebuilds the document for one restaurant from its current
+// details and menu. Inactive or deleted restaurants are removed.
+func Reindex(db *gorm.DB, restaurantID string) error {
+	idx, err := For(db)
+	if err != nil {
+		return err
+	}
+
+	var r models.Restaurant
+	err = db.Preload("MenuItems", "is_available = ?", true).First(&r, "id = ?", restaurantID).Error
+	vocabFor(db).markStale()
+	if errors.Is(err, gorm.ErrRecordNotFound) || (err == nil && !r.IsActive) {
+		if err := db.Delete(&Document{}, "restaurant_id = ?", restaurantID).Error; err != nil {
+			return err
+		}
+		return idx.Delete(db, restaurantID)
+	}
+	if err != nil {
+		return err
+	}
+
+	lines := make([]string, len(r.MenuItems))
+	for i, m := range r.MenuItems {
+		lines[i] = m.Name
+		if m.Description != "" {
+			lines[i] += ": " + m.Description
+		}
+	}
+	doc := Document{
+		RestaurantID: r.ID,
+		Name:         r.Name,
+		Cuisines:     strings.ReplaceAll(r.Cuisines, ",", ", "),
+		Description:  r.Description,
+		Menu:         strings.Join(lines, "\n"),
+	}
+	if err := db.Save(&doc).Error; err != nil {
+		return err
+	}
+	return idx.Put(db, &doc)
+}
+
+// Result is a restaurant matching a search, with the field that matched
+// best and a snippet of it with the matching words in **bold**.
+type Result struct {
+	RestaurantID string
+	Score        float64
+	MatchedIn    string // name, cuisines, description or menu
+	Highlight    string
+}
+
+// Blank reports whether q has no words to search for, e.g. only
+// punctuation or single letters. Such queries should not filter at all.
+func Blank(q string) bool {
+	return len(parseQuery(q)) == 0
+}
+
+// Search runs a free-text query, tolerating small typos, and returns up to
+// limit restaurants best first. A Blank query returns nil.
+func Search(db *gorm.DB, q string, limit int) ([]Result, error) {
+	terms := parseQuery(q)
+	if len(terms) == 0 {
+		return nil, nil
+	}
+	idx, err := For(db)
+	if err != nil {
+		return nil, err
+	}
+	if err := correctTerms(db, idx, terms); err != nil {
+		return nil, err
+	}
+
+	hits, err := idx.Query(db, terms, limit)
+	if err != nil || len(hits) == 0 {
+		return nil, err
+	}
+
+	ids := make([]string, len(hits))
+	for i, h := range hits {
+		ids[i] = h.RestaurantID
+	}
+	var docs []Document
+	if err := db.Where("restaurant_id IN ?", ids).Find(&docs).Error; err != nil {
+		return nil, err
+	}
+	byID := make(map[string]*Document, len(docs))
+	for i := range docs {
+		byID[docs[i].RestaurantID] = &docs[i]
+	}
+
+	results := make([]Result, 0, len(hits))
+	for _, h := range hits {
+		res := Result{RestaurantID: h.RestaurantID, Score: h.Score}
+		if doc := byID[h.RestaurantID]; doc != nil {
+			res.MatchedIn, res.Highlight = explain(doc, terms)
+		}
+		results = append(results, res)
+	}
+	sort.SliceStable(results, func(i, j int) bool { return results[i].Score > results[j].Score })
+	return results, nil
+}
+
+// explain picks the most telling field of doc that matches the terms and
+// highlights it. For the menu, only the first matching item is shown.
+func explain(doc *Document, terms []Term) (field, snippet string) {
+	if s := highlight(doc.Name, terms); s != "" {
+		return "name", s
+	}
+	if s := highlight(doc.Cuisines, terms); s != "" {
+		return "cuisines", s
+	}
+	if s := highlight(doc.Description, terms); s != "" {
+		return "description", s
+	}
+	for _, line := range strings.Split(doc.Menu, "\n") {
+		if s := highlight(line, terms); s != "" {
+			return "menu", s
+		}
+	}
+	return "", ""
+}
+
+// Matcher scores and highlights arbitrary text against a query, with the
+// same typo tolerance as Search. It is for ranking rows that aren't in the
+// index, such as individual menu items.
+type Matcher struct {
+	terms []Term
+}
+
+// NewMatcher prepares q for matching, correcting misspelled words against
+// the index vocabulary. The result is nil for a Blank query.
+func NewMatcher(db *gorm.DB, q string) (*Matcher, error) {
+	terms := parseQuery(q)
+	if len(terms) == 0 {
+		return nil, nil
+	}
+	idx, err := For(db)
+	if err != nil {
+		return nil, err
+	}
+	if err := correctTerms(db, idx, terms); err != nil {
+		return nil, err
+	}
+	return &Matcher{terms: terms}, nil
+}
+
+// Score returns how well the fields match, weighting earlier fields more.
+// Zero means no match.
+func (m *Matcher) Score(fields ...string) float64 {
+	score := 0.0
+	for i, f := range fields {
+		score += fieldScore(f, m.terms) / float64(i+1)
+	}
+	return score
+}
+
+// Highlight marks the matching words of s in **bold**, returning "" when
+// nothing matches.
+func (m *Matcher) Highlight(s string) string {
+	return highlight(s, m.terms)
+}
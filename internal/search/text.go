@@ -0,0 +1,277 @@
+package search
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/runes"
+	"golang.org/x/text/transform"
+	"golang.org/x/text/unicode/norm"
+)
+
+// fold lowercases s and strips diacritics, so "Jardín" and "jardin" match.
+func fold(s string) string {
+	t := transform.Chain(norm.NFD, runes.Remove(runes.In(unicode.Mn)), norm.NFC)
+	folded, _, err := transform.String(t, s)
+	if err != nil {
+		folded = s
+	}
+	return strings.ToLower(folded)
+}
+
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+// tokenize splits s into folded words.
+func tokenize(s string) []string {
+	return strings.FieldsFunc(fold(s), func(r rune) bool { return !isWordRune(r) })
+}
+
+// Term is one word of a search query. It matches indexed words that start
+// with Text, or that equal one of its spelling corrections.
+type Term struct {
+	Text        string
+	Corrections []string
+}
+
+// matches reports whether the folded word w satisfies the term.
+func (t Term) matches(w string) bool {
+	if strings.HasPrefix(w, t.Text) {
+		return true
+	}
+	for _, c := range t.Corrections {
+		if w == c {
+			return true
+		}
+	}
+	return false
+}
+
+// parseQuery turns free text into search terms, dropping duplicates and
+// single characters, which would prefix-match nearly everything.
+func parseQuery(q string) []Term {
+	var terms []Term
+	seen := map[string]bool{}
+	for _, w := range tokenize(q) {
+		if len([]rune(w)) < 2 || seen[w] {
+			continue
+		}
+		seen[w] = true
+		terms = append(terms, Term{Text: w})
+	}
+	return terms
+}
+
+// maxTypos is how many edits a word of n runes may be away from an indexed
+// word and still count as a misspelling of it.
+func maxTypos(n int) int {
+	switch {
+	case n < 4:
+		return 0
+	case n < 8:
+		return 1
+	default:
+		return 2
+	}
+}
+
+// maxCorrections caps the alternatives tried for one misspelled word.
+const maxCorrections = 3
+
+// misspelled reports whether any term long enough to correct has no word
+// in vocab starting with it.
+func misspelled(terms []Term, vocab []string) bool {
+	for _, t := range terms {
+		if maxTypos(len([]rune(t.Text))) == 0 {
+			continue
+		}
+		known := false
+		for _, w := range vocab {
+			if strings.HasPrefix(w, t.Text) {
+				known = true
+				break
+			}
+		}
+		if !known {
+			return true
+		}
+	}
+	return false
+}
+
+// correct adds spelling corrections to terms that no indexed word starts
+// with, choosing the closest words in vocab.
+func correct(terms []Term, vocab []string) {
+	for i := range terms {
+		t := &terms[i]
+		limit := maxTypos(len([]rune(t.Text)))
+		if limit == 0 {
+			continue
+		}
+
+		type candidate struct {
+			word string
+			dist int
+		}
+		var candidates []candidate
+		known := false
+		for _, w := range vocab {
+			if strings.HasPrefix(w, t.Text) {
+				known = true
+				break
+			}
+			if d := editDistance(t.Text, w, limit); d <= limit {
+				candidates = append(candidates, candidate{w, d})
+			}
+		}
+		if known {
+			continue
+		}
+		sort.Slice(candidates, func(a, b int) bool {
+			if candidates[a].dist != candidates[b].dist {
+				return candidates[a].dist < candidates[b].dist
+			}
+			return candidates[a].word < candidates[b].word
+		})
+		for j := 0; j < len(candidates) && j < maxCorrections; j++ {
+			t.Corrections = append(t.Corrections, candidates[j].word)
+		}
+	}
+}
+
+// editDistance returns the optimal string alignment distance between a and
+// b (Levenshtein plus adjacent transpositions), or limit+1 once it is clear
+// the distance exceeds limit.
+func editDistance(a, b string, limit int) int {
+	ra, rb := []rune(a), []rune(b)
+	if d := len(ra) - len(rb); d > limit || -d > limit {
+		return limit + 1
+	}
+
+	prev2 := make([]int, len(rb)+1)
+	prev := make([]int, len(rb)+1)
+	cur := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		cur[0] = i
+		rowMin := cur[0]
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+			if i > 1 && j > 1 && ra[i-1] == rb[j-2] && ra[i-2] == rb[j-1] {
+				cur[j] = min(cur[j], prev2[j-2]+1)
+			}
+			rowMin = min(rowMin, cur[j])
+		}
+		if rowMin > limit {
+			return limit + 1
+		}
+		prev2, prev, cur = prev, cur, prev2
+	}
+	return prev[len(rb)]
+}
+
+// vocabulary returns the distinct folded words of the given texts.
+func vocabulary(texts ...string) []string {
+	seen := map[string]bool{}
+	var words []string
+	for _, s := range texts {
+		for _, w := range tokenize(s) {
+			if !seen[w] {
+				seen[w] = true
+				words = append(words, w)
+			}
+		}
+	}
+	return words
+}
+
+const (
+	markOpen  = "**"
+	markClose = "**"
+
+	// snippetWords is roughly how many words of a long field a highlight
+	// shows around the first match.
+	snippetWords = 16
+)
+
+type span struct{ start, end int }
+
+// wordSpans returns the byte ranges of the words in s.
+func wordSpans(s string) []span {
+	var spans []span
+	start := -1
+	for i, r := range s {
+		if isWordRune(r) {
+			if start < 0 {
+				start = i
+			}
+		} else if start >= 0 {
+			spans = append(spans, span{start, i})
+			start = -1
+		}
+	}
+	if start >= 0 {
+		spans = append(spans, span{start, len(s)})
+	}
+	return spans
+}
+
+// highlight marks the words of s that match any of the terms. Long text is
+// cut down to a snippet around the first match. It returns "" when nothing
+// in s matches.
+func highlight(s string, terms []Term) string {
+	spans := wordSpans(s)
+	matched := make([]bool, len(spans))
+	first := -1
+	for i, sp := range spans {
+		w := fold(s[sp.start:sp.end])
+		for _, t := range terms {
+			if t.matches(w) {
+				matched[i] = true
+				if first < 0 {
+					first = i
+				}
+				break
+			}
+		}
+	}
+	if first < 0 {
+		return ""
+	}
+
+	from, to := 0, len(spans)
+	if len(spans) > snippetWords {
+		from = max(0, first-snippetWords/4)
+		to = min(len(spans), from+snippetWords)
+	}
+
+	var b strings.Builder
+	if from > 0 {
+		b.WriteString("…")
+	}
+	pos := spans[from].start
+	for i := from; i < to; i++ {
+		sp := spans[i]
+		b.WriteString(s[pos:sp.start])
+		if matched[i] {
+			b.WriteString(markOpen + s[sp.start:sp.end] + markClose)
+		} else {
+			b.WriteString(s[sp.start:sp.end])
+		}
+		pos = sp.end
+	}
+	if to < len(spans) {
+		b.WriteString("…")
+	} else {
+		b.WriteString(s[pos:])
+	}
+	return strings.TrimSpace(b.String())
+}
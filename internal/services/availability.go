@@ -0,0 +1,367 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/agenteats/agenteats/internal/dto"
+	"github.com/agenteats/agenteats/internal/models"
+)
+
+// ErrInvalidInput is returned when a request fails basic validation
+// (malformed dates or times, out-of-range values). It is usually wrapped
+// with a more specific message.
+var ErrInvalidInput = errors.New("invalid input")
+
+// ErrSlotUnavailable is returned when a party no longer fits in the
+// requested slot because overlapping reservations use up the capacity.
+var ErrSlotUnavailable = errors.New("the requested time slot is not available for this party size")
+
+const (
+	// slotInterval is the spacing, in minutes, between bookable seating times.
+	slotInterval = 30
+
+	// defaultLastSeatingMinutes is how long before closing the last table is
+	// seated when a restaurant doesn't set its own offset.
+	defaultLastSeatingMinutes = 30
+
+	// defaultTurnMinutes is how long a table is assumed to be occupied when a
+	// restaurant doesn't set its own turn time.
+	defaultTurnMinutes = 90
+
+	minutesPerDay = 24 * 60
+)
+
+// parseDate parses a YYYY-MM-DD date string.
+func parseDate(s string) (time.Time, error) {
+	d, err := time.Parse("2006-01-02", s)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%w: date must be in YYYY-MM-DD format", ErrInvalidInput)
+	}
+	return d, nil
+}
+
+// parseClock converts an "HH:MM" string into minutes past midnight.
+func parseClock(s string) (int, error) {
+	t, err := time.Parse("15:04", s)
+	if err != nil {
+		return 0, fmt.Errorf("%w: time must be in HH:MM 24-hour format", ErrInvalidInput)
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+// formatClock converts minutes past midnight into an "HH:MM" string.
+func formatClock(m int) string {
+	return fmt.Sprintf("%02d:%02d", m/60, m%60)
+}
+
+func weekdayName(d time.Time) string {
+	return strings.ToLower(d.Weekday().String())
+}
+
+func lastSeatingMinutes(r *models.Restaurant) int {
+	if r.LastSeatingMinutes <= 0 {
+		return defaultLastSeatingMinutes
+	}
+	return r.LastSeatingMinutes
+}
+
+// serviceWindow returns the open and close times of an OperatingHours row in
+// minutes past midnight. Overnight closes (e.g. 18:00–02:00) are extended
+// past midnight so that close is always after open.
+func serviceWindow(h models.OperatingHours) (opens, closes int, ok bool) {
+	if h.IsClosed {
+		return 0, 0, false
+	}
+	opens, err := parseClock(h.OpenTime)
+	if err != nil {
+		return 0, 0, false
+	}
+	closes, err = parseClock(h.CloseTime)
+	if err != nil {
+		return 0, 0, false
+	}
+	if closes <= opens {
+		closes += minutesPerDay
+	}
+	return opens, closes, true
+}
+
+// preloadSchedule preloads everything needed to work out a restaurant's
+// seating times and capacity: weekly hours, upcoming hours overrides, turn
+// times and active tables.
+func preloadSchedule(db *gorm.DB) *gorm.DB {
+	// Two days back covers the previous day's overnight service in time
+	// zones behind the server's.
+	since := time.Now().AddDate(0, 0, -2).Format("2006-01-02")
+	return db.Preload("Hours").
+		Preload("HoursOverrides", "date >= ?", since).
+		Preload("TurnTimes").
+		Preload("Tables", "is_active = ?", true)
+}
+
+// overridesOn returns the closed or hours override for date, if any, and
+// the date's blackouts.
+func overridesOn(r *models.Restaurant, date string) (day *models.HoursOverride, blackouts []models.HoursOverride) {
+	for i, o := range r.HoursOverrides {
+		if o.Date != date {
+			continue
+		}
+		if o.Kind == models.OverrideBlackout {
+			blackouts = append(blackouts, o)
+		} else {
+			day = &r.HoursOverrides[i]
+		}
+	}
+	return day, blackouts
+}
+
+// weeklyHours returns the restaurant's regular hours for date's weekday.
+func weeklyHours(r *models.Restaurant, date time.Time) (models.OperatingHours, bool) {
+	day := weekdayName(date)
+	for _, h := range r.Hours {
+		if strings.ToLower(h.Day) == day {
+			return h, true
+		}
+	}
+	return models.OperatingHours{}, false
+}
+
+// dayWindow returns the service hours for date, preferring a closed or
+// hours override over the weekly hours. See serviceWindow.
+func dayWindow(r *models.Restaurant, date time.Time) (opens, closes int, ok bool) {
+	if o, _ := overridesOn(r, date.Format("2006-01-02")); o != nil {
+		if o.Kind == models.OverrideClosed {
+			return 0, 0, false
+		}
+		return serviceWindow(models.OperatingHours{OpenTime: o.OpenTime, CloseTime: o.CloseTime})
+	}
+	h, ok := weeklyHours(r, date)
+	if !ok {
+		return 0, 0, false
+	}
+	return serviceWindow(h)
+}
+
+// seatingSlots returns the bookable seating times on the given date, as
+// minutes past midnight, derived from the restaurant's weekly hours and any
+// hours overrides for the date.
+//
+// Slots are calendar-correct: the after-midnight part of the previous day's
+// overnight service belongs to this date, and the after-midnight part of
+// this day's service belongs to the next one. When there are no slots, a
+// human-readable reason is returned.
+func seatingSlots(r *models.Restaurant, date time.Time) ([]int, string) {
+	if len(r.Hours) == 0 && len(r.HoursOverrides) == 0 {
+		return nil, "Restaurant has not published its operating hours"
+	}
+	lastSeating := lastSeatingMinutes(r)
+
+	seen := make(map[int]bool)
+	var slots []int
+	add := func(opens, closes, shift int) {
+		for m := opens; m <= closes-lastSeating; m += slotInterval {
+			if s := m + shift; s >= 0 && s < minutesPerDay && !seen[s] {
+				seen[s] = true
+				slots = append(slots, s)
+			}
+		}
+	}
+
+	if opens, closes, ok := dayWindow(r, date.AddDate(0, 0, -1)); ok && closes > minutesPerDay {
+		add(opens, closes, -minutesPerDay)
+	}
+	if opens, closes, ok := dayWindow(r, date); ok {
+		add(opens, closes, 0)
+	}
+
+	override, blackouts := overridesOn(r, date.Format("2006-01-02"))
+	hadSlots := len(slots) > 0
+	for _, b := range blackouts {
+		from, errFrom := parseClock(b.OpenTime)
+		to, errTo := parseClock(b.CloseTime)
+		if errFrom != nil || errTo != nil {
+			continue
+		}
+		kept := slots[:0]
+		for _, m := range slots {
+			if m < from || m >= to {
+				kept = append(kept, m)
+			}
+		}
+		slots = kept
+	}
+
+	if len(slots) == 0 {
+		day := date.Weekday().String()
+		switch {
+		case override != nil && override.Kind == models.OverrideClosed:
+			return nil, withNote(fmt.Sprintf("Restaurant is closed on %s", date.Format("Monday, January 2")), override.Note)
+		case hadSlots && len(blackouts) > 0:
+			return nil, withNote(fmt.Sprintf("No seating times available on %s", date.Format("Monday, January 2")), blackouts[0].Note)
+		case override == nil:
+			if h, ok := weeklyHours(r, date); !ok || h.IsClosed {
+				return nil, fmt.Sprintf("Restaurant is closed on %s", day)
+			}
+		}
+		return nil, fmt.Sprintf("No seating times within operating hours on %s", day)
+	}
+
+	sort.Ints(slots)
+	return slots, ""
+}
+
+// withNote appends an owner's note, such as a holiday name, to a reason.
+func withNote(reason, note string) string {
+	if note == "" {
+		return reason
+	}
+	return fmt.Sprintf("%s (%s)", reason, note)
+}
+
+// turnMinutes returns how long a party of the given size is expected to
+// occupy its seats, using the first matching party-size band if any.
+func turnMinutes(r *models.Restaurant, partySize int) int {
+	for _, t := range r.TurnTimes {
+		if partySize >= t.MinPartySize && (t.MaxPartySize == 0 || partySize <= t.MaxPartySize) {
+			return t.Minutes
+		}
+	}
+	if r.TurnMinutes <= 0 {
+		return defaultTurnMinutes
+	}
+	return r.TurnMinutes
+}
+
+// booking is a confirmed reservation placed on the minute axis of the day
+// being checked. Bookings from the day before or after have negative or
+// >= 1440 offsets so that overlaps across midnight are counted.
+type booking struct {
+	reservationID string
+	start, end    int
+	seats         int
+	tableIDs      []string
+}
+
+// loadBookings returns the confirmed and seated reservations that could
+// overlap any seating on the given date.
+func loadBookings(db *gorm.DB, r *models.Restaurant, day time.Time) []booking {
+	dates := make([]string, 3)
+	for i := range dates {
+		dates[i] = day.AddDate(0, 0, i-1).Format("2006-01-02")
+	}
+
+	var existing []models.Reservation
+	db.Where("restaurant_id = ? AND date IN ? AND status IN ?",
+		r.ID, dates, []models.ReservationStatus{models.StatusConfirmed, models.StatusSeated}).Find(&existing)
+
+	bookings := make([]booking, 0, len(existing))
+	for _, res := range existing {
+		m, err := parseClock(res.Time)
+		if err != nil {
+			continue
+		}
+		for i, d := range dates {
+			if res.Date == d {
+				m += (i - 1) * minutesPerDay
+				break
+			}
+		}
+		bookings = append(bookings, booking{
+			reservationID: res.ID,
+			start:         m,
+			end:           m + turnMinutes(r, res.PartySize),
+			seats:         res.PartySize,
+			tableIDs:      splitCSV(res.TableIDs),
+		})
+	}
+	return bookings
+}
+
+// peakSeats returns the highest number of seats occupied at any moment in
+// [start, end). Occupancy only rises when a booking starts, so it is enough
+// to sample at start and at every booking start inside the window.
+func peakSeats(bookings []booking, start, end int) int {
+	occupiedAt := func(t int) int {
+		seats := 0
+		for _, b := range bookings {
+			if b.start <= t && t < b.end {
+				seats += b.seats
+			}
+		}
+		return seats
+	}
+
+	peak := occupiedAt(start)
+	for _, b := range bookings {
+		if b.start > start && b.start < end {
+			if seats := occupiedAt(b.start); seats > peak {
+				peak = seats
+			}
+		}
+	}
+	return peak
+}
+
+// seatParty reports whether a party can be seated at the given minute.
+// Restaurants with a table inventory need a free table (or combination) for
+// the whole stay, which is returned; otherwise overlapping reservations must
+// not exceed TotalSeats at any point during it.
+func seatParty(r *models.Restaurant, bookings []booking, start, partySize int) ([]models.DiningTable, bool) {
+	if len(r.Tables) > 0 {
+		tables := assignTables(r, bookings, start, partySize)
+		return tables, tables != nil
+	}
+	end := start + turnMinutes(r, partySize)
+	return nil, peakSeats(bookings, start, end)+partySize <= r.TotalSeats
+}
+
+// GetOccupancy returns how full a restaurant is at each seating time on a
+// date, without any guest details. It is the public counterpart to the
+// owner-only reservation listing.
+func GetOccupancy(db *gorm.DB, restaurantID, date string) (*dto.OccupancyOut, error) {
+	day, err := parseDate(date)
+	if err != nil {
+		return nil, err
+	}
+
+	var r models.Restaurant
+	if err := preloadSchedule(db).First(&r, "id = ?", restaurantID).Error; err != nil {
+		return nil, err
+	}
+
+	capacity := r.TotalSeats
+	if len(r.Tables) > 0 {
+		capacity = 0
+		for _, t := range r.Tables {
+			capacity += t.MaxCapacity
+		}
+	}
+
+	out := &dto.OccupancyOut{
+		RestaurantID:   restaurantID,
+		RestaurantName: r.Name,
+		Date:           date,
+		Capacity:       capacity,
+		Slots:          []dto.SlotOccupancyOut{},
+	}
+
+	slots, _ := seatingSlots(&r, day)
+	bookings := loadBookings(db, &r, day)
+	for _, m := range slots {
+		slot := dto.SlotOccupancyOut{Time: formatClock(m)}
+		for _, b := range bookings {
+			if b.start <= m && m < b.end {
+				slot.SeatsBooked += b.seats
+				slot.Reservations++
+			}
+		}
+		out.Slots = append(out.Slots, slot)
+	}
+	return out, nil
+}
@@ -0,0 +1,197 @@
+package services
+
+import (
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/agenteats/agenteats/internal/dto"
+	"github.com/agenteats/agenteats/internal/models"
+)
+
+// everyDay returns the same hours for each day of the week.
+func everyDay(open, close string) []models.OperatingHours {
+	var hours []models.OperatingHours
+	for _, day := range []string{"monday", "tuesday", "wednesday", "thursday", "friday", "saturday", "sunday"} {
+		hours = append(hours, models.OperatingHours{Day: day, OpenTime: open, CloseTime: close})
+	}
+	return hours
+}
+
+func clocks(slots []int) []string {
+	var out []string
+	for _, m := range slots {
+		out = append(out, formatClock(m))
+	}
+	return out
+}
+
+func TestSeatingSlots(t *testing.T) {
+	// A Friday.
+	day := time.Date(2030, 1, 4, 0, 0, 0, 0, time.UTC)
+	const date = "2030-01-04"
+	override := func(kind models.OverrideKind, open, close string) models.HoursOverride {
+		return models.HoursOverride{Date: date, Kind: kind, OpenTime: open, CloseTime: close}
+	}
+
+	tests := []struct {
+		name       string
+		r          models.Restaurant
+		want       []string
+		wantReason string
+	}{
+		{
+			name: "weekly hours",
+			r:    models.Restaurant{Hours: everyDay("11:00", "13:00")},
+			want: []string{"11:00", "11:30", "12:00", "12:30"},
+		},
+		{
+			name: "own last seating",
+			r:    models.Restaurant{Hours: everyDay("11:00", "13:00"), LastSeatingMinutes: 60},
+			want: []string{"11:00", "11:30", "12:00"},
+		},
+		{
+			name:       "closed weekday",
+			r:          models.Restaurant{Hours: []models.OperatingHours{{Day: "friday", IsClosed: true}, {Day: "saturday", OpenTime: "11:00", CloseTime: "13:00"}}},
+			wantReason: "Restaurant is closed on Friday",
+		},
+		{
+			name:       "no hours",
+			r:          models.Restaurant{},
+			wantReason: "Restaurant has not published its operating hours",
+		},
+		{
+			name: "closed override",
+			r: models.Restaurant{
+				Hours:          everyDay("11:00", "13:00"),
+				HoursOverrides: []models.HoursOverride{{Date: date, Kind: models.OverrideClosed, Note: "New Year"}},
+			},
+			wantReason: "Restaurant is closed on Friday, January 4 (New Year)",
+		},
+		{
+			name: "hours override",
+			r: models.Restaurant{
+				Hours:          everyDay("11:00", "13:00"),
+				HoursOverrides: []models.HoursOverride{override(models.OverrideHours, "17:00", "19:00")},
+			},
+			want: []string{"17:00", "17:30", "18:00", "18:30"},
+		},
+		{
+			name: "override on another day",
+			r: models.Restaurant{
+				Hours:          everyDay("11:00", "12:00"),
+				HoursOverrides: []models.HoursOverride{{Date: "2030-01-05", Kind: models.OverrideClosed}},
+			},
+			want: []string{"11:00", "11:30"},
+		},
+		{
+			name: "blackout",
+			r: models.Restaurant{
+				Hours:          everyDay("11:00", "14:00"),
+				HoursOverrides: []models.HoursOverride{override(models.OverrideBlackout, "12:00", "13:00")},
+			},
+			want: []string{"11:00", "11:30", "13:00", "13:30"},
+		},
+		{
+			name: "overnight",
+			r:    models.Restaurant{Hours: everyDay("22:00", "02:00")},
+			// After midnight belongs to the day the seating is on: the
+			// early slots come from Thursday's service, and Friday's own
+			// after-midnight slots are Saturday's.
+			want: []string{"00:00", "00:30", "01:00", "01:30", "22:00", "22:30", "23:00", "23:30"},
+		},
+		{
+			name: "overnight into a closed day",
+			r: models.Restaurant{
+				Hours: append(everyDay("22:00", "01:00")[:3], // Monday to Wednesday
+					models.OperatingHours{Day: "thursday", IsClosed: true},
+					models.OperatingHours{Day: "friday", OpenTime: "22:00", CloseTime: "01:00"}),
+			},
+			want: []string{"22:00", "22:30", "23:00", "23:30"},
+		},
+		{
+			name: "previous day's overnight after an override",
+			r: models.Restaurant{
+				Hours: everyDay("11:00", "13:00"),
+				HoursOverrides: []models.HoursOverride{
+					{Date: "2030-01-03", Kind: models.OverrideHours, OpenTime: "20:00", CloseTime: "01:30"},
+				},
+			},
+			want: []string{"00:00", "00:30", "01:00", "11:00", "11:30", "12:00", "12:30"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			slots, reason := seatingSlots(&tt.r, day)
+			if got := clocks(slots); !slices.Equal(got, tt.want) {
+				t.Fatalf("slots = %v, want %v", got, tt.want)
+			}
+			if tt.wantReason != "" && reason != tt.wantReason {
+				t.Fatalf("reason = %q, want %q", reason, tt.wantReason)
+			}
+		})
+	}
+}
+
+func TestIsOpenAt(t *testing.T) {
+	r := models.Restaurant{Timezone: "America/New_York", Hours: everyDay("18:00", "02:00")}
+	tests := []struct {
+		at   string // UTC
+		want bool
+	}{
+		{"2030-01-04T20:00:00Z", false}, // 15:00 in New York
+		{"2030-01-04T23:00:00Z", true},  // 18:00
+		{"2030-01-05T04:00:00Z", true},  // 23:00
+		{"2030-01-05T06:30:00Z", true},  // 01:30, Friday's service
+		{"2030-01-05T07:00:00Z", false}, // 02:00, closed
+		{"2030-07-05T06:30:00Z", false}, // 02:30 in summer time
+		{"2030-07-05T05:30:00Z", true},  // 01:30 in summer time
+	}
+	for _, tt := range tests {
+		at, _ := time.Parse(time.RFC3339, tt.at)
+		if got := isOpenAt(&r, at); got != tt.want {
+			t.Errorf("isOpenAt(%s) = %v, want %v", tt.at, got, tt.want)
+		}
+	}
+
+	r.HoursOverrides = []models.HoursOverride{{Date: "2030-01-04", Kind: models.OverrideClosed}}
+	if at, _ := time.Parse(time.RFC3339, "2030-01-05T04:00:00Z"); isOpenAt(&r, at) {
+		t.Error("open on a closed override")
+	}
+}
+
+func TestCheckAvailabilityUsesRestaurantTimezone(t *testing.T) {
+	db := openTestDB(t)
+	// Somewhere it is already tomorrow, or still yesterday, in UTC.
+	for _, tz := range []string{"Pacific/Kiritimati", "Pacific/Pago_Pago"} {
+		t.Run(tz, func(t *testing.T) {
+			r := createTestRestaurant(t, db, dto.RestaurantIn{Timezone: tz})
+			loc, _ := time.LoadLocation(tz)
+			local := time.Now().In(loc)
+			yesterday := local.AddDate(0, 0, -1).Format("2006-01-02")
+
+			out, err := CheckAvailability(db, r.ID, yesterday, 2)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(out.AvailableTimes) != 0 {
+				t.Errorf("%s is in the past at the restaurant but offers %v", yesterday, out.AvailableTimes)
+			}
+			_, err = MakeReservation(db, r.ID, dto.ReservationIn{CustomerName: "Guest", PartySize: 2, Date: yesterday, Time: "19:00"})
+			if !errors.Is(err, ErrInvalidInput) {
+				t.Errorf("booking %s: err = %v, want ErrInvalidInput", yesterday, err)
+			}
+
+			tomorrow := local.AddDate(0, 0, 1).Format("2006-01-02")
+			out, err = CheckAvailability(db, r.ID, tomorrow, 2)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(out.AvailableTimes) != 24 || out.AvailableTimes[0] != "11:00" {
+				t.Errorf("%s offers %v, want 11:00 to 22:30", tomorrow, out.AvailableTimes)
+			}
+		})
+	}
+}
@@ -0,0 +1,223 @@
+package services
+
+import (
+	"crypto/subtle"
+	"fmt"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/agenteats/agenteats/internal/calendar"
+	"github.com/agenteats/agenteats/internal/dto"
+	"github.com/agenteats/agenteats/internal/models"
+)
+
+// maxFeedEvents caps the reservations in a restaurant's calendar feed.
+const maxFeedEvents = 2000
+
+// visitTimes returns when a reservation starts and is expected to end at
+// the restaurant: start plus the party's turn time, cut short at closing
+// if the restaurant closes sooner. r must be loaded with preloadSchedule.
+func visitTimes(r *models.Restaurant, res *models.Reservation) (start, end time.Time, err error) {
+	day, err := parseDate(res.Date)
+	if err != nil {
+		return start, end, err
+	}
+	m, err := parseClock(res.Time)
+	if err != nil {
+		return start, end, err
+	}
+	finish := m + turnMinutes(r, res.PartySize)
+	if opens, closes, ok := dayWindow(r, day); ok && m >= opens && m < closes {
+		finish = min(finish, closes)
+	} else if opens, closes, ok := dayWindow(r, day.AddDate(0, 0, -1)); ok && m+minutesPerDay >= opens && m+minutesPerDay < closes {
+		// Seated in the after-midnight part of the previous day's service.
+		finish = min(finish, closes-minutesPerDay)
+	}
+
+	loc := restaurantLocation(r)
+	midnight := time.Date(day.Year(), day.Month(), day.Day(), 0, 0, 0, 0, loc)
+	start = midnight.Add(time.Duration(m) * time.Minute)
+	end = midnight.Add(time.Duration(finish) * time.Minute)
+	return start, end, nil
+}
+
+// eventSequences returns the iCalendar SEQUENCE of each reservation's
+// event: one more for each modification and for a cancellation, so
+// calendar apps replace older copies.
+func eventSequences(db *gorm.DB, reservations []models.Reservation) map[string]int {
+	ids := make([]string, len(reservations))
+	for i, res := range reservations {
+		ids[i] = res.ID
+	}
+	var counts []struct {
+		ReservationID string
+		Changes       int
+	}
+	db.Model(&models.ReservationChange{}).
+		Select("reservation_id, COUNT(*) AS changes").
+		Where("reservation_id IN ?", ids).Group("reservation_id").
+		Scan(&counts)
+	seq := make(map[string]int, len(reservations))
+	for _, c := range counts {
+		seq[c.ReservationID] = c.Changes
+	}
+	for _, res := range reservations {
+		if res.Status == models.StatusCancelled {
+			seq[res.ID]++
+		}
+	}
+	return seq
+}
+
+func eventUID(res *models.Reservation) string {
+	return res.ID + "@agenteats"
+}
+
+// GetReservation returns a reservation to the guest holding its manage
+// token or to the restaurant's owner.
+func GetReservation(db *gorm.DB, reservationID, manageToken, ownerID string) (*dto.ReservationOut, error) {
+	var res models.Reservation
+	if err := db.First(&res, "id = ?", reservationID).Error; err != nil {
+		return nil, err
+	}
+	if _, err := authorizeReservation(db, &res, manageToken, ownerID); err != nil {
+		return nil, err
+	}
+	var r models.Restaurant
+	db.Select("id", "name").First(&r, "id = ?", res.RestaurantID)
+	out := toReservationOut(&res, r.Name)
+	return &out, nil
+}
+
+// ReservationCalendar returns a reservation as an iCalendar file, for the
+// guest holding its manage token or the restaurant's owner. The event is
+// in the restaurant's time zone. It never holds the manage token, since
+// calendar apps sync and share events well beyond the guest.
+func ReservationCalendar(db *gorm.DB, reservationID, manageToken, ownerID string) ([]byte, error) {
+	var res models.Reservation
+	if err := db.First(&res, "id = ?", reservationID).Error; err != nil {
+		return nil, err
+	}
+	actor, err := authorizeReservation(db, &res, manageToken, ownerID)
+	if err != nil {
+		return nil, err
+	}
+	var r models.Restaurant
+	if err := preloadSchedule(db).First(&r, "id = ?", res.RestaurantID).Error; err != nil {
+		return nil, err
+	}
+	start, end, err := visitTimes(&r, &res)
+	if err != nil {
+		return nil, err
+	}
+
+	lines := []string{fmt.Sprintf("Reservation for %s, party of %d.", res.CustomerName, res.PartySize)}
+	if res.SpecialRequests != "" {
+		lines = append(lines, "Special requests: "+res.SpecialRequests)
+	}
+	if r.Phone != "" {
+		lines = append(lines, "Restaurant phone: "+r.Phone)
+	}
+	lines = append(lines, "Reservation ID: "+res.ID)
+	if actor == actorGuest {
+		lines = append(lines, "To change or cancel, use the manage token from your booking confirmation.")
+	}
+	event := calendar.Event{
+		UID:       eventUID(&res),
+		Sequence:  eventSequences(db, []models.Reservation{res})[res.ID],
+		Summary:   fmt.Sprintf("Table for %d at %s", res.PartySize, r.Name),
+		Location:  restaurantAddress(&r),
+		Start:     start,
+		End:       end,
+		Cancelled: res.Status == models.StatusCancelled,
+		Created:   res.CreatedAt,
+	}
+	event.Description = strings.Join(lines, "\n")
+
+	cal := calendar.Calendar{Location: restaurantLocation(&r), Events: []calendar.Event{event}}
+	return cal.Bytes(), nil
+}
+
+// CreateCalendarFeed issues a new calendar feed token for a restaurant,
+// replacing any earlier one, and returns it. It is shown only once.
+func CreateCalendarFeed(db *gorm.DB, restaurantID string) (string, error) {
+	raw, hash := models.GenerateCalendarToken()
+	result := db.Model(&models.Restaurant{}).Where("id = ?", restaurantID).Update("calendar_token_hash", hash)
+	if result.Error != nil {
+		return "", result.Error
+	}
+	if result.RowsAffected == 0 {
+		return "", gorm.ErrRecordNotFound
+	}
+	return raw, nil
+}
+
+// DeleteCalendarFeed turns off a restaurant's calendar feed; subscribed
+// calendars stop updating.
+func DeleteCalendarFeed(db *gorm.DB, restaurantID string) error {
+	return db.Model(&models.Restaurant{}).Where("id = ?", restaurantID).Update("calendar_token_hash", "").Error
+}
+
+// RestaurantCalendar returns the restaurant's upcoming confirmed
+// reservations as an iCalendar feed, for holders of its feed token or its
+// owner.
+func RestaurantCalendar(db *gorm.DB, restaurantID, feedToken, ownerID string) ([]byte, error) {
+	var r models.Restaurant
+	if err := preloadSchedule(db).First(&r, "id = ?", restaurantID).Error; err != nil {
+		return nil, err
+	}
+	authorized := ownerID != "" && r.OwnerID == ownerID
+	if !authorized && feedToken != "" && r.CalendarTokenHash != "" {
+		authorized = subtle.ConstantTimeCompare([]byte(models.HashAPIKey(feedToken)), []byte(r.CalendarTokenHash)) == 1
+	}
+	if !authorized {
+		return nil, fmt.Errorf("%w: a valid calendar feed token or the owner's API key is required", ErrNotAuthorized)
+	}
+
+	today, _ := restaurantClock(&r)
+	var reservations []models.Reservation
+	if err := db.Where("restaurant_id = ? AND status = ? AND date >= ?",
+		r.ID, models.StatusConfirmed, today.Format("2006-01-02")).
+		Order("date ASC, time ASC, id ASC").Limit(maxFeedEvents).
+		Find(&reservations).Error; err != nil {
+		return nil, err
+	}
+
+	sequences := eventSequences(db, reservations)
+	cal := calendar.Calendar{
+		Name:     r.Name + " reservations",
+		Location: restaurantLocation(&r),
+		Events:   make([]calendar.Event, 0, len(reservations)),
+	}
+	for i := range reservations {
+		res := &reservations[i]
+		start, end, err := visitTimes(&r, res)
+		if err != nil {
+			continue
+		}
+		var lines []string
+		if res.CustomerPhone != "" {
+			lines = append(lines, "Phone: "+res.CustomerPhone)
+		}
+		if res.CustomerEmail != "" {
+			lines = append(lines, "Email: "+res.CustomerEmail)
+		}
+		if res.SpecialRequests != "" {
+			lines = append(lines, "Special requests: "+res.SpecialRequests)
+		}
+		lines = append(lines, "Reservation ID: "+res.ID)
+		cal.Events = append(cal.Events, calendar.Event{
+			UID:         eventUID(res),
+			Sequence:    sequences[res.ID],
+			Summary:     fmt.Sprintf("%s (%d)", res.CustomerName, res.PartySize),
+			Description: strings.Join(lines, "\n"),
+			Location:    restaurantAddress(&r),
+			Start:       start,
+			End:         end,
+			Created:     res.CreatedAt,
+		})
+	}
+	return cal.Bytes(), nil
+}
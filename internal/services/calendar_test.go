@@ -0,0 +1,28 @@
+package services
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/agenteats/agenteats/internal/dto"
+)
+
+func TestReservationCalendarLeavesOutToken(t *testing.T) {
+	db := openTestDB(t)
+	r := createTestRestaurant(t, db, dto.RestaurantIn{})
+	res, err := MakeReservation(db, r.ID, dto.ReservationIn{CustomerName: "Guest", PartySize: 2, Date: daysFromNow(3), Time: "19:00"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ics, err := ReservationCalendar(db, res.ID, res.ManageToken, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	body := string(ics)
+	if strings.Contains(body, res.ManageToken) || strings.Contains(body, "token=") {
+		t.Fatalf("calendar holds the manage token:\n%s", body)
+	}
+	if !strings.Contains(body, res.ID) || !strings.Contains(body, "DTSTART;TZID=UTC:") {
+		t.Fatalf("calendar is missing the reservation:\n%s", body)
+	}
+}
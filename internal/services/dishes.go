@@ -0,0 +1,177 @@
+package services
+
+import (
+	"fmt"
+	"sort"
+
+	"gorm.io/gorm"
+
+	"github.com/agenteats/agenteats/internal/dto"
+	"github.com/agenteats/agenteats/internal/models"
+	"github.com/agenteats/agenteats/internal/search"
+)
+
+// dishCandidates caps how many menu items a free-text dish search scores
+// after the structured filters are applied.
+const dishCandidates = 2000
+
+// SearchDishes finds available menu items across all active restaurants.
+// Structured filters run in SQL. Free text first narrows the search to the
+// restaurants the search index matches, whose documents hold every
+// available dish's name and description, and is then matched against each
+// dish in Go so it gets the same typo tolerance as restaurant search. Only
+// the best searchCandidates restaurants and, of their dishes, the first
+// dishCandidates in popular-then-cheapest order are considered. Results
+// are ranked by text relevance, then popular dishes first, then price.
+func SearchDishes(db *gorm.DB, q dto.DishQuery) (*dto.Page[dto.DishResult], error) {
+	if q.MinPrice != nil && *q.MinPrice < 0 || q.MaxPrice != nil && *q.MaxPrice < 0 {
+		return nil, fmt.Errorf("%w: prices can't be negative", ErrInvalidInput)
+	}
+	if q.MinPrice != nil && q.MaxPrice != nil && *q.MinPrice > *q.MaxPrice {
+		return nil, fmt.Errorf("%w: min_price is above max_price", ErrInvalidInput)
+	}
+
+	offset, err := startOffset(q.Cursor, q.Offset)
+	if err != nil {
+		return nil, err
+	}
+	if q.Limit <= 0 {
+		q.Limit = 20
+	}
+
+	matcher, err := search.NewMatcher(db, q.Q)
+	if err != nil {
+		return nil, err
+	}
+
+	query := db.Model(&models.MenuItem{}).
+		Joins("JOIN restaurants ON restaurants.id = menu_items.restaurant_id").
+		Where("restaurants.is_active = ? AND menu_items.is_available = ?", true, true)
+	if q.City != "" {
+		query = query.Where("restaurants.city LIKE ?", "%"+q.City+"%")
+	}
+	if q.PriceRange != "" {
+		query = query.Where("restaurants.price_range = ?", q.PriceRange)
+	}
+	if q.Category != "" {
+		query = query.Where("LOWER(menu_items.category) = LOWER(?)", q.Category)
+	}
+	if len(q.Dietary) > 0 {
+		idx, err := loadTaxonomy(db)
+		if err != nil {
+			return nil, err
+		}
+		dietary, err := resolveTerms(idx, models.KindDietary, q.Dietary)
+		if err != nil {
+			return nil, err
+		}
+		for _, d := range dietary {
+			query = query.Where(hasTermSQL("menu_item_dietary_labels", "menu_items", "menu_item_id"), d.ID)
+		}
+	}
+	if len(q.ExcludeAllergens) > 0 {
+		idx, err := loadTaxonomy(db)
+		if err != nil {
+			return nil, err
+		}
+		allergens, err := resolveTerms(idx, models.KindAllergen, q.ExcludeAllergens)
+		if err != nil {
+			return nil, err
+		}
+		query = withoutAllergens(query, allergens)
+	}
+	if q.MinPrice != nil {
+		query = query.Where("menu_items.price >= ?", *q.MinPrice)
+	}
+	if q.MaxPrice != nil {
+		query = query.Where("menu_items.price <= ?", *q.MaxPrice)
+	}
+	if q.MaxCalories != nil {
+		query = query.Where("menu_items.calories IS NOT NULL AND menu_items.calories <= ?", *q.MaxCalories)
+	}
+	if matcher != nil {
+		results, err := search.Search(db, q.Q, searchCandidates)
+		if err != nil {
+			return nil, err
+		}
+		if len(results) == 0 {
+			return &dto.Page[dto.DishResult]{Items: []dto.DishResult{}}, nil
+		}
+		ids := make([]string, len(results))
+		for i, res := range results {
+			ids[i] = res.RestaurantID
+		}
+		query = query.Where("menu_items.restaurant_id IN ?", ids)
+	}
+	// The Session makes the filtered query safe to reuse for the count.
+	query = query.Session(&gorm.Session{})
+
+	var total int64
+	var items []models.MenuItem
+	if matcher == nil {
+		if err := query.Count(&total).Error; err != nil {
+			return nil, err
+		}
+		query = query.Offset(offset).Limit(q.Limit)
+	} else {
+		query = query.Limit(dishCandidates)
+	}
+	if err := query.Order("menu_items.is_popular DESC, menu_items.price ASC, menu_items.id ASC").
+		Find(&items).Error; err != nil {
+		return nil, err
+	}
+
+	type match struct {
+		item      *models.MenuItem
+		score     float64
+		highlight string
+	}
+	matches := make([]match, 0, len(items))
+	for i := range items {
+		m := match{item: &items[i]}
+		if matcher != nil {
+			m.score = matcher.Score(m.item.Name, m.item.Category, m.item.Description)
+			if m.score == 0 {
+				continue
+			}
+			m.highlight = matcher.Highlight(m.item.Name)
+			if m.highlight == "" {
+				m.highlight = matcher.Highlight(m.item.Name + ": " + m.item.Description)
+			}
+		}
+		matches = append(matches, m)
+	}
+	if matcher != nil {
+		// Stable, so equal scores keep the popular-then-cheapest order.
+		sort.SliceStable(matches, func(i, j int) bool { return matches[i].score > matches[j].score })
+		total = int64(len(matches))
+		matches = matches[min(offset, len(matches)):min(len(matches), offset+q.Limit)]
+	}
+
+	restaurantIDs := make([]string, 0, len(matches))
+	for _, m := range matches {
+		restaurantIDs = append(restaurantIDs, m.item.RestaurantID)
+	}
+	var restaurants []models.Restaurant
+	if err := db.Where("id IN ?", restaurantIDs).Find(&restaurants).Error; err != nil {
+		return nil, err
+	}
+	summaries := make(map[string]dto.RestaurantSummary, len(restaurants))
+	for i := range restaurants {
+		summaries[restaurants[i].ID] = toSummary(&restaurants[i])
+	}
+
+	page := &dto.Page[dto.DishResult]{
+		Items:         make([]dto.DishResult, len(matches)),
+		NextCursor:    nextOffsetCursor(offset, len(matches), total),
+		TotalEstimate: total,
+	}
+	for i, m := range matches {
+		page.Items[i] = dto.DishResult{
+			Item:       toMenuItemOut(m.item),
+			Restaurant: summaries[m.item.RestaurantID],
+			Highlight:  m.highlight,
+		}
+	}
+	return page, nil
+}
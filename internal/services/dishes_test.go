@@ -0,0 +1,84 @@
+package services
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/agenteats/agenteats/internal/dto"
+)
+
+func TestSearchDishesText(t *testing.T) {
+	db := openTestDB(t)
+	add := func(restaurant string, items ...dto.MenuItemIn) string {
+		r := createTestRestaurant(t, db, dto.RestaurantIn{Name: restaurant})
+		for _, in := range items {
+			if _, err := AddMenuItem(db, r.ID, in); err != nil {
+				t.Fatal(err)
+			}
+		}
+		return r.ID
+	}
+	add("Trattoria",
+		dto.MenuItemIn{Name: "Mushroom Risotto", Price: 18, Description: "Porcini and parmesan"},
+		dto.MenuItemIn{Name: "Tiramisu", Price: 8},
+	)
+	add("Osteria",
+		dto.MenuItemIn{Name: "Saffron Risotto", Price: 20, IsPopular: true},
+		dto.MenuItemIn{Name: "Lasagne", Price: 16, Description: "With a side of risotto cakes"},
+	)
+	diner := add("Diner", dto.MenuItemIn{Name: "Cheeseburger", Price: 12})
+
+	names := func(p *dto.Page[dto.DishResult]) []string {
+		var out []string
+		for _, d := range p.Items {
+			out = append(out, d.Item.Name)
+		}
+		return out
+	}
+	tests := []struct {
+		q    string
+		want []string
+	}{
+		{q: "risotto", want: []string{"Saffron Risotto", "Mushroom Risotto", "Lasagne"}},
+		{q: "risoto", want: []string{"Saffron Risotto", "Mushroom Risotto", "Lasagne"}},
+		{q: "porcini", want: []string{"Mushroom Risotto"}},
+		{q: "cheeseburger", want: []string{"Cheeseburger"}},
+		{q: "sushi", want: nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.q, func(t *testing.T) {
+			page, err := SearchDishes(db, dto.DishQuery{Q: tt.q})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := names(page); !slices.Equal(got, tt.want) {
+				t.Fatalf("SearchDishes(%q) = %v, want %v", tt.q, got, tt.want)
+			}
+		})
+	}
+
+	// Text results page like the others.
+	page, err := SearchDishes(db, dto.DishQuery{Q: "risotto", Limit: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	next, err := SearchDishes(db, dto.DishQuery{Q: "risotto", Limit: 2, Cursor: page.NextCursor})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := append(names(page), names(next)...); page.TotalEstimate != 3 || !slices.Equal(got, tests[0].want) || next.NextCursor != "" {
+		t.Fatalf("pages = %v (total %d), want %v", got, page.TotalEstimate, tests[0].want)
+	}
+
+	// A dish taken off the menu leaves the index with it.
+	menu, err := GetMenu(db, diner, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := SetMenuItemAvailability(db, diner, menu.Categories["Main"][0].ID, false); err != nil {
+		t.Fatal(err)
+	}
+	if page, err := SearchDishes(db, dto.DishQuery{Q: "cheeseburger"}); err != nil || len(page.Items) != 0 {
+		t.Fatalf("unavailable dish: %v, %v", names(page), err)
+	}
+}
@@ -0,0 +1,88 @@
+package services
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/agenteats/agenteats/internal/dto"
+)
+
+const (
+	earthRadiusKm = 6371.0
+
+	defaultSearchRadiusKm = 5.0
+	maxSearchRadiusKm     = 100.0
+)
+
+// geoQuery is a validated "near a point" search.
+type geoQuery struct {
+	lat, lng, radiusKm float64
+}
+
+// parseGeoQuery validates the location part of a restaurant search. It
+// returns nil when the search has no location.
+func parseGeoQuery(q dto.RestaurantQuery) (*geoQuery, error) {
+	if q.Latitude == nil && q.Longitude == nil {
+		if q.RadiusKm != 0 {
+			return nil, fmt.Errorf("%w: radius_km needs lat and lng", ErrInvalidInput)
+		}
+		return nil, nil
+	}
+	if q.Latitude == nil || q.Longitude == nil {
+		return nil, fmt.Errorf("%w: lat and lng must be given together", ErrInvalidInput)
+	}
+	g := geoQuery{lat: *q.Latitude, lng: *q.Longitude, radiusKm: q.RadiusKm}
+	if g.lat < -90 || g.lat > 90 || math.IsNaN(g.lat) {
+		return nil, fmt.Errorf("%w: lat must be between -90 and 90", ErrInvalidInput)
+	}
+	if g.lng < -180 || g.lng > 180 || math.IsNaN(g.lng) {
+		return nil, fmt.Errorf("%w: lng must be between -180 and 180", ErrInvalidInput)
+	}
+	if g.radiusKm == 0 {
+		g.radiusKm = defaultSearchRadiusKm
+	}
+	if g.radiusKm < 0 || g.radiusKm > maxSearchRadiusKm || math.IsNaN(g.radiusKm) {
+		return nil, fmt.Errorf("%w: radius_km must be between 0 and %g", ErrInvalidInput, maxSearchRadiusKm)
+	}
+	return &g, nil
+}
+
+// boundingBox returns the latitude and longitude ranges that contain every
+// point within the search radius. It is a cheap, index-friendly pre-filter;
+// exact distances are checked with haversineKm afterwards. lngOK is false
+// when the box spans a pole or the antimeridian and longitude can't be
+// bounded by a single range.
+func (g *geoQuery) boundingBox() (minLat, maxLat, minLng, maxLng float64, lngOK bool) {
+	dLat := g.radiusKm / earthRadiusKm * 180 / math.Pi
+	minLat, maxLat = g.lat-dLat, g.lat+dLat
+	if minLat <= -90 || maxLat >= 90 {
+		return max(minLat, -90), min(maxLat, 90), 0, 0, false
+	}
+	dLng := dLat / math.Cos(g.lat*math.Pi/180)
+	minLng, maxLng = g.lng-dLng, g.lng+dLng
+	if minLng < -180 || maxLng > 180 {
+		return minLat, maxLat, 0, 0, false
+	}
+	return minLat, maxLat, minLng, maxLng, true
+}
+
+// nearestFirst returns an ORDER BY expression that sorts points by their
+// distance from the search's centre, close enough to pick candidates by:
+// an equirectangular approximation, which needs no trigonometry in SQL.
+// Exact distances are checked with haversineKm afterwards.
+func (g *geoQuery) nearestFirst() string {
+	// The values are validated floats, so formatting them is safe.
+	k := math.Cos(g.lat * math.Pi / 180)
+	return fmt.Sprintf("(latitude - (%g)) * (latitude - (%g)) + (longitude - (%g)) * (longitude - (%g)) * %g",
+		g.lat, g.lat, g.lng, g.lng, k*k)
+}
+
+// haversineKm returns the great-circle distance between two points.
+func haversineKm(lat1, lng1, lat2, lng2 float64) float64 {
+	const rad = math.Pi / 180
+	dLat := (lat2 - lat1) * rad
+	dLng := (lng2 - lng1) * rad
+	a := math.Sin(dLat/2)*math.Sin(dLat/2) +
+		math.Cos(lat1*rad)*math.Cos(lat2*rad)*math.Sin(dLng/2)*math.Sin(dLng/2)
+	return 2 * earthRadiusKm * math.Asin(math.Sqrt(min(a, 1)))
+}
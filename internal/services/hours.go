@@ -0,0 +1,166 @@
+package services
+
+import (
+	"fmt"
+	"strings"
+
+	"gorm.io/gorm"
+
+	"github.com/agenteats/agenteats/internal/dto"
+	"github.com/agenteats/agenteats/internal/models"
+)
+
+func toHoursOverrideOut(o *models.HoursOverride) dto.HoursOverrideOut {
+	return dto.HoursOverrideOut{
+		ID:        o.ID,
+		Date:      o.Date,
+		Kind:      string(o.Kind),
+		OpenTime:  o.OpenTime,
+		CloseTime: o.CloseTime,
+		Note:      o.Note,
+	}
+}
+
+// validateHoursOverride checks an override payload and normalizes its date
+// and times. Closed overrides carry no times.
+func validateHoursOverride(in *dto.HoursOverrideIn) error {
+	day, err := parseDate(in.Date)
+	if err != nil {
+		return err
+	}
+	in.Date = day.Format("2006-01-02")
+	in.Note = strings.TrimSpace(in.Note)
+	if len(in.Note) > 200 {
+		return fmt.Errorf("%w: note must be at most 200 characters", ErrInvalidInput)
+	}
+
+	switch models.OverrideKind(in.Kind) {
+	case models.OverrideClosed:
+		in.OpenTime, in.CloseTime = "", ""
+		return nil
+	case models.OverrideHours, models.OverrideBlackout:
+	default:
+		return fmt.Errorf("%w: kind must be closed, hours or blackout", ErrInvalidInput)
+	}
+
+	opens, err := parseClock(in.OpenTime)
+	if err != nil {
+		return fmt.Errorf("%w: open_time must be in HH:MM 24-hour format", ErrInvalidInput)
+	}
+	closes, err := parseClock(in.CloseTime)
+	if err != nil {
+		return fmt.Errorf("%w: close_time must be in HH:MM 24-hour format", ErrInvalidInput)
+	}
+	if models.OverrideKind(in.Kind) == models.OverrideBlackout && closes <= opens {
+		return fmt.Errorf("%w: a blackout must end after it starts on the same day", ErrInvalidInput)
+	}
+	in.OpenTime, in.CloseTime = formatClock(opens), formatClock(closes)
+	return nil
+}
+
+// checkOverrideConflict rejects a second closed/hours override for the same
+// date; blackouts can be stacked freely.
+func checkOverrideConflict(db *gorm.DB, restaurantID, overrideID string, in dto.HoursOverrideIn) error {
+	if models.OverrideKind(in.Kind) == models.OverrideBlackout {
+		return nil
+	}
+	var count int64
+	db.Model(&models.HoursOverride{}).
+		Where("restaurant_id = ? AND date = ? AND kind <> ? AND id <> ?",
+			restaurantID, in.Date, models.OverrideBlackout, overrideID).
+		Count(&count)
+	if count > 0 {
+		return fmt.Errorf("%w: %s already has a closure or special hours; update that one instead", ErrInvalidInput, in.Date)
+	}
+	return nil
+}
+
+// --- Hours Overrides ---
+
+// ListHoursOverrides returns a restaurant's hours overrides from the given
+// date (YYYY-MM-DD) onwards, or all of them if from is empty.
+func ListHoursOverrides(db *gorm.DB, restaurantID, from string) ([]dto.HoursOverrideOut, error) {
+	query := db.Where("restaurant_id = ?", restaurantID)
+	if from != "" {
+		if _, err := parseDate(from); err != nil {
+			return nil, err
+		}
+		query = query.Where("date >= ?", from)
+	}
+	var overrides []models.HoursOverride
+	query.Order("date, open_time").Find(&overrides)
+
+	results := make([]dto.HoursOverrideOut, len(overrides))
+	for i := range overrides {
+		results[i] = toHoursOverrideOut(&overrides[i])
+	}
+	return results, nil
+}
+
+// CreateHoursOverride adds a closure, special hours or blackout on a date.
+// Existing reservations on that date are left for the owner to handle.
+func CreateHoursOverride(db *gorm.DB, restaurantID string, in dto.HoursOverrideIn) (*dto.HoursOverrideOut, error) {
+	if err := validateHoursOverride(&in); err != nil {
+		return nil, err
+	}
+	var r models.Restaurant
+	if err := db.First(&r, "id = ?", restaurantID).Error; err != nil {
+		return nil, err
+	}
+	if err := checkOverrideConflict(db, restaurantID, "", in); err != nil {
+		return nil, err
+	}
+
+	o := models.HoursOverride{
+		ID:           models.NewID(),
+		RestaurantID: restaurantID,
+		Date:         in.Date,
+		Kind:         models.OverrideKind(in.Kind),
+		OpenTime:     in.OpenTime,
+		CloseTime:    in.CloseTime,
+		Note:         in.Note,
+	}
+	if err := db.Create(&o).Error; err != nil {
+		return nil, err
+	}
+	out := toHoursOverrideOut(&o)
+	return &out, nil
+}
+
+// UpdateHoursOverride replaces an override's attributes.
+func UpdateHoursOverride(db *gorm.DB, restaurantID, overrideID string, in dto.HoursOverrideIn) (*dto.HoursOverrideOut, error) {
+	if err := validateHoursOverride(&in); err != nil {
+		return nil, err
+	}
+	var o models.HoursOverride
+	if err := db.First(&o, "id = ? AND restaurant_id = ?", overrideID, restaurantID).Error; err != nil {
+		return nil, err
+	}
+	if err := checkOverrideConflict(db, restaurantID, overrideID, in); err != nil {
+		return nil, err
+	}
+
+	o.Date = in.Date
+	o.Kind = models.OverrideKind(in.Kind)
+	o.OpenTime = in.OpenTime
+	o.CloseTime = in.CloseTime
+	o.Note = in.Note
+	if err := db.Save(&o).Error; err != nil {
+		return nil, err
+	}
+	out := toHoursOverrideOut(&o)
+	return &out, nil
+}
+
+// DeleteHoursOverride removes an override, restoring the weekly hours for
+// its date.
+func DeleteHoursOverride(db *gorm.DB, restaurantID, overrideID string) error {
+	result := db.Where("id = ? AND restaurant_id = ?", overrideID, restaurantID).Delete(&models.HoursOverride{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
@@ -0,0 +1,538 @@
+package services
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"gorm.io/gorm"
+
+	"github.com/agenteats/agenteats/internal/dto"
+	"github.com/agenteats/agenteats/internal/models"
+)
+
+// validateMenuItem checks the fields every menu item needs.
+func validateMenuItem(name string, price float64) error {
+	if strings.TrimSpace(name) == "" {
+		return fmt.Errorf("%w: name is required", ErrInvalidInput)
+	}
+	if price < 0 {
+		return fmt.Errorf("%w: price must not be negative", ErrInvalidInput)
+	}
+	return nil
+}
+
+// maxExternalID is the longest external ID an item can have.
+const maxExternalID = 100
+
+// checkExternalID makes sure no other item on the restaurant's menu uses
+// externalID. itemID is the item being saved, if it exists already.
+func checkExternalID(db *gorm.DB, restaurantID, externalID, itemID string) error {
+	if externalID == "" {
+		return nil
+	}
+	if len(externalID) > maxExternalID {
+		return fmt.Errorf("%w: external_id must be at most %d characters", ErrInvalidInput, maxExternalID)
+	}
+	var n int64
+	db.Model(&models.MenuItem{}).
+		Where("restaurant_id = ? AND external_id = ? AND id <> ?", restaurantID, externalID, itemID).
+		Count(&n)
+	if n > 0 {
+		return fmt.Errorf("%w: external_id %q is already used by another item", ErrInvalidInput, externalID)
+	}
+	return nil
+}
+
+// resolveAllergens maps a menu item's allergens to terms. An allergen the
+// dish contains can't also be one it only may contain.
+func resolveAllergens(idx *models.TaxonomyIndex, contains, mayContain []string) (c, m []models.TaxonomyTerm, err error) {
+	if c, err = resolveTerms(idx, models.KindAllergen, contains); err != nil {
+		return nil, nil, err
+	}
+	if m, err = resolveTerms(idx, models.KindAllergen, mayContain); err != nil {
+		return nil, nil, err
+	}
+	for _, a := range m {
+		for _, b := range c {
+			if a.ID == b.ID {
+				return nil, nil, fmt.Errorf("%w: %s is in both allergens and may_contain_allergens", ErrInvalidInput, a.Slug)
+			}
+		}
+	}
+	return c, m, nil
+}
+
+// deleteMenuItemLinks removes the taxonomy links and option groups of the
+// given menu items, before the items themselves are deleted.
+func deleteMenuItemLinks(tx *gorm.DB, itemIDs []string) error {
+	for _, table := range []string{"menu_item_dietary_labels", "menu_item_allergens", "menu_item_may_contain_allergens"} {
+		if err := tx.Exec("DELETE FROM "+table+" WHERE menu_item_id IN ?", itemIDs).Error; err != nil {
+			return err
+		}
+	}
+	return deleteOptionGroups(tx, itemIDs)
+}
+
+// nextSortOrder returns the position after the last item in a category.
+func nextSortOrder(db *gorm.DB, restaurantID, category string) int {
+	var last int
+	db.Model(&models.MenuItem{}).
+		Where("restaurant_id = ? AND category = ?", restaurantID, category).
+		Select("COALESCE(MAX(sort_order), 0)").Scan(&last)
+	return last + 1
+}
+
+// categoryOrder returns the names of the menu's categories in display
+// order: those the owner has ordered first, then the rest alphabetically.
+func categoryOrder(db *gorm.DB, restaurantID string, categories map[string][]dto.MenuItemOut) []string {
+	var ordered []models.MenuCategory
+	db.Where("restaurant_id = ?", restaurantID).Order("sort_order, name").Find(&ordered)
+
+	out := make([]string, 0, len(categories))
+	listed := make(map[string]bool, len(ordered))
+	for _, c := range ordered {
+		if _, ok := categories[c.Name]; ok && !listed[c.Name] {
+			listed[c.Name] = true
+			out = append(out, c.Name)
+		}
+	}
+	var rest []string
+	for name := range categories {
+		if !listed[name] {
+			rest = append(rest, name)
+		}
+	}
+	sort.Strings(rest)
+	return append(out, rest...)
+}
+
+// ownedMenuItem loads a menu item and its options, making sure it is on
+// the restaurant's menu.
+func ownedMenuItem(db *gorm.DB, restaurantID, itemID string) (*models.MenuItem, error) {
+	var item models.MenuItem
+	if err := preloadOptions(db).Where("id = ? AND restaurant_id = ?", itemID, restaurantID).First(&item).Error; err != nil {
+		return nil, err
+	}
+	return &item, nil
+}
+
+// Limits on a menu item's option groups.
+const (
+	maxOptionGroups = 20
+	maxOptions      = 50
+)
+
+// preloadOptions loads menu items' option groups and their options in
+// display order.
+func preloadOptions(db *gorm.DB) *gorm.DB {
+	bySortOrder := func(db *gorm.DB) *gorm.DB { return db.Order("sort_order") }
+	return db.Preload("OptionGroups", bySortOrder).Preload("OptionGroups.Options", bySortOrder)
+}
+
+// buildOptionGroups validates a menu item's option groups and turns them
+// into models, in the given order. A required group needs at least one
+// selection, and a group with a minimum is required.
+func buildOptionGroups(idx *models.TaxonomyIndex, itemID string, in []dto.MenuOptionGroupIn) ([]models.MenuOptionGroup, error) {
+	if len(in) > maxOptionGroups {
+		return nil, fmt.Errorf("%w: an item can have at most %d option groups", ErrInvalidInput, maxOptionGroups)
+	}
+	groups := make([]models.MenuOptionGroup, 0, len(in))
+	groupNames := make(map[string]bool, len(in))
+	for i, g := range in {
+		name := strings.TrimSpace(g.Name)
+		if name == "" {
+			return nil, fmt.Errorf("%w: option group %d needs a name", ErrInvalidInput, i+1)
+		}
+		if groupNames[strings.ToLower(name)] {
+			return nil, fmt.Errorf("%w: option group %q is listed twice", ErrInvalidInput, name)
+		}
+		groupNames[strings.ToLower(name)] = true
+		if len(g.Options) == 0 {
+			return nil, fmt.Errorf("%w: option group %q has no options", ErrInvalidInput, name)
+		}
+		if len(g.Options) > maxOptions {
+			return nil, fmt.Errorf("%w: option group %q can have at most %d options", ErrInvalidInput, name, maxOptions)
+		}
+		if g.MinSelections < 0 || g.MaxSelections < 0 {
+			return nil, fmt.Errorf("%w: option group %q: selections must not be negative", ErrInvalidInput, name)
+		}
+		minSelections := g.MinSelections
+		if g.Required && minSelections == 0 {
+			minSelections = 1
+		}
+		if g.MaxSelections > 0 && minSelections > g.MaxSelections {
+			return nil, fmt.Errorf("%w: option group %q: min_selections is more than max_selections", ErrInvalidInput, name)
+		}
+		if minSelections > len(g.Options) {
+			return nil, fmt.Errorf("%w: option group %q: min_selections is more than its %d options", ErrInvalidInput, name, len(g.Options))
+		}
+
+		group := models.MenuOptionGroup{
+			ID:            models.NewID(),
+			MenuItemID:    itemID,
+			Name:          name,
+			Required:      minSelections > 0,
+			MinSelections: minSelections,
+			MaxSelections: g.MaxSelections,
+			SortOrder:     i + 1,
+			Options:       make([]models.MenuOption, 0, len(g.Options)),
+		}
+		optionNames := make(map[string]bool, len(g.Options))
+		for j, o := range g.Options {
+			optionName := strings.TrimSpace(o.Name)
+			if optionName == "" {
+				return nil, fmt.Errorf("%w: option %d of group %q needs a name", ErrInvalidInput, j+1, name)
+			}
+			if optionNames[strings.ToLower(optionName)] {
+				return nil, fmt.Errorf("%w: option %q is listed twice in group %q", ErrInvalidInput, optionName, name)
+			}
+			optionNames[strings.ToLower(optionName)] = true
+			dietary, err := resolveTerms(idx, models.KindDietary, o.DietaryLabels)
+			if err != nil {
+				return nil, err
+			}
+			allergens, err := resolveTerms(idx, models.KindAllergen, o.Allergens)
+			if err != nil {
+				return nil, err
+			}
+			group.Options = append(group.Options, models.MenuOption{
+				ID:            models.NewID(),
+				GroupID:       group.ID,
+				Name:          optionName,
+				PriceDelta:    o.PriceDelta,
+				DietaryLabels: models.TermsCSV(dietary),
+				Allergens:     models.TermsCSV(allergens),
+				SortOrder:     j + 1,
+				AllergenTerms: allergens,
+			})
+		}
+		groups = append(groups, group)
+	}
+	return groups, nil
+}
+
+// sameOptionGroups reports whether two sets of option groups offer the same
+// choices, ignoring their IDs.
+func sameOptionGroups(a, b []models.MenuOptionGroup) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		x, y := a[i], b[i]
+		if x.Name != y.Name || x.Required != y.Required || x.MinSelections != y.MinSelections ||
+			x.MaxSelections != y.MaxSelections || len(x.Options) != len(y.Options) {
+			return false
+		}
+		for j := range x.Options {
+			o, p := x.Options[j], y.Options[j]
+			if o.Name != p.Name || o.PriceDelta != p.PriceDelta || o.DietaryLabels != p.DietaryLabels ||
+				o.Allergens != p.Allergens {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// deleteOptionGroups removes the option groups of the given menu items,
+// with their options' allergen links.
+func deleteOptionGroups(tx *gorm.DB, itemIDs []string) error {
+	groups := tx.Model(&models.MenuOptionGroup{}).Select("id").Where("menu_item_id IN ?", itemIDs)
+	options := tx.Model(&models.MenuOption{}).Select("id").Where("group_id IN (?)", groups)
+	if err := tx.Exec("DELETE FROM menu_option_allergens WHERE menu_option_id IN (?)", options).Error; err != nil {
+		return err
+	}
+	if err := tx.Where("group_id IN (?)", groups).Delete(&models.MenuOption{}).Error; err != nil {
+		return err
+	}
+	return tx.Where("menu_item_id IN ?", itemIDs).Delete(&models.MenuOptionGroup{}).Error
+}
+
+// replaceOptionGroups swaps a menu item's option groups for groups.
+func replaceOptionGroups(tx *gorm.DB, itemID string, groups []models.MenuOptionGroup) error {
+	if err := deleteOptionGroups(tx, []string{itemID}); err != nil {
+		return err
+	}
+	if len(groups) == 0 {
+		return nil
+	}
+	return tx.Create(&groups).Error
+}
+
+// UpdateMenuItem replaces every field of a menu item. An omitted sort order
+// keeps the item's position, or puts it at the end of its new category, and
+// an omitted availability keeps the current one.
+func UpdateMenuItem(db *gorm.DB, restaurantID, itemID string, in dto.MenuItemIn) (*dto.MenuItemOut, error) {
+	patch := dto.MenuItemPatchIn{
+		ExternalID:    &in.ExternalID,
+		Category:      &in.Category,
+		Name:          &in.Name,
+		Description:   &in.Description,
+		Price:         &in.Price,
+		Currency:      &in.Currency,
+		DietaryLabels: &in.DietaryLabels,
+		IsAvailable:   in.IsAvailable,
+		IsPopular:     &in.IsPopular,
+		ImageURL:      &in.ImageURL,
+		Calories:      in.Calories,
+		SortOrder:     in.SortOrder,
+
+		Allergens:           &in.Allergens,
+		MayContainAllergens: &in.MayContainAllergens,
+		OptionGroups:        &in.OptionGroups,
+	}
+	return updateMenuItem(db, restaurantID, itemID, patch, true)
+}
+
+// PatchMenuItem changes the given fields of a menu item.
+func PatchMenuItem(db *gorm.DB, restaurantID, itemID string, in dto.MenuItemPatchIn) (*dto.MenuItemOut, error) {
+	return updateMenuItem(db, restaurantID, itemID, in, false)
+}
+
+// SetMenuItemAvailability marks a menu item available or sold out.
+// Unavailable items stay on the menu but drop out of search and
+// recommendations.
+func SetMenuItemAvailability(db *gorm.DB, restaurantID, itemID string, available bool) (*dto.MenuItemOut, error) {
+	return updateMenuItem(db, restaurantID, itemID, dto.MenuItemPatchIn{IsAvailable: &available}, false)
+}
+
+// updateMenuItem applies in to a menu item. With replace, a nil Calories
+// clears the item's calories rather than keeping them.
+func updateMenuItem(db *gorm.DB, restaurantID, itemID string, in dto.MenuItemPatchIn, replace bool) (*dto.MenuItemOut, error) {
+	item, err := ownedMenuItem(db, restaurantID, itemID)
+	if err != nil {
+		return nil, err
+	}
+	var dietary, allergens, mayContain []models.TaxonomyTerm
+	var declared bool
+	var groups []models.MenuOptionGroup
+	setAllergens := in.Allergens != nil || in.MayContainAllergens != nil
+	if in.DietaryLabels != nil || in.OptionGroups != nil || setAllergens {
+		idx, err := loadTaxonomy(db)
+		if err != nil {
+			return nil, err
+		}
+		if in.DietaryLabels != nil {
+			if dietary, err = resolveTerms(idx, models.KindDietary, *in.DietaryLabels); err != nil {
+				return nil, err
+			}
+		}
+		if setAllergens {
+			var contains, traces []string
+			if item.AllergensDeclared {
+				contains, traces = splitCSV(item.Allergens), splitCSV(item.MayContainAllergens)
+			}
+			if in.Allergens != nil {
+				contains = *in.Allergens
+			}
+			if in.MayContainAllergens != nil {
+				traces = *in.MayContainAllergens
+			}
+			if allergens, mayContain, err = resolveAllergens(idx, contains, traces); err != nil {
+				return nil, err
+			}
+			declared = contains != nil || traces != nil
+		}
+		if in.OptionGroups != nil {
+			if groups, err = buildOptionGroups(idx, item.ID, *in.OptionGroups); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	previousCategory := item.Category
+	if in.ExternalID != nil {
+		item.ExternalID = strings.TrimSpace(*in.ExternalID)
+	}
+	if in.Category != nil {
+		item.Category = strings.TrimSpace(*in.Category)
+		if item.Category == "" {
+			item.Category = "Main"
+		}
+	}
+	if in.Name != nil {
+		item.Name = *in.Name
+	}
+	if in.Description != nil {
+		item.Description = *in.Description
+	}
+	if in.Price != nil {
+		item.Price = *in.Price
+	}
+	if in.Currency != nil {
+		item.Currency = *in.Currency
+		if item.Currency == "" {
+			item.Currency = "USD"
+		}
+	}
+	if in.IsAvailable != nil {
+		item.IsAvailable = *in.IsAvailable
+	}
+	if in.IsPopular != nil {
+		item.IsPopular = *in.IsPopular
+	}
+	if in.ImageURL != nil {
+		item.ImageURL = *in.ImageURL
+	}
+	if in.Calories != nil || replace {
+		item.Calories = in.Calories
+	}
+	if err := validateMenuItem(item.Name, item.Price); err != nil {
+		return nil, err
+	}
+	if err := checkExternalID(db, restaurantID, item.ExternalID, item.ID); err != nil {
+		return nil, err
+	}
+	switch {
+	case in.SortOrder != nil:
+		item.SortOrder = *in.SortOrder
+	case item.Category != previousCategory:
+		item.SortOrder = nextSortOrder(db, restaurantID, item.Category)
+	}
+
+	err = db.Transaction(func(tx *gorm.DB) error {
+		if in.DietaryLabels != nil {
+			item.DietaryLabels = models.TermsCSV(dietary)
+			if err := tx.Model(item).Association("DietaryTerms").Replace(dietary); err != nil {
+				return err
+			}
+		}
+		if setAllergens {
+			item.Allergens = models.TermsCSV(allergens)
+			item.MayContainAllergens = models.TermsCSV(mayContain)
+			item.AllergensDeclared = declared
+			if err := tx.Model(item).Association("AllergenTerms").Replace(allergens); err != nil {
+				return err
+			}
+			if err := tx.Model(item).Association("MayContainTerms").Replace(mayContain); err != nil {
+				return err
+			}
+		}
+		if in.OptionGroups != nil {
+			if err := replaceOptionGroups(tx, item.ID, groups); err != nil {
+				return err
+			}
+			item.OptionGroups = groups
+		}
+		return tx.Omit("OptionGroups").Save(item).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	reindex(db, restaurantID)
+
+	out := toMenuItemOut(item)
+	return &out, nil
+}
+
+// DeleteMenuItem removes one item from a restaurant's menu.
+func DeleteMenuItem(db *gorm.DB, restaurantID, itemID string) error {
+	item, err := ownedMenuItem(db, restaurantID, itemID)
+	if err != nil {
+		return err
+	}
+	err = db.Transaction(func(tx *gorm.DB) error {
+		if err := deleteMenuItemLinks(tx, []string{item.ID}); err != nil {
+			return err
+		}
+		return tx.Delete(item).Error
+	})
+	if err != nil {
+		return err
+	}
+	reindex(db, restaurantID)
+	return nil
+}
+
+// ReorderMenu sets the display order of a restaurant's categories, items,
+// or both, and returns the reordered menu. Listed categories replace any
+// earlier category order. Listed items move to the front of their
+// categories in the given order; the others follow in their current order.
+func ReorderMenu(db *gorm.DB, restaurantID string, in dto.MenuOrderIn) (*dto.MenuOut, error) {
+	if len(in.Categories) == 0 && len(in.Items) == 0 {
+		return nil, fmt.Errorf("%w: categories or items is required", ErrInvalidInput)
+	}
+	var r models.Restaurant
+	if err := db.First(&r, "id = ?", restaurantID).Error; err != nil {
+		return nil, err
+	}
+	var items []models.MenuItem
+	if err := db.Where("restaurant_id = ?", restaurantID).
+		Order("category, sort_order, name").Find(&items).Error; err != nil {
+		return nil, err
+	}
+
+	byCategory := make(map[string][]*models.MenuItem)
+	byID := make(map[string]*models.MenuItem, len(items))
+	for i := range items {
+		byCategory[items[i].Category] = append(byCategory[items[i].Category], &items[i])
+		byID[items[i].ID] = &items[i]
+	}
+
+	categories := make([]string, 0, len(in.Categories))
+	seen := make(map[string]bool)
+	for _, name := range in.Categories {
+		name = strings.TrimSpace(name)
+		if _, ok := byCategory[name]; !ok {
+			return nil, fmt.Errorf("%w: no category %q on this menu", ErrInvalidInput, name)
+		}
+		if seen[name] {
+			return nil, fmt.Errorf("%w: category %q is listed twice", ErrInvalidInput, name)
+		}
+		seen[name] = true
+		categories = append(categories, name)
+	}
+	position := make(map[string]int, len(in.Items))
+	for i, id := range in.Items {
+		if byID[id] == nil {
+			return nil, fmt.Errorf("%w: no item %q on this menu", ErrInvalidInput, id)
+		}
+		if _, dup := position[id]; dup {
+			return nil, fmt.Errorf("%w: item %q is listed twice", ErrInvalidInput, id)
+		}
+		position[id] = i
+	}
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if len(categories) > 0 {
+			if err := tx.Where("restaurant_id = ?", restaurantID).Delete(&models.MenuCategory{}).Error; err != nil {
+				return err
+			}
+			rows := make([]models.MenuCategory, len(categories))
+			for i, name := range categories {
+				rows[i] = models.MenuCategory{RestaurantID: restaurantID, Name: name, SortOrder: i + 1}
+			}
+			if err := tx.Create(&rows).Error; err != nil {
+				return err
+			}
+		}
+		for _, group := range byCategory {
+			var listed, rest []*models.MenuItem
+			for _, item := range group {
+				if _, ok := position[item.ID]; ok {
+					listed = append(listed, item)
+				} else {
+					rest = append(rest, item)
+				}
+			}
+			if len(listed) == 0 {
+				continue
+			}
+			sort.SliceStable(listed, func(i, j int) bool { return position[listed[i].ID] < position[listed[j].ID] })
+			for i, item := range append(listed, rest...) {
+				if item.SortOrder == i+1 {
+					continue
+				}
+				if err := tx.Model(item).UpdateColumn("sort_order", i+1).Error; err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return GetMenu(db, restaurantID, nil)
+}
@@ -0,0 +1,365 @@
+package services
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gorm.io/gorm"
+
+	"github.com/agenteats/agenteats/internal/dto"
+	"github.com/agenteats/agenteats/internal/models"
+)
+
+// menuCSVColumns are the columns of an exported menu, in order. Imports
+// accept them in any order.
+var menuCSVColumns = []string{
+	"external_id", "category", "name", "description", "price", "currency",
+	"dietary_labels", "allergens", "may_contain_allergens", "calories", "is_available", "is_popular",
+	"image_url", "sort_order",
+}
+
+// menuCSVAliases maps other common spreadsheet headings to menu columns.
+var menuCSVAliases = map[string]string{
+	"sku":         "external_id",
+	"id":          "external_id",
+	"section":     "category",
+	"item":        "name",
+	"dish":        "name",
+	"labels":      "dietary_labels",
+	"dietary":     "dietary_labels",
+	"contains":    "allergens",
+	"may_contain": "may_contain_allergens",
+	"traces":      "may_contain_allergens",
+	"kcal":        "calories",
+	"available":   "is_available",
+	"popular":     "is_popular",
+	"image":       "image_url",
+	"position":    "sort_order",
+}
+
+// menuCSVHeader maps a CSV header to column positions. Headings are
+// matched ignoring case, spaces and hyphens; unrecognized ones are
+// ignored.
+func menuCSVHeader(header []string) (map[string]int, error) {
+	known := make(map[string]bool, len(menuCSVColumns))
+	for _, c := range menuCSVColumns {
+		known[c] = true
+	}
+	cols := make(map[string]int)
+	for i, h := range header {
+		name := strings.ToLower(strings.TrimSpace(h))
+		name = strings.NewReplacer(" ", "_", "-", "_").Replace(name)
+		if alias, ok := menuCSVAliases[name]; ok {
+			name = alias
+		}
+		if !known[name] {
+			continue
+		}
+		if _, dup := cols[name]; dup {
+			return nil, fmt.Errorf("%w: more than one %s column", ErrInvalidInput, name)
+		}
+		cols[name] = i
+	}
+	for _, required := range []string{"name", "price"} {
+		if _, ok := cols[required]; !ok {
+			return nil, fmt.Errorf("%w: the header row needs a %s column", ErrInvalidInput, required)
+		}
+	}
+	return cols, nil
+}
+
+// parseMenuCSVRow reads one CSV record into a menu item. Empty
+// is_available means available.
+func parseMenuCSVRow(cols map[string]int, record []string) (dto.MenuItemIn, error) {
+	get := func(col string) string {
+		if i, ok := cols[col]; ok && i < len(record) {
+			return strings.TrimSpace(record[i])
+		}
+		return ""
+	}
+	item := dto.MenuItemIn{
+		ExternalID:  get("external_id"),
+		Category:    get("category"),
+		Name:        get("name"),
+		Description: get("description"),
+		Currency:    strings.ToUpper(get("currency")),
+		ImageURL:    get("image_url"),
+	}
+
+	price := strings.TrimLeft(get("price"), "$€£¥ ")
+	if price == "" {
+		return item, errors.New("price is required")
+	}
+	var err error
+	if item.Price, err = strconv.ParseFloat(price, 64); err != nil {
+		return item, fmt.Errorf("price %q is not a number", get("price"))
+	}
+	item.DietaryLabels = splitCSVList(get("dietary_labels"))
+	item.Allergens = parseCSVAllergens(get("allergens"))
+	item.MayContainAllergens = parseCSVAllergens(get("may_contain_allergens"))
+	if v := get("calories"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return item, fmt.Errorf("calories %q is not a whole number", v)
+		}
+		item.Calories = &n
+	}
+	if v := get("sort_order"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return item, fmt.Errorf("sort_order %q is not a whole number", v)
+		}
+		item.SortOrder = &n
+	}
+	if v := get("is_available"); v != "" {
+		available, err := parseCSVBool(v)
+		if err != nil {
+			return item, fmt.Errorf("is_available: %v", err)
+		}
+		item.IsAvailable = &available
+	}
+	if v := get("is_popular"); v != "" {
+		if item.IsPopular, err = parseCSVBool(v); err != nil {
+			return item, fmt.Errorf("is_popular: %v", err)
+		}
+	}
+	return item, nil
+}
+
+// splitCSVList splits a cell listing several values, separated by
+// semicolons, commas or bars.
+func splitCSVList(v string) []string {
+	return strings.FieldsFunc(v, func(r rune) bool { return r == ';' || r == ',' || r == '|' })
+}
+
+// noAllergens is the allergens cell of a dish declared free of them. An
+// empty cell means the dish's allergens are unknown.
+const noAllergens = "none"
+
+// parseCSVAllergens reads an allergen cell: nil when it's empty, and an
+// empty list for "none".
+func parseCSVAllergens(v string) []string {
+	switch {
+	case v == "":
+		return nil
+	case strings.EqualFold(v, noAllergens):
+		return []string{}
+	}
+	return splitCSVList(v)
+}
+
+// parseCSVBool reads the ways spreadsheets write yes and no.
+func parseCSVBool(v string) (bool, error) {
+	switch strings.ToLower(v) {
+	case "true", "yes", "y", "1", "x":
+		return true, nil
+	case "false", "no", "n", "0":
+		return false, nil
+	}
+	return false, fmt.Errorf("%q is not yes or no", v)
+}
+
+// ImportMenuCSV imports a menu from CSV with a header row, like
+// BulkImportMenu with in's strategy and options. Rows are reported by
+// their line in the file. A row that can't be read is an error like any
+// other, so nothing is imported. On merge, items keep the fields the file
+// has no column for.
+//
+// The strategy defaults to merge, since CSV can't hold option groups and
+// replacing would drop them. For the same reason replace is refused while
+// any item on the menu has option groups.
+func ImportMenuCSV(db *gorm.DB, restaurantID string, r io.Reader, in dto.BulkMenuImportIn) (*dto.BulkMenuImportOut, error) {
+	if in.Strategy == "" {
+		in.Strategy = "merge"
+	}
+	if in.Strategy == "replace" {
+		var n int64
+		if err := db.Model(&models.MenuOptionGroup{}).
+			Joins("JOIN menu_items ON menu_items.id = menu_option_groups.menu_item_id").
+			Where("menu_items.restaurant_id = ?", restaurantID).
+			Distinct("menu_option_groups.menu_item_id").Count(&n).Error; err != nil {
+			return nil, err
+		}
+		if n > 0 {
+			return nil, fmt.Errorf("%w: %d items on the menu have option groups, which CSV can't hold, so a CSV replace would delete them; use merge, or replace with JSON", ErrInvalidInput, n)
+		}
+	}
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+	header, err := reader.Read()
+	if err == io.EOF {
+		return nil, fmt.Errorf("%w: the CSV file is empty", ErrInvalidInput)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidInput, err)
+	}
+	if len(header) > 0 {
+		header[0] = strings.TrimPrefix(header[0], "\ufeff") // byte order mark from Excel
+	}
+	cols, err := menuCSVHeader(header)
+	if err != nil {
+		return nil, err
+	}
+
+	var lines []int
+	var bad []dto.MenuImportRowOut
+	in.Items = nil
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			var parseErr *csv.ParseError
+			if errors.As(err, &parseErr) {
+				bad = append(bad, dto.MenuImportRowOut{Row: parseErr.StartLine, Action: importError, Error: parseErr.Err.Error()})
+				continue
+			}
+			return nil, err
+		}
+		line, _ := reader.FieldPos(0)
+		if strings.TrimSpace(strings.Join(record, "")) == "" {
+			continue
+		}
+		item, err := parseMenuCSVRow(cols, record)
+		if err != nil {
+			bad = append(bad, dto.MenuImportRowOut{
+				Row: line, Action: importError, ExternalID: item.ExternalID,
+				Category: item.Category, Name: item.Name, Error: err.Error(),
+			})
+			continue
+		}
+		in.Items = append(in.Items, item)
+		lines = append(lines, line)
+	}
+	if len(in.Items) == 0 && len(bad) == 0 {
+		return nil, fmt.Errorf("%w: the CSV file has no menu items", ErrInvalidInput)
+	}
+
+	dryRun := in.DryRun
+	if len(bad) > 0 {
+		// Check the readable rows too, so every problem is reported at once.
+		in.DryRun = true
+	}
+	// CSV has no way to write option groups, so merges keep them.
+	src := importSource{rows: lines, omitted: map[string]bool{"option_groups": true}}
+	for _, col := range menuCSVColumns {
+		if _, ok := cols[col]; !ok {
+			src.omitted[col] = true
+		}
+	}
+	out, err := bulkImportMenu(db, restaurantID, in, src)
+	if err != nil {
+		return nil, err
+	}
+	if len(bad) > 0 {
+		out.DryRun = dryRun
+		out.Errors += len(bad)
+		out.Rows = append(out.Rows, bad...)
+		sort.SliceStable(out.Rows, func(i, j int) bool {
+			// Removed items, which have no row, stay last.
+			a, b := out.Rows[i].Row, out.Rows[j].Row
+			return a != 0 && (b == 0 || a < b)
+		})
+	}
+	return out, nil
+}
+
+// ExportMenu returns a restaurant's menu in display order, in the form
+// BulkImportMenu takes. Importing it unchanged with "merge" leaves the menu
+// as it is.
+func ExportMenu(db *gorm.DB, restaurantID string) (*dto.BulkMenuImportIn, error) {
+	menu, err := GetMenu(db, restaurantID, nil)
+	if err != nil {
+		return nil, err
+	}
+	out := &dto.BulkMenuImportIn{Strategy: "merge", Items: []dto.MenuItemIn{}}
+	for _, category := range menu.CategoryOrder {
+		for _, m := range menu.Categories[category] {
+			sortOrder := m.SortOrder
+			available := m.IsAvailable
+			out.Items = append(out.Items, dto.MenuItemIn{
+				ExternalID:          m.ExternalID,
+				Category:            m.Category,
+				Name:                m.Name,
+				Description:         m.Description,
+				Price:               m.Price,
+				Currency:            m.Currency,
+				DietaryLabels:       m.DietaryLabels,
+				Allergens:           m.Allergens,
+				MayContainAllergens: m.MayContainAllergens,
+				IsAvailable:         &available,
+				IsPopular:           m.IsPopular,
+				ImageURL:            m.ImageURL,
+				Calories:            m.Calories,
+				SortOrder:           &sortOrder,
+				OptionGroups:        toOptionGroupsIn(m.OptionGroups),
+			})
+		}
+	}
+	return out, nil
+}
+
+// toOptionGroupsIn turns a menu item's option groups back into import
+// input.
+func toOptionGroupsIn(groups []dto.MenuOptionGroupOut) []dto.MenuOptionGroupIn {
+	var out []dto.MenuOptionGroupIn
+	for _, g := range groups {
+		options := make([]dto.MenuOptionIn, len(g.Options))
+		for i, o := range g.Options {
+			options[i] = dto.MenuOptionIn{Name: o.Name, PriceDelta: o.PriceDelta, DietaryLabels: o.DietaryLabels, Allergens: o.Allergens}
+		}
+		out = append(out, dto.MenuOptionGroupIn{
+			Name:          g.Name,
+			Required:      g.Required,
+			MinSelections: g.MinSelections,
+			MaxSelections: g.MaxSelections,
+			Options:       options,
+		})
+	}
+	return out
+}
+
+// WriteMenuCSV writes menu items as CSV with a header row, in the columns
+// ImportMenuCSV reads. Option groups are left out.
+func WriteMenuCSV(w io.Writer, items []dto.MenuItemIn) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(menuCSVColumns); err != nil {
+		return err
+	}
+	for _, m := range items {
+		calories, sortOrder, available := "", "", ""
+		if m.Calories != nil {
+			calories = strconv.Itoa(*m.Calories)
+		}
+		if m.SortOrder != nil {
+			sortOrder = strconv.Itoa(*m.SortOrder)
+		}
+		if m.IsAvailable != nil {
+			available = strconv.FormatBool(*m.IsAvailable)
+		}
+		allergens := strings.Join(m.Allergens, ";")
+		if allergens == "" && (m.Allergens != nil || m.MayContainAllergens != nil) {
+			allergens = noAllergens
+		}
+		record := []string{
+			m.ExternalID, m.Category, m.Name, m.Description,
+			strconv.FormatFloat(m.Price, 'f', -1, 64), m.Currency,
+			strings.Join(m.DietaryLabels, ";"), allergens,
+			strings.Join(m.MayContainAllergens, ";"), calories,
+			available, strconv.FormatBool(m.IsPopular),
+			m.ImageURL, sortOrder,
+		}
+		if err := cw.Write(record); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
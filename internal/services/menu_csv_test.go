@@ -0,0 +1,114 @@
+package services
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/agenteats/agenteats/internal/dto"
+	"github.com/agenteats/agenteats/internal/models"
+)
+
+func TestImportMenuCSVCreatesUnavailableItem(t *testing.T) {
+	db := openTestDB(t)
+	r := createTestRestaurant(t, db, dto.RestaurantIn{})
+	csv := "name,price,is_available\nSoup,5,no\nBread,3,yes\n"
+	out, err := ImportMenuCSV(db, r.ID, strings.NewReader(csv), dto.BulkMenuImportIn{Strategy: "merge"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out.Errors > 0 || out.Created != 2 {
+		t.Fatalf("import: %d created, %d errors: %+v", out.Created, out.Errors, out.Rows)
+	}
+
+	for name, want := range map[string]bool{"Soup": false, "Bread": true} {
+		var item models.MenuItem
+		if err := db.First(&item, "restaurant_id = ? AND name = ?", r.ID, name).Error; err != nil {
+			t.Fatal(err)
+		}
+		if item.IsAvailable != want {
+			t.Errorf("%s: is_available = %v, want %v", name, item.IsAvailable, want)
+		}
+	}
+}
+
+func TestMenuCSVAllergenDeclarations(t *testing.T) {
+	db := openTestDB(t)
+	r := createTestRestaurant(t, db, dto.RestaurantIn{})
+	csv := "name,price,allergens,may_contain_allergens\nSalad,8,none,\nCurry,12,,\nToast,4,,wheat\n"
+	if out, err := ImportMenuCSV(db, r.ID, strings.NewReader(csv), dto.BulkMenuImportIn{}); err != nil || out.Errors > 0 {
+		t.Fatalf("import: %v %+v", err, out)
+	}
+
+	export, err := ExportMenu(db, r.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var b strings.Builder
+	if err := WriteMenuCSV(&b, export.Items); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{",Salad,,8,USD,,none,,", ",Curry,,12,USD,,,,", ",Toast,,4,USD,,none,wheat,"} {
+		if !strings.Contains(b.String(), want) {
+			t.Errorf("exported CSV has no %q:\n%s", want, b.String())
+		}
+	}
+
+	// Re-importing the export keeps each item's declaration.
+	out, err := ImportMenuCSV(db, r.ID, strings.NewReader(b.String()), dto.BulkMenuImportIn{Strategy: "merge"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out.Unchanged != 3 {
+		t.Errorf("re-import: %d unchanged, want 3: %+v", out.Unchanged, out.Rows)
+	}
+	for name, want := range map[string]bool{"Salad": true, "Curry": false, "Toast": true} {
+		var item models.MenuItem
+		if err := db.First(&item, "restaurant_id = ? AND name = ?", r.ID, name).Error; err != nil {
+			t.Fatal(err)
+		}
+		if item.AllergensDeclared != want {
+			t.Errorf("%s: allergens_declared = %v, want %v", name, item.AllergensDeclared, want)
+		}
+	}
+}
+
+func TestImportMenuCSVKeepsOptionGroups(t *testing.T) {
+	db := openTestDB(t)
+	r := createTestRestaurant(t, db, dto.RestaurantIn{})
+	pizza, err := AddMenuItem(db, r.ID, dto.MenuItemIn{Category: "Pizza", Name: "Margherita", Price: 14,
+		OptionGroups: []dto.MenuOptionGroupIn{{Name: "Size", Required: true, Options: []dto.MenuOptionIn{
+			{Name: "10 inch"}, {Name: "14 inch", PriceDelta: 5},
+		}}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	export, err := ExportMenu(db, r.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var b strings.Builder
+	if err := WriteMenuCSV(&b, export.Items); err != nil {
+		t.Fatal(err)
+	}
+	edited := strings.Replace(b.String(), ",14,", ",15,", 1)
+
+	if _, err := ImportMenuCSV(db, r.ID, strings.NewReader(edited), dto.BulkMenuImportIn{Strategy: "replace"}); !errors.Is(err, ErrInvalidInput) {
+		t.Errorf("replace with option groups: err = %v, want ErrInvalidInput", err)
+	}
+	out, err := ImportMenuCSV(db, r.ID, strings.NewReader(edited), dto.BulkMenuImportIn{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out.Strategy != "merge" || out.Updated != 1 || out.Removed != 0 {
+		t.Fatalf("default import: strategy %s, %d updated, %d removed", out.Strategy, out.Updated, out.Removed)
+	}
+	item, err := ownedMenuItem(db, r.ID, pizza.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if item.Price != 15 || len(item.OptionGroups) != 1 || len(item.OptionGroups[0].Options) != 2 {
+		t.Errorf("after import: price %v with %d option groups, want 15 with the size group", item.Price, len(item.OptionGroups))
+	}
+}
@@ -0,0 +1,89 @@
+package services
+
+import (
+	"slices"
+	"sort"
+	"testing"
+
+	"github.com/agenteats/agenteats/internal/dto"
+)
+
+func TestExcludeAllergens(t *testing.T) {
+	db := openTestDB(t)
+	r := createTestRestaurant(t, db, dto.RestaurantIn{})
+	extras := []dto.MenuOptionGroupIn{{Name: "Extras", Options: []dto.MenuOptionIn{
+		{Name: "Crushed peanuts", Allergens: []string{"peanuts"}},
+		{Name: "Lime"},
+	}}}
+	sizes := []dto.MenuOptionGroupIn{{Name: "Size", Required: true, Options: []dto.MenuOptionIn{
+		{Name: "Small"}, {Name: "Large", PriceDelta: 3},
+	}}}
+	for _, in := range []dto.MenuItemIn{
+		{Name: "Satay", Price: 9, Allergens: []string{"peanuts"}},
+		{Name: "Fried Rice", Price: 9, Allergens: []string{"eggs"}, MayContainAllergens: []string{"peanut"}},
+		{Name: "Undeclared Curry", Price: 12},
+		{Name: "Pad Thai", Price: 14, Allergens: []string{"eggs"}, OptionGroups: extras},
+		{Name: "Green Salad", Price: 8, Allergens: []string{}},
+		{Name: "Noodle Soup", Price: 11, MayContainAllergens: []string{"sesame"}, OptionGroups: sizes},
+	} {
+		if _, err := AddMenuItem(db, r.ID, in); err != nil {
+			t.Fatalf("%s: %v", in.Name, err)
+		}
+	}
+
+	menu, err := GetMenu(db, r.ID, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, item := range menu.Categories["Main"] {
+		switch item.Name {
+		case "Undeclared Curry":
+			if item.AllergensDeclared || item.Allergens != nil || item.MayContainAllergens != nil {
+				t.Errorf("%s: declared %v with %v / %v, want undeclared with null lists",
+					item.Name, item.AllergensDeclared, item.Allergens, item.MayContainAllergens)
+			}
+		case "Green Salad":
+			if !item.AllergensDeclared || item.Allergens == nil || len(item.Allergens) != 0 {
+				t.Errorf("%s: declared %v with %v, want declared with an empty list", item.Name, item.AllergensDeclared, item.Allergens)
+			}
+		}
+	}
+
+	names := func(items []dto.MenuItemOut) []string {
+		var out []string
+		for _, item := range items {
+			out = append(out, item.Name)
+		}
+		sort.Strings(out)
+		return out
+	}
+	tests := []struct {
+		exclude []string
+		want    []string
+	}{
+		{[]string{"peanuts"}, []string{"Green Salad", "Noodle Soup"}},
+		{[]string{"sesame"}, []string{"Fried Rice", "Green Salad", "Pad Thai", "Satay"}},
+		{[]string{"milk"}, []string{"Fried Rice", "Green Salad", "Noodle Soup", "Pad Thai", "Satay"}},
+	}
+	for _, tt := range tests {
+		menu, err := GetMenu(db, r.ID, tt.exclude)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := names(menu.Categories["Main"]); !slices.Equal(got, tt.want) {
+			t.Errorf("GetMenu excluding %v = %v, want %v", tt.exclude, got, tt.want)
+		}
+
+		page, err := SearchDishes(db, dto.DishQuery{ExcludeAllergens: tt.exclude})
+		if err != nil {
+			t.Fatal(err)
+		}
+		var found []dto.MenuItemOut
+		for _, d := range page.Items {
+			found = append(found, d.Item)
+		}
+		if got := names(found); !slices.Equal(got, tt.want) {
+			t.Errorf("SearchDishes excluding %v = %v, want %v", tt.exclude, got, tt.want)
+		}
+	}
+}
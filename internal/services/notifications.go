@@ -0,0 +1,284 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+	"unicode/utf8"
+
+	"gorm.io/gorm"
+
+	"github.com/agenteats/agenteats/internal/dto"
+	"github.com/agenteats/agenteats/internal/models"
+	"github.com/agenteats/agenteats/internal/notify"
+)
+
+// Guest notification template limits, in characters.
+const (
+	maxTemplateSubject = 500
+	maxTemplateBody    = 10000
+)
+
+// guestMessageData fills the template data for a message about res.
+func guestMessageData(r *models.Restaurant, res *models.Reservation) notify.TemplateData {
+	return notify.TemplateData{
+		GuestName:         res.CustomerName,
+		RestaurantName:    r.Name,
+		RestaurantAddress: restaurantAddress(r),
+		RestaurantPhone:   r.Phone,
+		ReservationID:     res.ID,
+		Date:              res.Date,
+		DisplayDate:       notify.DisplayDate(res.Date),
+		Time:              res.Time,
+		PartySize:         res.PartySize,
+		SpecialRequests:   res.SpecialRequests,
+	}
+}
+
+// restaurantAddress joins a restaurant's street address and city.
+func restaurantAddress(r *models.Restaurant) string {
+	if r.City == "" {
+		return r.Address
+	}
+	return r.Address + ", " + r.City
+}
+
+// restaurantTemplate returns the restaurant's template for kind, or the
+// default, and whether it is customized.
+func restaurantTemplate(db *gorm.DB, restaurantID string, kind models.NotificationKind) (notify.Template, bool, error) {
+	var t models.NotificationTemplate
+	err := db.Where("restaurant_id = ? AND kind = ?", restaurantID, kind).First(&t).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return notify.Defaults[kind], false, nil
+	}
+	if err != nil {
+		return notify.Template{}, false, err
+	}
+	return notify.Template{Subject: t.Subject, Body: t.Body}, true, nil
+}
+
+// enqueueNotification renders a kind message for res and queues it for the
+// guest in the caller's transaction. Guests without an email address
+// aren't notified. If the restaurant's own template fails to render, the
+// default is sent instead.
+func enqueueNotification(tx *gorm.DB, res *models.Reservation, kind models.NotificationKind, data notify.TemplateData) error {
+	if res.CustomerEmail == "" {
+		return nil
+	}
+	tmpl, custom, err := restaurantTemplate(tx, res.RestaurantID, kind)
+	if err != nil {
+		return err
+	}
+	msg, err := notify.Render(tmpl, data)
+	if err != nil && custom {
+		log.Printf("notify: %s template for restaurant %s: %v; using the default", kind, res.RestaurantID, err)
+		msg, err = notify.Render(notify.Defaults[kind], data)
+	}
+	if err != nil {
+		return err
+	}
+	return tx.Create(&models.Notification{
+		ID:            models.NewID(),
+		RestaurantID:  res.RestaurantID,
+		ReservationID: res.ID,
+		Kind:          kind,
+		Recipient:     res.CustomerEmail,
+		Subject:       msg.Subject,
+		Body:          msg.Body,
+		Status:        models.NotificationPending,
+		NextAttemptAt: time.Now().UTC(),
+	}).Error
+}
+
+// QueueReminders queues day-before reminders for confirmed reservations
+// once it is fromHour or later on the day before their date, at their
+// restaurant. Reservations whose reminder time passed before they were
+// booked, or while reminders weren't running, are caught up as long as the
+// visit is still ahead. Each reservation is reminded once; changing its
+// date or time re-arms the reminder. It returns how many reminders it
+// queued.
+func QueueReminders(db *gorm.DB, now time.Time, fromHour int) (int, error) {
+	// Today and tomorrow somewhere on Earth fall between yesterday and the
+	// day after tomorrow in UTC.
+	utc := now.UTC()
+	var candidates []models.Reservation
+	if err := db.Where("status = ? AND reminder_sent_at IS NULL AND customer_email <> '' AND date BETWEEN ? AND ?",
+		models.StatusConfirmed, utc.AddDate(0, 0, -1).Format("2006-01-02"), utc.AddDate(0, 0, 2).Format("2006-01-02")).
+		Find(&candidates).Error; err != nil {
+		return 0, err
+	}
+	if len(candidates) == 0 {
+		return 0, nil
+	}
+
+	ids := make([]string, 0, len(candidates))
+	for _, res := range candidates {
+		ids = append(ids, res.RestaurantID)
+	}
+	var restaurants []models.Restaurant
+	if err := db.Where("id IN ?", ids).Find(&restaurants).Error; err != nil {
+		return 0, err
+	}
+	byID := make(map[string]*models.Restaurant, len(restaurants))
+	for i := range restaurants {
+		byID[restaurants[i].ID] = &restaurants[i]
+	}
+
+	queued := 0
+	for i := range candidates {
+		res := &candidates[i]
+		r := byID[res.RestaurantID]
+		if r == nil {
+			continue
+		}
+		visit, err := time.ParseInLocation("2006-01-02 15:04", res.Date+" "+res.Time, restaurantLocation(r))
+		if err != nil {
+			continue
+		}
+		remindAt := time.Date(visit.Year(), visit.Month(), visit.Day()-1, fromHour, 0, 0, 0, visit.Location())
+		if now.Before(remindAt) || !now.Before(visit) {
+			continue
+		}
+		sent := false
+		err = db.Transaction(func(tx *gorm.DB) error {
+			result := tx.Model(&models.Reservation{}).
+				Where("id = ? AND status = ? AND reminder_sent_at IS NULL", res.ID, models.StatusConfirmed).
+				UpdateColumn("reminder_sent_at", utc)
+			if result.Error != nil || result.RowsAffected == 0 {
+				return result.Error
+			}
+			if err := enqueueNotification(tx, res, models.NotifyReminder, guestMessageData(r, res)); err != nil {
+				return err
+			}
+			sent = true
+			return nil
+		})
+		if err != nil {
+			return queued, err
+		}
+		if sent {
+			queued++
+		}
+	}
+	return queued, nil
+}
+
+// ScheduleReminders runs QueueReminders every interval until ctx is done.
+func ScheduleReminders(ctx context.Context, db *gorm.DB, fromHour int, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if _, err := QueueReminders(db.WithContext(ctx), time.Now(), fromHour); err != nil && ctx.Err() == nil {
+			log.Printf("notify: queueing reminders: %v", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// templateOut describes a restaurant's template for kind, previewed with
+// sample guest details and the restaurant's own name, address and phone.
+func templateOut(r *models.Restaurant, kind models.NotificationKind, t notify.Template, custom bool) dto.NotificationTemplateOut {
+	out := dto.NotificationTemplateOut{
+		Kind:    string(kind),
+		Subject: t.Subject,
+		Body:    t.Body,
+		Custom:  custom,
+	}
+	data := notify.SampleData(kind)
+	data.RestaurantName = r.Name
+	data.RestaurantAddress = restaurantAddress(r)
+	data.RestaurantPhone = r.Phone
+	if msg, err := notify.Render(t, data); err == nil {
+		out.Preview = dto.NotificationPreviewOut{Subject: msg.Subject, Body: msg.Body}
+	}
+	return out
+}
+
+// ListNotificationTemplates returns the template the restaurant uses for
+// each kind of guest message.
+func ListNotificationTemplates(db *gorm.DB, restaurantID string) ([]dto.NotificationTemplateOut, error) {
+	var r models.Restaurant
+	if err := db.First(&r, "id = ?", restaurantID).Error; err != nil {
+		return nil, err
+	}
+	out := make([]dto.NotificationTemplateOut, 0, len(models.NotificationKinds))
+	for _, kind := range models.NotificationKinds {
+		t, custom, err := restaurantTemplate(db, restaurantID, kind)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, templateOut(&r, kind, t, custom))
+	}
+	return out, nil
+}
+
+// parseKind validates a notification kind from a URL.
+func parseKind(kind string) (models.NotificationKind, error) {
+	k := models.NotificationKind(kind)
+	if !k.Valid() {
+		return "", fmt.Errorf("%w: unknown message kind %q (use confirmation, modification, cancellation, reminder or manage_token)", ErrInvalidInput, kind)
+	}
+	return k, nil
+}
+
+// SetNotificationTemplate replaces the restaurant's template for one kind
+// of guest message. The template must render with sample data.
+func SetNotificationTemplate(db *gorm.DB, restaurantID, kind string, in dto.NotificationTemplateIn) (*dto.NotificationTemplateOut, error) {
+	k, err := parseKind(kind)
+	if err != nil {
+		return nil, err
+	}
+	if utf8.RuneCountInString(in.Subject) > maxTemplateSubject {
+		return nil, fmt.Errorf("%w: subject must be at most %d characters", ErrInvalidInput, maxTemplateSubject)
+	}
+	if in.Body == "" || utf8.RuneCountInString(in.Body) > maxTemplateBody {
+		return nil, fmt.Errorf("%w: body is required and must be at most %d characters", ErrInvalidInput, maxTemplateBody)
+	}
+	tmpl := notify.Template{Subject: in.Subject, Body: in.Body}
+	if _, err := notify.Validate(k, tmpl); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidInput, err)
+	}
+
+	var r models.Restaurant
+	if err := db.First(&r, "id = ?", restaurantID).Error; err != nil {
+		return nil, err
+	}
+	var t models.NotificationTemplate
+	err = db.Where("restaurant_id = ? AND kind = ?", restaurantID, k).First(&t).Error
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		t = models.NotificationTemplate{RestaurantID: restaurantID, Kind: k}
+	case err != nil:
+		return nil, err
+	}
+	t.Subject = in.Subject
+	t.Body = in.Body
+	if err := db.Save(&t).Error; err != nil {
+		return nil, err
+	}
+	out := templateOut(&r, k, tmpl, true)
+	return &out, nil
+}
+
+// DeleteNotificationTemplate goes back to the default for one kind of
+// guest message.
+func DeleteNotificationTemplate(db *gorm.DB, restaurantID, kind string) error {
+	k, err := parseKind(kind)
+	if err != nil {
+		return err
+	}
+	result := db.Where("restaurant_id = ? AND kind = ?", restaurantID, k).Delete(&models.NotificationTemplate{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
@@ -0,0 +1,60 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/agenteats/agenteats/internal/dto"
+	"github.com/agenteats/agenteats/internal/models"
+)
+
+func TestQueueReminders(t *testing.T) {
+	db := openTestDB(t)
+	r := createTestRestaurant(t, db, dto.RestaurantIn{})
+	now := time.Now().UTC()
+	earlier := now.AddDate(0, 0, -2)
+
+	book := func(name, email, date string, created time.Time) string {
+		res, err := MakeReservation(db, r.ID, dto.ReservationIn{CustomerName: name, CustomerEmail: email, PartySize: 2, Date: date, Time: "19:00"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		db.Model(&models.Reservation{}).Where("id = ?", res.ID).UpdateColumn("created_at", created)
+		return res.ID
+	}
+	tomorrow := book("Tomorrow", "tomorrow@example.com", daysFromNow(1), earlier)
+	book("No email", "", daysFromNow(1), earlier)
+	book("Day after", "later@example.com", daysFromNow(2), earlier)
+	// Booked after its reminder time, so it is caught up.
+	late := book("Booked late", "late@example.com", daysFromNow(1), now)
+	cancelled := book("Cancelled", "cancelled@example.com", daysFromNow(1), earlier)
+	db.Model(&models.Reservation{}).Where("id = ?", cancelled).Update("status", models.StatusCancelled)
+	reminded := book("Reminded", "reminded@example.com", daysFromNow(1), earlier)
+	db.Model(&models.Reservation{}).Where("id = ?", reminded).UpdateColumn("reminder_sent_at", now)
+	db.Where("1 = 1").Delete(&models.Notification{})
+
+	// Before the reminder hour nothing goes out.
+	if n, err := QueueReminders(db, now, 24); err != nil || n != 0 {
+		t.Fatalf("before the reminder hour: queued %d, %v; want 0", n, err)
+	}
+
+	n, err := QueueReminders(db, now, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var notes []models.Notification
+	db.Order("recipient").Find(&notes)
+	if n != 2 || len(notes) != 2 {
+		t.Fatalf("queued %d: %+v; want reminders for the two bookings tomorrow", n, notes)
+	}
+	for i, want := range []string{late, tomorrow} {
+		if notes[i].ReservationID != want || notes[i].Kind != models.NotifyReminder {
+			t.Errorf("notification %d is a %s for %s, want a reminder for %s", i, notes[i].Kind, notes[i].ReservationID, want)
+		}
+	}
+
+	// Each reservation is reminded once.
+	if n, err := QueueReminders(db, now, 0); err != nil || n != 0 {
+		t.Fatalf("second run: queued %d, %v; want 0", n, err)
+	}
+}
@@ -0,0 +1,58 @@
+package services
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+)
+
+// cursor marks where the next page of a list starts. Lists ordered in SQL
+// by a stable key resume after the last row's key, so rows added or
+// removed earlier in the list don't shift later pages; lists ranked in Go
+// resume at an offset. Clients only see it as an opaque string.
+type cursor struct {
+	Offset int      `json:"o,omitempty"`
+	Rating *float64 `json:"r,omitempty"`
+	ID     string   `json:"id,omitempty"`
+}
+
+func encodeCursor(c cursor) string {
+	b, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// decodeCursor parses a cursor from a previous page. An empty string is the
+// first page.
+func decodeCursor(s string) (cursor, error) {
+	var c cursor
+	if s == "" {
+		return c, nil
+	}
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil || json.Unmarshal(b, &c) != nil || c.Offset < 0 {
+		return cursor{}, fmt.Errorf("%w: invalid cursor", ErrInvalidInput)
+	}
+	return c, nil
+}
+
+// startOffset returns where an offset-paged list resumes: the cursor's
+// offset, or the legacy offset parameter on the first page.
+func startOffset(cursorParam string, offset int) (int, error) {
+	if cursorParam == "" {
+		return offset, nil
+	}
+	c, err := decodeCursor(cursorParam)
+	if err != nil {
+		return 0, err
+	}
+	return c.Offset, nil
+}
+
+// nextOffsetCursor returns the cursor for the page after one that started
+// at offset and held n items out of total, or "" on the last page.
+func nextOffsetCursor(offset, n int, total int64) string {
+	if int64(offset+n) >= total || n == 0 {
+		return ""
+	}
+	return encodeCursor(cursor{Offset: offset + n})
+}
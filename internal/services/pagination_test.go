@@ -0,0 +1,149 @@
+package services
+
+import (
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/agenteats/agenteats/internal/dto"
+	"github.com/agenteats/agenteats/internal/models"
+)
+
+func TestListRestaurantsCursorIsStable(t *testing.T) {
+	db := openTestDB(t)
+	rate := func(name string, rating *float64) string {
+		r := createTestRestaurant(t, db, dto.RestaurantIn{Name: name})
+		db.Model(&models.Restaurant{}).Where("id = ?", r.ID).Update("rating", rating)
+		return r.ID
+	}
+	stars := func(v float64) *float64 { return &v }
+	rate("A", stars(4.8))
+	rate("B", stars(4.5))
+	rate("C", stars(4.5))
+	rate("D", stars(4.0))
+	rate("E", nil)
+	rate("F", nil)
+
+	names := func(p *dto.Page[dto.RestaurantSummary]) []string {
+		var out []string
+		for _, s := range p.Items {
+			out = append(out, s.Name)
+		}
+		return out
+	}
+	all, err := ListRestaurants(db, dto.RestaurantQuery{Limit: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	order := names(all)
+	if len(order) != 6 || order[0] != "A" || order[3] != "D" {
+		t.Fatalf("order = %v, want rating first, unrated last", order)
+	}
+
+	var seen []string
+	page, err := ListRestaurants(db, dto.RestaurantQuery{Limit: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	seen = append(seen, names(page)...)
+
+	// A new top-rated restaurant and a removed one from the first page
+	// don't shift the pages that follow.
+	rate("New", stars(5))
+	db.Model(&models.Restaurant{}).Where("name = ?", order[0]).Update("is_active", false)
+
+	for page.NextCursor != "" {
+		page, err = ListRestaurants(db, dto.RestaurantQuery{Limit: 2, Cursor: page.NextCursor})
+		if err != nil {
+			t.Fatal(err)
+		}
+		seen = append(seen, names(page)...)
+	}
+	if !slices.Equal(seen, order) {
+		t.Fatalf("paged through %v, want %v", seen, order)
+	}
+}
+
+func TestListRestaurantsOpenNowPaging(t *testing.T) {
+	db := openTestDB(t)
+	oldScan, oldBatch := openNowScan, openNowBatch
+	t.Cleanup(func() { openNowScan, openNowBatch = oldScan, oldBatch })
+	openNowScan, openNowBatch = 3, 2
+
+	var allDay, closed []dto.OperatingHoursIn
+	for _, day := range []string{"monday", "tuesday", "wednesday", "thursday", "friday", "saturday", "sunday"} {
+		allDay = append(allDay, dto.OperatingHoursIn{Day: day, OpenTime: "00:00", CloseTime: "00:00"})
+		closed = append(closed, dto.OperatingHoursIn{Day: day, IsClosed: true})
+	}
+	var want []string
+	for i, name := range []string{"A", "B", "C", "D", "E", "F", "G", "H"} {
+		hours := closed
+		if i%3 == 0 {
+			hours = allDay
+			want = append(want, name)
+		}
+		r := createTestRestaurant(t, db, dto.RestaurantIn{Name: name, Hours: hours})
+		db.Model(&models.Restaurant{}).Where("id = ?", r.ID).Update("rating", 5-float64(i)/10)
+	}
+
+	var seen []string
+	q := dto.RestaurantQuery{OpenNow: true, Limit: 2}
+	for pages := 0; ; pages++ {
+		if pages > 10 {
+			t.Fatal("paging never ends")
+		}
+		page, err := ListRestaurants(db, q)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, s := range page.Items {
+			seen = append(seen, s.Name)
+		}
+		if page.NextCursor == "" {
+			break
+		}
+		q.Cursor = page.NextCursor
+	}
+	if !slices.Equal(seen, want) {
+		t.Fatalf("paged through %v, want %v", seen, want)
+	}
+}
+
+func TestListRestaurantsNearestFirst(t *testing.T) {
+	db := openTestDB(t)
+	at := func(name string, lat, lng float64) {
+		createTestRestaurant(t, db, dto.RestaurantIn{Name: name, Latitude: &lat, Longitude: &lng})
+	}
+	at("Far", 42.3700, -71.0500)
+	at("Near", 42.3605, -71.0590)
+	at("Middle", 42.3650, -71.0700)
+	at("Out of range", 42.5000, -71.0000)
+
+	lat, lng := 42.3601, -71.0589
+	page, err := ListRestaurants(db, dto.RestaurantQuery{Latitude: &lat, Longitude: &lng, RadiusKm: 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, s := range page.Items {
+		got = append(got, s.Name)
+	}
+	if want := []string{"Near", "Middle", "Far"}; !slices.Equal(got, want) {
+		t.Fatalf("nearest first = %v, want %v", got, want)
+	}
+}
+
+func TestDecodeCursor(t *testing.T) {
+	rating := 4.5
+	for _, c := range []cursor{{}, {Offset: 40}, {Rating: &rating, ID: "abc"}, {ID: "abc"}} {
+		got, err := decodeCursor(encodeCursor(c))
+		if err != nil || got.Offset != c.Offset || got.ID != c.ID || (got.Rating == nil) != (c.Rating == nil) {
+			t.Errorf("round trip of %+v = %+v, %v", c, got, err)
+		}
+	}
+	for _, s := range []string{"not base64!", "bm90IGpzb24", encodeCursor(cursor{Offset: -1})} {
+		if _, err := decodeCursor(s); !errors.Is(err, ErrInvalidInput) {
+			t.Errorf("decodeCursor(%q) = %v, want ErrInvalidInput", s, err)
+		}
+	}
+}
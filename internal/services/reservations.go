@@ -0,0 +1,302 @@
+package services
+
+import (
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"net/mail"
+	"strings"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/agenteats/agenteats/internal/dto"
+	"github.com/agenteats/agenteats/internal/models"
+)
+
+// Reservation validation errors. Handlers map these to HTTP status codes and
+// MCP error codes; they are usually wrapped with a more specific message.
+var (
+	ErrPartyTooLarge      = errors.New("party too large")
+	ErrRestaurantClosed   = errors.New("restaurant is closed")
+	ErrRestaurantInactive = errors.New("restaurant is not accepting reservations")
+)
+
+// ErrNotAuthorized is returned when a caller tries to change a reservation
+// without its manage token or the owning restaurant's API key.
+var ErrNotAuthorized = errors.New("a valid manage token or the restaurant owner's API key is required")
+
+// ErrInvalidTransition is returned when a reservation can't move from its
+// current status to the requested one, e.g. completing a cancelled booking.
+var ErrInvalidTransition = errors.New("invalid status transition")
+
+// bookingMu serializes booking writes on SQLite, which has no row locks.
+// Other processes sharing the file are kept out by the database package
+// beginning SQLite transactions IMMEDIATE, which takes the file's write lock;
+// the mutex just saves this process's bookings from polling for it. On
+// Postgres the restaurant row is locked with SELECT ... FOR UPDATE instead.
+var bookingMu sync.Mutex
+
+// withRestaurantLock runs fn in a transaction that holds an exclusive lock on
+// the restaurant for its duration, so concurrent bookings for the same
+// restaurant can't both pass the capacity check. The restaurant is loaded
+// with preloadSchedule.
+func withRestaurantLock(db *gorm.DB, restaurantID string, fn func(tx *gorm.DB, r *models.Restaurant) error) error {
+	postgres := db.Dialector.Name() == "postgres"
+	if !postgres {
+		bookingMu.Lock()
+		defer bookingMu.Unlock()
+	}
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		if postgres {
+			var locked models.Restaurant
+			if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+				Select("id").First(&locked, "id = ?", restaurantID).Error; err != nil {
+				return err
+			}
+		}
+
+		var r models.Restaurant
+		if err := preloadSchedule(tx).First(&r, "id = ?", restaurantID).Error; err != nil {
+			return err
+		}
+		return fn(tx, &r)
+	})
+}
+
+// Actors recorded against reservation changes.
+const (
+	actorGuest = "guest"
+	actorOwner = "owner"
+)
+
+// authorizeReservation checks that the caller may manage res: either the
+// authenticated owner of its restaurant (ownerID) or a guest presenting the
+// manage token returned when it was booked. It returns which of the two the
+// caller is.
+func authorizeReservation(db *gorm.DB, res *models.Reservation, manageToken, ownerID string) (string, error) {
+	return authorizeGuestOrOwner(db, res.RestaurantID, res.ManageTokenHash, manageToken, ownerID)
+}
+
+// authorizeGuestOrOwner checks a manage token against tokenHash, or that
+// ownerID owns the restaurant, and returns which of the two the caller is.
+// Without a tokenHash only the owner is authorized.
+func authorizeGuestOrOwner(db *gorm.DB, restaurantID, tokenHash, manageToken, ownerID string) (string, error) {
+	if ownerID != "" && RestaurantBelongsToOwner(db, restaurantID, ownerID) {
+		return actorOwner, nil
+	}
+	if manageToken != "" && tokenHash != "" &&
+		subtle.ConstantTimeCompare([]byte(models.HashAPIKey(manageToken)), []byte(tokenHash)) == 1 {
+		return actorGuest, nil
+	}
+	return "", ErrNotAuthorized
+}
+
+// IssueMissingManageTokens gives every reservation booked before manage
+// tokens existed a token, so a bare reservation ID no longer lets anyone
+// manage it. Guests with an email address and a confirmed booking still to
+// come are sent theirs in a manage_token message; the other tokens are
+// discarded, which leaves those bookings to the owner. It returns how many
+// tokens it issued and how many it sent.
+func IssueMissingManageTokens(db *gorm.DB) (issued, sent int, err error) {
+	var legacy []models.Reservation
+	if err := db.Where("manage_token_hash IS NULL OR manage_token_hash = ''").Find(&legacy).Error; err != nil {
+		return 0, 0, err
+	}
+	restaurants := make(map[string]*models.Restaurant)
+	for i := range legacy {
+		res := &legacy[i]
+		r, ok := restaurants[res.RestaurantID]
+		if !ok {
+			r = &models.Restaurant{}
+			if err := db.First(r, "id = ?", res.RestaurantID).Error; err != nil {
+				r = nil
+			}
+			restaurants[res.RestaurantID] = r
+		}
+		deliver := false
+		if r != nil && res.Status == models.StatusConfirmed && res.CustomerEmail != "" {
+			today, _ := restaurantClock(r)
+			deliver = res.Date >= today.Format("2006-01-02")
+		}
+
+		raw, hash := models.GenerateManageToken()
+		var updated bool
+		err := db.Transaction(func(tx *gorm.DB) error {
+			// Another process may have issued one since legacy was read.
+			result := tx.Model(&models.Reservation{}).
+				Where("id = ? AND (manage_token_hash IS NULL OR manage_token_hash = '')", res.ID).
+				UpdateColumn("manage_token_hash", hash)
+			if result.Error != nil || result.RowsAffected == 0 {
+				return result.Error
+			}
+			updated = true
+			if !deliver {
+				return nil
+			}
+			data := guestMessageData(r, res)
+			data.ManageToken = raw
+			return enqueueNotification(tx, res, models.NotifyManageToken, data)
+		})
+		if err != nil {
+			return issued, sent, err
+		}
+		if updated {
+			issued++
+			if deliver {
+				sent++
+			}
+		}
+	}
+	return issued, sent, nil
+}
+
+// validateGuest checks the customer-supplied contact fields.
+func validateGuest(in dto.ReservationIn) error {
+	name := strings.TrimSpace(in.CustomerName)
+	if name == "" {
+		return fmt.Errorf("%w: customer_name is required", ErrInvalidInput)
+	}
+	if len(name) > 200 {
+		return fmt.Errorf("%w: customer_name must be at most 200 characters", ErrInvalidInput)
+	}
+	if in.CustomerEmail != "" {
+		if _, err := mail.ParseAddress(in.CustomerEmail); err != nil || len(in.CustomerEmail) > 200 {
+			return fmt.Errorf("%w: customer_email is not a valid email address", ErrInvalidInput)
+		}
+	}
+	if len(in.CustomerPhone) > 30 {
+		return fmt.Errorf("%w: customer_phone must be at most 30 characters", ErrInvalidInput)
+	}
+	return nil
+}
+
+// bookingSlot is a validated reservation time, with the tables the party
+// will be seated at when the restaurant has a table inventory.
+type bookingSlot struct {
+	day    time.Time
+	start  int
+	tables []models.DiningTable
+}
+
+func (s *bookingSlot) date() string  { return s.day.Format("2006-01-02") }
+func (s *bookingSlot) clock() string { return formatClock(s.start) }
+
+func (s *bookingSlot) tableIDs() string {
+	ids := make([]string, len(s.tables))
+	for i, t := range s.tables {
+		ids[i] = t.ID
+	}
+	return joinCSV(ids)
+}
+
+// validateSlot checks that a party of partySize can be booked at date/clock
+// at restaurant r: the restaurant is active, the party fits, the time is one
+// of the seating slots for that day, and overlapping reservations leave
+// enough capacity (or a free table). The reservation with ID excludeID, if
+// any, is left out of the capacity check so it can be moved without
+// competing with itself.
+func validateSlot(tx *gorm.DB, r *models.Restaurant, date, clock string, partySize int, excludeID string) (*bookingSlot, error) {
+	if !r.IsActive {
+		return nil, ErrRestaurantInactive
+	}
+	if partySize < 1 {
+		return nil, fmt.Errorf("%w: party_size must be at least 1", ErrInvalidInput)
+	}
+	if limit := maxPartySize(r); partySize > limit {
+		return nil, fmt.Errorf("%w: %s seats at most %d guests", ErrPartyTooLarge, r.Name, limit)
+	}
+
+	day, err := parseDate(date)
+	if err != nil {
+		return nil, err
+	}
+	start, err := parseClock(clock)
+	if err != nil {
+		return nil, err
+	}
+
+	today, now := restaurantClock(r)
+	if day.Before(today) || (day.Equal(today) && start <= now) {
+		return nil, fmt.Errorf("%w: %s %s has already passed at the restaurant", ErrInvalidInput, date, formatClock(start))
+	}
+
+	slots, _ := seatingSlots(r, day)
+	if len(slots) == 0 {
+		note := ""
+		if override, blackouts := overridesOn(r, day.Format("2006-01-02")); override != nil {
+			note = override.Note
+		} else if len(blackouts) > 0 {
+			note = blackouts[0].Note
+		}
+		return nil, fmt.Errorf("%w on %s", ErrRestaurantClosed, withNote(day.Format("Monday, January 2"), note))
+	}
+	onGrid := false
+	for _, m := range slots {
+		if m == start {
+			onGrid = true
+			break
+		}
+	}
+	if !onGrid {
+		return nil, fmt.Errorf("%w: %s is not a seating time on %s (first %s, last %s)",
+			ErrRestaurantClosed, formatClock(start), day.Weekday(), formatClock(slots[0]), formatClock(slots[len(slots)-1]))
+	}
+
+	bookings := loadBookings(tx, r, day)
+	if excludeID != "" {
+		kept := bookings[:0]
+		for _, b := range bookings {
+			if b.reservationID != excludeID {
+				kept = append(kept, b)
+			}
+		}
+		bookings = kept
+	}
+
+	tables, ok := seatParty(r, bookings, start, partySize)
+	if !ok {
+		return nil, ErrSlotUnavailable
+	}
+	return &bookingSlot{day: day, start: start, tables: tables}, nil
+}
+
+// setStatus moves res to status next, stamping the matching timestamp and
+// recording the change. It fails with ErrInvalidTransition if the state
+// machine doesn't allow the move.
+func setStatus(tx *gorm.DB, res *models.Reservation, next models.ReservationStatus, actor, ownerID string) error {
+	if !res.Status.CanTransition(next) {
+		return fmt.Errorf("%w: reservation is %s and cannot become %s", ErrInvalidTransition, res.Status, next)
+	}
+
+	now := time.Now().UTC()
+	switch next {
+	case models.StatusSeated:
+		res.SeatedAt = &now
+	case models.StatusCompleted:
+		res.CompletedAt = &now
+	case models.StatusNoShow:
+		res.NoShowAt = &now
+	case models.StatusCancelled:
+		res.CancelledAt = &now
+	}
+
+	change := models.ReservationStatusChange{
+		ReservationID: res.ID,
+		FromStatus:    res.Status,
+		ToStatus:      next,
+		ChangedBy:     actor,
+	}
+	if actor == actorOwner {
+		change.OwnerID = ownerID
+	}
+	res.Status = next
+
+	if err := tx.Save(res).Error; err != nil {
+		return err
+	}
+	return tx.Create(&change).Error
+}
@@ -0,0 +1,296 @@
+package services
+
+import (
+	"errors"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+
+	"github.com/agenteats/agenteats/internal/config"
+	"github.com/agenteats/agenteats/internal/database"
+	"github.com/agenteats/agenteats/internal/dto"
+	"github.com/agenteats/agenteats/internal/models"
+)
+
+func TestMakeReservationCapacity(t *testing.T) {
+	type booking struct {
+		time   string
+		party  int
+		status models.ReservationStatus
+	}
+	// 10 seats and a 90 minute turn.
+	tests := []struct {
+		name     string
+		existing []booking
+		time     string
+		party    int
+		wantErr  error
+	}{
+		{name: "empty", time: "19:00", party: 10},
+		{name: "fills the slot", existing: []booking{{"19:00", 6, ""}}, time: "19:00", party: 4},
+		{name: "over capacity", existing: []booking{{"19:00", 6, ""}}, time: "19:00", party: 5, wantErr: ErrSlotUnavailable},
+		{name: "overlaps the start", existing: []booking{{"19:00", 6, ""}}, time: "18:00", party: 5, wantErr: ErrSlotUnavailable},
+		{name: "overlaps the end", existing: []booking{{"19:00", 6, ""}}, time: "20:00", party: 5, wantErr: ErrSlotUnavailable},
+		{name: "ends as the other starts", existing: []booking{{"19:00", 6, ""}}, time: "17:30", party: 5},
+		{name: "starts as the other ends", existing: []booking{{"19:00", 6, ""}}, time: "20:30", party: 5},
+		{
+			name:     "peak inside the stay",
+			existing: []booking{{"18:00", 4, ""}, {"19:00", 4, ""}},
+			time:     "18:30", party: 3, wantErr: ErrSlotUnavailable,
+		},
+		{
+			name:     "seats freed as the next booking arrives",
+			existing: []booking{{"17:30", 5, ""}, {"19:00", 5, ""}},
+			time:     "18:30", party: 5,
+		},
+		{name: "cancelled seats are free", existing: []booking{{"19:00", 10, models.StatusCancelled}}, time: "19:00", party: 10},
+		{name: "seated guests hold seats", existing: []booking{{"19:00", 10, models.StatusSeated}}, time: "19:30", party: 1, wantErr: ErrSlotUnavailable},
+		{name: "party too large", time: "19:00", party: 11, wantErr: ErrPartyTooLarge},
+		{name: "not a seating time", time: "19:15", party: 2, wantErr: ErrRestaurantClosed},
+		{name: "after last seating", time: "23:00", party: 2, wantErr: ErrRestaurantClosed},
+	}
+
+	db := openTestDB(t)
+	date := daysFromNow(3)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := createTestRestaurant(t, db, dto.RestaurantIn{TotalSeats: 10})
+			for _, b := range tt.existing {
+				res, err := MakeReservation(db, r.ID, dto.ReservationIn{CustomerName: "Guest", PartySize: b.party, Date: date, Time: b.time})
+				if err != nil {
+					t.Fatal(err)
+				}
+				if b.status != "" {
+					db.Model(&models.Reservation{}).Where("id = ?", res.ID).Update("status", b.status)
+				}
+			}
+			_, err := MakeReservation(db, r.ID, dto.ReservationIn{CustomerName: "Guest", PartySize: tt.party, Date: date, Time: tt.time})
+			if tt.wantErr == nil && err != nil {
+				t.Fatalf("err = %v, want nil", err)
+			}
+			if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+				t.Fatalf("err = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestModifyReservationReleasesOwnSeats(t *testing.T) {
+	db := openTestDB(t)
+	r := createTestRestaurant(t, db, dto.RestaurantIn{TotalSeats: 6})
+	date := daysFromNow(3)
+	res, err := MakeReservation(db, r.ID, dto.ReservationIn{CustomerName: "Guest", PartySize: 4, Date: date, Time: "19:00"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := MakeReservation(db, r.ID, dto.ReservationIn{CustomerName: "Other", PartySize: 2, Date: date, Time: "19:00"}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Moving half an hour overlaps the booking's own old time.
+	clock := "19:30"
+	if _, err := ModifyReservation(db, res.ID, res.ManageToken, "", dto.ReservationUpdateIn{Time: &clock}); err != nil {
+		t.Fatalf("moving within own seats: %v", err)
+	}
+	party := 5
+	if _, err := ModifyReservation(db, res.ID, res.ManageToken, "", dto.ReservationUpdateIn{PartySize: &party}); !errors.Is(err, ErrSlotUnavailable) {
+		t.Fatalf("growing past capacity: err = %v, want ErrSlotUnavailable", err)
+	}
+	got, err := GetReservation(db, res.ID, res.ManageToken, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Time != "19:30" || got.PartySize != 4 {
+		t.Fatalf("reservation is %s for %d, want 19:30 for 4", got.Time, got.PartySize)
+	}
+}
+
+func TestConcurrentBookingsDontOversell(t *testing.T) {
+	db := openTestDB(t)
+	r := createTestRestaurant(t, db, dto.RestaurantIn{TotalSeats: 8})
+	date := daysFromNow(3)
+
+	const guests = 12
+	var wg sync.WaitGroup
+	errs := make(chan error, guests)
+	for i := 0; i < guests; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := MakeReservation(db, r.ID, dto.ReservationIn{CustomerName: "Guest", PartySize: 2, Date: date, Time: "19:00"})
+			errs <- err
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	booked := 0
+	for err := range errs {
+		switch {
+		case err == nil:
+			booked++
+		case errors.Is(err, ErrSlotUnavailable):
+		default:
+			t.Errorf("unexpected error: %v", err)
+		}
+	}
+	var seats int64
+	db.Model(&models.Reservation{}).Where("restaurant_id = ?", r.ID).Select("COALESCE(SUM(party_size), 0)").Scan(&seats)
+	if booked != 4 || seats != 8 {
+		t.Fatalf("booked %d parties and %d seats, want 4 and 8", booked, seats)
+	}
+}
+
+// TestBookingWaitsForOtherProcess checks the capacity check across two
+// connections to one SQLite file, as when the API and the MCP server share
+// it: a booking waits for another writer's transaction and then sees its
+// reservation, instead of failing with SQLITE_BUSY or overselling.
+func TestBookingWaitsForOtherProcess(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.db")
+	database.Init(&config.Config{DatabaseURL: path})
+	db := database.DB
+	r := createTestRestaurant(t, db, dto.RestaurantIn{TotalSeats: 4})
+	date := daysFromNow(3)
+
+	other, err := gorm.Open(sqlite.Open(path+"?_txlock=immediate&_busy_timeout=5000"), &gorm.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tx := other.Begin()
+	if err := tx.Create(&models.Reservation{
+		ID: models.NewID(), RestaurantID: r.ID, CustomerName: "Other process", PartySize: 4,
+		Date: date, Time: "19:00", Status: models.StatusConfirmed, ManageTokenHash: "x",
+	}).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := MakeReservation(db, r.ID, dto.ReservationIn{CustomerName: "Guest", PartySize: 2, Date: date, Time: "19:00"})
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		tx.Rollback()
+		t.Fatalf("booking finished while another writer held the lock: %v", err)
+	case <-time.After(300 * time.Millisecond):
+	}
+	if err := tx.Commit().Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := <-done; !errors.Is(err, ErrSlotUnavailable) {
+		t.Fatalf("err = %v, want ErrSlotUnavailable", err)
+	}
+}
+
+func TestAuthorizeGuestOrOwner(t *testing.T) {
+	db := openTestDB(t)
+	owner, err := RegisterOwner(db, dto.RegisterOwnerIn{Name: "Owner", Email: "owner@example.com"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	stranger, err := RegisterOwner(db, dto.RegisterOwnerIn{Name: "Stranger", Email: "stranger@example.com"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := createTestRestaurant(t, db, dto.RestaurantIn{})
+	db.Model(&models.Restaurant{}).Where("id = ?", r.ID).Update("owner_id", owner.ID)
+	token, hash := models.GenerateManageToken()
+	other, _ := models.GenerateManageToken()
+
+	tests := []struct {
+		name      string
+		tokenHash string
+		token     string
+		ownerID   string
+		want      string
+	}{
+		{name: "owner", tokenHash: hash, ownerID: owner.ID, want: actorOwner},
+		{name: "owner with a wrong token", tokenHash: hash, token: other, ownerID: owner.ID, want: actorOwner},
+		{name: "owner without a token hash", ownerID: owner.ID, want: actorOwner},
+		{name: "guest", tokenHash: hash, token: token, want: actorGuest},
+		{name: "guest who is another owner", tokenHash: hash, token: token, ownerID: stranger.ID, want: actorGuest},
+		{name: "another owner", tokenHash: hash, ownerID: stranger.ID},
+		{name: "wrong token", tokenHash: hash, token: other},
+		{name: "hash as token", tokenHash: hash, token: hash},
+		{name: "nothing", tokenHash: hash},
+		{name: "no token hash", token: token},
+		{name: "empty token and hash"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := authorizeGuestOrOwner(db, r.ID, tt.tokenHash, tt.token, tt.ownerID)
+			if tt.want == "" {
+				if !errors.Is(err, ErrNotAuthorized) {
+					t.Fatalf("authorized as %q, err = %v; want ErrNotAuthorized", got, err)
+				}
+				return
+			}
+			if err != nil || got != tt.want {
+				t.Fatalf("authorized as %q, err = %v; want %q", got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestIssueMissingManageTokens(t *testing.T) {
+	db := openTestDB(t)
+	r := createTestRestaurant(t, db, dto.RestaurantIn{})
+	book := func(email string) *dto.ReservationOut {
+		res, err := MakeReservation(db, r.ID, dto.ReservationIn{CustomerName: "Guest", CustomerEmail: email, PartySize: 2, Date: daysFromNow(7), Time: "19:00"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return res
+	}
+	upcoming := book("guest@example.com")
+	noEmail := book("")
+	past := models.Reservation{ID: models.NewID(), RestaurantID: r.ID, CustomerName: "Guest", CustomerEmail: "past@example.com",
+		PartySize: 2, Date: daysFromNow(-7), Time: "19:00", Status: models.StatusCompleted}
+	if err := db.Create(&past).Error; err != nil {
+		t.Fatal(err)
+	}
+	// Make all three look like they were booked before manage tokens.
+	db.Model(&models.Reservation{}).Where("1 = 1").Update("manage_token_hash", "")
+	db.Where("1 = 1").Delete(&models.Notification{})
+
+	issued, sent, err := IssueMissingManageTokens(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if issued != 3 || sent != 1 {
+		t.Fatalf("issued %d and sent %d, want 3 and 1", issued, sent)
+	}
+	var missing int64
+	db.Model(&models.Reservation{}).Where("manage_token_hash = ''").Count(&missing)
+	if missing != 0 {
+		t.Errorf("%d reservations still have no token", missing)
+	}
+
+	var notes []models.Notification
+	db.Find(&notes)
+	if len(notes) != 1 || notes[0].ReservationID != upcoming.ID || notes[0].Kind != models.NotifyManageToken {
+		t.Fatalf("queued %+v, want one manage_token message for the upcoming booking", notes)
+	}
+	i := strings.Index(notes[0].Body, "rm_")
+	if i < 0 {
+		t.Fatalf("message has no token:\n%s", notes[0].Body)
+	}
+	token := strings.Fields(notes[0].Body[i:])[0]
+	if _, err := GetReservation(db, upcoming.ID, token, ""); err != nil {
+		t.Errorf("emailed token doesn't manage the booking: %v", err)
+	}
+	if _, err := GetReservation(db, noEmail.ID, token, ""); !errors.Is(err, ErrNotAuthorized) {
+		t.Errorf("token works on another booking: err = %v", err)
+	}
+
+	// Running it again changes nothing.
+	if issued, sent, err := IssueMissingManageTokens(db); err != nil || issued != 0 || sent != 0 {
+		t.Errorf("second run: issued %d, sent %d, err %v", issued, sent, err)
+	}
+}
@@ -0,0 +1,256 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"gorm.io/gorm"
+
+	"github.com/agenteats/agenteats/internal/dto"
+	"github.com/agenteats/agenteats/internal/models"
+)
+
+// ErrNotReviewable is returned when a reservation can't be reviewed: the
+// visit hasn't been completed, or it already has a review.
+var ErrNotReviewable = errors.New("reservation can't be reviewed")
+
+// maxReviewLength caps review comments and owner replies, in characters.
+const maxReviewLength = 2000
+
+func toReviewOut(rv *models.Review) dto.ReviewOut {
+	out := dto.ReviewOut{
+		ID:           rv.ID,
+		RestaurantID: rv.RestaurantID,
+		AuthorName:   rv.AuthorName,
+		Rating:       rv.Rating,
+		Comment:      rv.Comment,
+		VisitDate:    rv.VisitDate,
+		Status:       string(rv.Status),
+		OwnerReply:   rv.OwnerReply,
+		CreatedAt:    rv.CreatedAt.Format("2006-01-02T15:04:05Z"),
+	}
+	if rv.RepliedAt != nil {
+		out.RepliedAt = rv.RepliedAt.Format("2006-01-02T15:04:05Z")
+	}
+	if rv.Status == models.ReviewHidden {
+		out.ModerationNote = rv.ModerationNote
+	}
+	return out
+}
+
+// recomputeRating sets a restaurant's Rating and ReviewCount from all of
+// its reviews. Hidden reviews still count: owners hide a review's text, but
+// can't take it out of their own rating. Rating is the mean rounded to one
+// decimal, or nil without reviews.
+func recomputeRating(tx *gorm.DB, restaurantID string) error {
+	var agg struct {
+		Count int
+		Avg   *float64
+	}
+	if err := tx.Model(&models.Review{}).
+		Select("COUNT(*) AS count, AVG(rating) AS avg").
+		Where("restaurant_id = ?", restaurantID).
+		Scan(&agg).Error; err != nil {
+		return err
+	}
+	var rating *float64
+	if agg.Count > 0 && agg.Avg != nil {
+		r := math.Round(*agg.Avg*10) / 10
+		rating = &r
+	}
+	return tx.Model(&models.Restaurant{}).Where("id = ?", restaurantID).
+		UpdateColumns(map[string]any{"rating": rating, "review_count": agg.Count}).Error
+}
+
+// RecomputeRating refreshes a restaurant's Rating and ReviewCount from its
+// reviews.
+func RecomputeRating(db *gorm.DB, restaurantID string) error {
+	return recomputeRating(db, restaurantID)
+}
+
+// SubmitReview records the guest's review of a completed reservation. It
+// needs the reservation's manage token: owners can't review their own
+// restaurants. The review is published straight away and the restaurant's
+// rating updated.
+func SubmitReview(db *gorm.DB, reservationID, manageToken string, in dto.ReviewIn) (*dto.ReviewOut, error) {
+	if in.Rating < 1 || in.Rating > 5 {
+		return nil, fmt.Errorf("%w: rating must be between 1 and 5", ErrInvalidInput)
+	}
+	comment := strings.TrimSpace(in.Comment)
+	if utf8.RuneCountInString(comment) > maxReviewLength {
+		return nil, fmt.Errorf("%w: comment must be at most %d characters", ErrInvalidInput, maxReviewLength)
+	}
+
+	var rv models.Review
+	err := db.Transaction(func(tx *gorm.DB) error {
+		var res models.Reservation
+		if err := tx.First(&res, "id = ?", reservationID).Error; err != nil {
+			return err
+		}
+		if _, err := authorizeReservation(tx, &res, manageToken, ""); err != nil {
+			return err
+		}
+		if res.Status != models.StatusCompleted {
+			return fmt.Errorf("%w: reservation is %s; only completed visits can be reviewed", ErrNotReviewable, res.Status)
+		}
+		var existing int64
+		if err := tx.Model(&models.Review{}).Where("reservation_id = ?", res.ID).Count(&existing).Error; err != nil {
+			return err
+		}
+		if existing > 0 {
+			return fmt.Errorf("%w: this reservation has already been reviewed", ErrNotReviewable)
+		}
+
+		rv = models.Review{
+			ID:            models.NewID(),
+			RestaurantID:  res.RestaurantID,
+			ReservationID: res.ID,
+			AuthorName:    models.ReviewAuthorName(res.CustomerName),
+			Rating:        in.Rating,
+			Comment:       comment,
+			VisitDate:     res.Date,
+			Status:        models.ReviewPublished,
+		}
+		if err := tx.Create(&rv).Error; err != nil {
+			return err
+		}
+		if err := recomputeRating(tx, res.RestaurantID); err != nil {
+			return err
+		}
+		out := toReviewOut(&rv)
+		out.ReservationID = rv.ReservationID
+		return enqueueRestaurantEvent(tx, res.RestaurantID, models.EventReviewCreated, out)
+	})
+	if err != nil {
+		return nil, err
+	}
+	out := toReviewOut(&rv)
+	out.ReservationID = rv.ReservationID
+	return &out, nil
+}
+
+// ListReviews pages through a restaurant's reviews, newest first. Only the
+// restaurant's owner (ownerID) may list hidden reviews.
+func ListReviews(db *gorm.DB, restaurantID string, q dto.ReviewQuery, ownerID string) (*dto.Page[dto.ReviewOut], error) {
+	var r models.Restaurant
+	if err := db.Select("id").First(&r, "id = ?", restaurantID).Error; err != nil {
+		return nil, err
+	}
+	status := models.ReviewStatus(q.Status)
+	if status == "" {
+		status = models.ReviewPublished
+	}
+	if !status.Valid() {
+		return nil, fmt.Errorf("%w: unknown review status %q", ErrInvalidInput, q.Status)
+	}
+	isOwner := ownerID != "" && RestaurantBelongsToOwner(db, restaurantID, ownerID)
+	if status != models.ReviewPublished && !isOwner {
+		return nil, fmt.Errorf("%w: only the restaurant's owner can list %s reviews", ErrNotAuthorized, status)
+	}
+
+	offset, err := startOffset(q.Cursor, q.Offset)
+	if err != nil {
+		return nil, err
+	}
+	limit := q.Limit
+	if limit <= 0 {
+		limit = 20
+	}
+
+	query := db.Model(&models.Review{}).Where("restaurant_id = ? AND status = ?", restaurantID, status)
+	var total int64
+	if err := query.Session(&gorm.Session{}).Count(&total).Error; err != nil {
+		return nil, err
+	}
+	var reviews []models.Review
+	if err := query.Order("created_at DESC, id DESC").Offset(offset).Limit(limit).Find(&reviews).Error; err != nil {
+		return nil, err
+	}
+
+	page := &dto.Page[dto.ReviewOut]{
+		Items:         make([]dto.ReviewOut, len(reviews)),
+		NextCursor:    nextOffsetCursor(offset, len(reviews), total),
+		TotalEstimate: total,
+	}
+	for i := range reviews {
+		page.Items[i] = toReviewOut(&reviews[i])
+		if isOwner {
+			page.Items[i].ReservationID = reviews[i].ReservationID
+		}
+	}
+	return page, nil
+}
+
+// ownedReview loads a review of one of ownerID's restaurants.
+func ownedReview(db *gorm.DB, reviewID, ownerID string) (*models.Review, error) {
+	var rv models.Review
+	if err := db.First(&rv, "id = ?", reviewID).Error; err != nil {
+		return nil, err
+	}
+	if !RestaurantBelongsToOwner(db, rv.RestaurantID, ownerID) {
+		return nil, ErrNotAuthorized
+	}
+	return &rv, nil
+}
+
+// ReplyToReview sets or, with an empty reply, removes the owner's public
+// reply to a review.
+func ReplyToReview(db *gorm.DB, reviewID, ownerID string, in dto.ReviewReplyIn) (*dto.ReviewOut, error) {
+	reply := strings.TrimSpace(in.Reply)
+	if utf8.RuneCountInString(reply) > maxReviewLength {
+		return nil, fmt.Errorf("%w: reply must be at most %d characters", ErrInvalidInput, maxReviewLength)
+	}
+	rv, err := ownedReview(db, reviewID, ownerID)
+	if err != nil {
+		return nil, err
+	}
+	rv.OwnerReply = reply
+	rv.RepliedAt = nil
+	if reply != "" {
+		now := time.Now().UTC()
+		rv.RepliedAt = &now
+	}
+	if err := db.Save(rv).Error; err != nil {
+		return nil, err
+	}
+	out := toReviewOut(rv)
+	out.ReservationID = rv.ReservationID
+	return &out, nil
+}
+
+// ModerateReview publishes or hides a review's text. Hiding needs a note
+// saying why, which is kept with the review. The restaurant's rating is
+// unaffected, so hiding can't be used to inflate it.
+func ModerateReview(db *gorm.DB, reviewID, ownerID string, in dto.ReviewStatusIn) (*dto.ReviewOut, error) {
+	status := models.ReviewStatus(in.Status)
+	if !status.Valid() {
+		return nil, fmt.Errorf("%w: status must be published or hidden", ErrInvalidInput)
+	}
+	note := strings.TrimSpace(in.Note)
+	if status == models.ReviewHidden && note == "" {
+		return nil, fmt.Errorf("%w: a note is required when hiding a review", ErrInvalidInput)
+	}
+
+	var rv *models.Review
+	err := db.Transaction(func(tx *gorm.DB) error {
+		var err error
+		if rv, err = ownedReview(tx, reviewID, ownerID); err != nil {
+			return err
+		}
+		rv.Status = status
+		if status == models.ReviewHidden {
+			rv.ModerationNote = note
+		}
+		return tx.Save(rv).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	out := toReviewOut(rv)
+	out.ReservationID = rv.ReservationID
+	return &out, nil
+}
@@ -3,14 +3,19 @@ package services
 import (
 	"errors"
 	"fmt"
+	"log"
 	"math"
+	"slices"
 	"sort"
 	"strings"
+	"time"
 
 	"gorm.io/gorm"
 
 	"github.com/agenteats/agenteats/internal/dto"
 	"github.com/agenteats/agenteats/internal/models"
+	"github.com/agenteats/agenteats/internal/notify"
+	"github.com/agenteats/agenteats/internal/search"
 )
 
 // ErrDuplicateRestaurant is returned when a restaurant with the same name
@@ -61,34 +66,73 @@ func toDetail(r *models.Restaurant) dto.RestaurantDetail {
 			IsClosed:  h.IsClosed,
 		}
 	}
+	turnTimes := make([]dto.TurnTimeOut, len(r.TurnTimes))
+	for i, t := range r.TurnTimes {
+		turnTimes[i] = dto.TurnTimeOut{
+			MinPartySize: t.MinPartySize,
+			MaxPartySize: t.MaxPartySize,
+			Minutes:      t.Minutes,
+		}
+	}
+	var specialHours []dto.HoursOverrideOut
+	for i := range r.HoursOverrides {
+		specialHours = append(specialHours, toHoursOverrideOut(&r.HoursOverrides[i]))
+	}
 	return dto.RestaurantDetail{
-		ID:          r.ID,
-		Name:        r.Name,
-		Description: r.Description,
-		Cuisines:    splitCSV(r.Cuisines),
-		PriceRange:  string(r.PriceRange),
-		Address:     r.Address,
-		City:        r.City,
-		State:       r.State,
-		ZipCode:     r.ZipCode,
-		Country:     r.Country,
-		Latitude:    r.Latitude,
-		Longitude:   r.Longitude,
-		Phone:       r.Phone,
-		Email:       r.Email,
-		Website:     r.Website,
-		Features:    splitCSV(r.Features),
-		TotalSeats:  r.TotalSeats,
-		Rating:      r.Rating,
-		ReviewCount: r.ReviewCount,
-		IsActive:    r.IsActive,
-		Hours:       hours,
+		ID:                 r.ID,
+		Name:               r.Name,
+		Description:        r.Description,
+		Cuisines:           splitCSV(r.Cuisines),
+		PriceRange:         string(r.PriceRange),
+		Address:            r.Address,
+		City:               r.City,
+		State:              r.State,
+		ZipCode:            r.ZipCode,
+		Country:            r.Country,
+		Timezone:           r.Timezone,
+		Latitude:           r.Latitude,
+		Longitude:          r.Longitude,
+		Phone:              r.Phone,
+		Email:              r.Email,
+		Website:            r.Website,
+		Features:           splitCSV(r.Features),
+		TotalSeats:         r.TotalSeats,
+		LastSeatingMinutes: r.LastSeatingMinutes,
+		TurnMinutes:        r.TurnMinutes,
+		TurnTimes:          turnTimes,
+		Rating:             r.Rating,
+		ReviewCount:        r.ReviewCount,
+		IsActive:           r.IsActive,
+		Hours:              hours,
+		SpecialHours:       specialHours,
 	}
 }
 
 func toMenuItemOut(m *models.MenuItem) dto.MenuItemOut {
-	return dto.MenuItemOut{
+	var groups []dto.MenuOptionGroupOut
+	for _, g := range m.OptionGroups {
+		options := make([]dto.MenuOptionOut, len(g.Options))
+		for i, o := range g.Options {
+			options[i] = dto.MenuOptionOut{
+				ID:            o.ID,
+				Name:          o.Name,
+				PriceDelta:    o.PriceDelta,
+				DietaryLabels: splitCSV(o.DietaryLabels),
+				Allergens:     splitCSV(o.Allergens),
+			}
+		}
+		groups = append(groups, dto.MenuOptionGroupOut{
+			ID:            g.ID,
+			Name:          g.Name,
+			Required:      g.Required,
+			MinSelections: g.MinSelections,
+			MaxSelections: g.MaxSelections,
+			Options:       options,
+		})
+	}
+	out := dto.MenuItemOut{
 		ID:            m.ID,
+		ExternalID:    m.ExternalID,
 		Category:      m.Category,
 		Name:          m.Name,
 		Description:   m.Description,
@@ -99,7 +143,16 @@ func toMenuItemOut(m *models.MenuItem) dto.MenuItemOut {
 		IsPopular:     m.IsPopular,
 		ImageURL:      m.ImageURL,
 		Calories:      m.Calories,
+		SortOrder:     m.SortOrder,
+
+		AllergensDeclared: m.AllergensDeclared,
+		OptionGroups:      groups,
+	}
+	if m.AllergensDeclared {
+		out.Allergens = splitCSV(m.Allergens)
+		out.MayContainAllergens = splitCSV(m.MayContainAllergens)
 	}
+	return out
 }
 
 func toReservationOut(r *models.Reservation, restaurantName string) dto.ReservationOut {
@@ -115,10 +168,38 @@ func toReservationOut(r *models.Reservation, restaurantName string) dto.Reservat
 		Time:            r.Time,
 		Status:          string(r.Status),
 		SpecialRequests: r.SpecialRequests,
+		SeatedAt:        formatTimestamp(r.SeatedAt),
+		CompletedAt:     formatTimestamp(r.CompletedAt),
+		NoShowAt:        formatTimestamp(r.NoShowAt),
+		CancelledAt:     formatTimestamp(r.CancelledAt),
 		CreatedAt:       r.CreatedAt.Format("2006-01-02T15:04:05Z"),
 	}
 }
 
+// formatTimestamp formats an optional timestamp, returning "" when unset.
+func formatTimestamp(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+	return t.UTC().Format("2006-01-02T15:04:05Z")
+}
+
+func toTurnTimes(restaurantID string, in []dto.TurnTimeIn) []models.TurnTime {
+	turnTimes := make([]models.TurnTime, 0, len(in))
+	for _, t := range in {
+		if t.Minutes <= 0 {
+			continue
+		}
+		turnTimes = append(turnTimes, models.TurnTime{
+			RestaurantID: restaurantID,
+			MinPartySize: t.MinPartySize,
+			MaxPartySize: t.MaxPartySize,
+			Minutes:      t.Minutes,
+		})
+	}
+	return turnTimes
+}
+
 // checkDuplicateRestaurant returns ErrDuplicateRestaurant if an active
 // restaurant with the same name already exists in the same city.
 // The comparison is case-insensitive.
@@ -133,44 +214,307 @@ func checkDuplicateRestaurant(db *gorm.DB, name, city string) error {
 	return nil
 }
 
+// reindex refreshes a restaurant's search document after a change. A
+// failure only makes search results stale, so it is logged rather than
+// failing the change itself.
+func reindex(db *gorm.DB, restaurantID string) {
+	if err := search.Reindex(db, restaurantID); err != nil {
+		log.Printf("search: failed to reindex restaurant %s: %v", restaurantID, err)
+	}
+}
+
 // --- Restaurant CRUD ---
 
-// ListRestaurants searches and filters restaurants.
-func ListRestaurants(db *gorm.DB, q, city, cuisine, priceRange string, features []string, limit, offset int) []dto.RestaurantSummary {
-	query := db.Where("is_active = ?", true)
+const (
+	// searchCandidates caps how many full-text matches a restaurant search
+	// considers before the other filters are applied.
+	searchCandidates = 500
 
-	if city != "" {
-		query = query.Where("city LIKE ?", "%"+city+"%")
+	// geoCandidates caps how many restaurants in a location search's
+	// bounding box are considered, nearest first.
+	geoCandidates = 500
+)
+
+// openNowScan caps how many restaurants one page of an open_now listing
+// checks, openNowBatch at a time. A page can come back short of the limit,
+// with a cursor to carry on from, when few of them are open.
+var (
+	openNowScan  = 500
+	openNowBatch = 100
+)
+
+// ListRestaurants searches and filters restaurants. Free text in q.Q is
+// matched against the search index and ranks results by relevance. With
+// q.OpenNow, only restaurants open right now in their own time zone are
+// returned. With a location, only restaurants within the radius are
+// returned, nearest first, each with its distance.
+//
+// Listings ordered by rating, with or without q.OpenNow, page by keyset, so
+// restaurants coming and going don't shift later pages. Free-text and
+// location searches are ranked in Go among a bounded set of candidates
+// (searchCandidates and geoCandidates) and page by offset into that
+// ranking, so their pages can shift if restaurants change in between.
+func ListRestaurants(db *gorm.DB, q dto.RestaurantQuery) (*dto.Page[dto.RestaurantSummary], error) {
+	geo, err := parseGeoQuery(q)
+	if err != nil {
+		return nil, err
 	}
-	if cuisine != "" {
-		query = query.Where("cuisines LIKE ?", "%"+cuisine+"%")
+	c, err := decodeCursor(q.Cursor)
+	if err != nil {
+		return nil, err
+	}
+	if q.Cursor == "" {
+		c.Offset = q.Offset
+	}
+	if q.Limit <= 0 {
+		q.Limit = 20
+	}
+
+	query := db.Model(&models.Restaurant{}).Where("is_active = ?", true)
+
+	if q.City != "" {
+		query = query.Where("city LIKE ?", "%"+q.City+"%")
+	}
+	if q.Cuisine != "" || len(q.Features) > 0 {
+		idx, err := loadTaxonomy(db)
+		if err != nil {
+			return nil, err
+		}
+		if q.Cuisine != "" {
+			term, err := resolveTerm(idx, models.KindCuisine, q.Cuisine)
+			if err != nil {
+				return nil, err
+			}
+			query = query.Where(hasTermSQL("restaurant_cuisines", "restaurants", "restaurant_id"), term.ID)
+		}
+		features, err := resolveTerms(idx, models.KindFeature, q.Features)
+		if err != nil {
+			return nil, err
+		}
+		for _, f := range features {
+			query = query.Where(hasTermSQL("restaurant_features", "restaurants", "restaurant_id"), f.ID)
+		}
+	}
+	if q.PriceRange != "" {
+		query = query.Where("price_range = ?", q.PriceRange)
+	}
+	var matches map[string]search.Result
+	if !search.Blank(q.Q) {
+		results, err := search.Search(db, q.Q, searchCandidates)
+		if err != nil {
+			return nil, err
+		}
+		if len(results) == 0 {
+			return &dto.Page[dto.RestaurantSummary]{Items: []dto.RestaurantSummary{}}, nil
+		}
+		matches = make(map[string]search.Result, len(results))
+		ids := make([]string, len(results))
+		for i, res := range results {
+			matches[res.RestaurantID] = res
+			ids[i] = res.RestaurantID
+		}
+		query = query.Where("id IN ?", ids)
+	}
+	if geo != nil {
+		minLat, maxLat, minLng, maxLng, lngOK := geo.boundingBox()
+		query = query.Where("latitude BETWEEN ? AND ?", minLat, maxLat)
+		if lngOK {
+			query = query.Where("longitude BETWEEN ? AND ?", minLng, maxLng)
+		} else {
+			query = query.Where("longitude IS NOT NULL")
+		}
+		query = query.Order(geo.nearestFirst()).Limit(geoCandidates)
+	}
+	// The Session makes the filtered query safe to reuse for the count.
+	query = query.Session(&gorm.Session{}).
+		Order("CASE WHEN rating IS NULL THEN 1 ELSE 0 END, rating DESC, id ASC")
+
+	if geo == nil && matches == nil {
+		if q.OpenNow {
+			return listOpenRestaurantsByRating(query, c, q.Limit, time.Now())
+		}
+		return listRestaurantsByRating(query, c, q.Limit)
+	}
+
+	var restaurants []models.Restaurant
+	if q.OpenNow {
+		query = preloadHours(query)
+	}
+	if err := query.Find(&restaurants).Error; err != nil {
+		return nil, err
 	}
-	if priceRange != "" {
-		query = query.Where("price_range = ?", priceRange)
+
+	now := time.Now()
+	results := make([]dto.RestaurantSummary, 0, len(restaurants))
+	for i := range restaurants {
+		r := &restaurants[i]
+		if q.OpenNow && !isOpenAt(r, now) {
+			continue
+		}
+		s := toSummary(r)
+		if m, ok := matches[r.ID]; ok {
+			s.MatchedIn, s.Highlight = m.MatchedIn, m.Highlight
+		}
+		if geo != nil {
+			d := haversineKm(geo.lat, geo.lng, *r.Latitude, *r.Longitude)
+			if d > geo.radiusKm {
+				continue
+			}
+			d = math.Round(d*100) / 100
+			s.DistanceKm = &d
+		}
+		results = append(results, s)
+	}
+	switch {
+	case geo != nil:
+		// Stable, so equal distances keep the rating order.
+		sort.SliceStable(results, func(i, j int) bool {
+			return *results[i].DistanceKm < *results[j].DistanceKm
+		})
+	case matches != nil:
+		sort.SliceStable(results, func(i, j int) bool {
+			return matches[results[i].ID].Score > matches[results[j].ID].Score
+		})
 	}
-	for _, f := range features {
-		query = query.Where("features LIKE ?", "%"+f+"%")
+
+	page := &dto.Page[dto.RestaurantSummary]{
+		Items:         []dto.RestaurantSummary{},
+		TotalEstimate: int64(len(results)),
+	}
+	if c.Offset < len(results) {
+		page.Items = results[c.Offset:min(len(results), c.Offset+q.Limit)]
+	}
+	page.NextCursor = nextOffsetCursor(c.Offset, len(page.Items), page.TotalEstimate)
+	return page, nil
+}
+
+// listRestaurantsByRating pages through restaurants in the query's (rating,
+// id) order. After the first page it resumes after the cursor's row rather
+// than at an offset, so pages don't shift as restaurants come and go.
+func listRestaurantsByRating(query *gorm.DB, c cursor, limit int) (*dto.Page[dto.RestaurantSummary], error) {
+	page := &dto.Page[dto.RestaurantSummary]{}
+	if err := query.Count(&page.TotalEstimate).Error; err != nil {
+		return nil, err
 	}
-	if q != "" {
-		query = query.Where("name LIKE ? OR description LIKE ? OR cuisines LIKE ?",
-			"%"+q+"%", "%"+q+"%", "%"+q+"%")
+
+	query = afterRating(query, c)
+	if c.ID == "" {
+		query = query.Offset(c.Offset)
 	}
 
+	// One extra row tells us whether there is a next page.
 	var restaurants []models.Restaurant
-	query.Order("CASE WHEN rating IS NULL THEN 1 ELSE 0 END, rating DESC").
-		Offset(offset).Limit(limit).Find(&restaurants)
+	if err := query.Limit(limit + 1).Find(&restaurants).Error; err != nil {
+		return nil, err
+	}
+	if len(restaurants) > limit {
+		restaurants = restaurants[:limit]
+		page.NextCursor = encodeCursor(ratingCursor(&restaurants[limit-1]))
+	}
 
-	results := make([]dto.RestaurantSummary, len(restaurants))
+	page.Items = make([]dto.RestaurantSummary, len(restaurants))
 	for i := range restaurants {
-		results[i] = toSummary(&restaurants[i])
+		page.Items[i] = toSummary(&restaurants[i])
 	}
-	return results
+	return page, nil
+}
+
+// listOpenRestaurantsByRating is listRestaurantsByRating for restaurants
+// open at instant at. Opening hours are checked in Go, so it reads the
+// query's restaurants in batches from the cursor, checking at most
+// openNowScan of them; if it runs out before filling the page, the cursor
+// resumes after the last one checked. TotalEstimate counts restaurants
+// whether or not they are open.
+func listOpenRestaurantsByRating(query *gorm.DB, c cursor, limit int, at time.Time) (*dto.Page[dto.RestaurantSummary], error) {
+	page := &dto.Page[dto.RestaurantSummary]{}
+	if err := query.Count(&page.TotalEstimate).Error; err != nil {
+		return nil, err
+	}
+	query = preloadHours(query)
+
+	// A legacy offset counts open restaurants.
+	skip := 0
+	if c.ID == "" {
+		skip = c.Offset
+	}
+	var open []models.Restaurant
+	exhausted := false
+scan:
+	for scanned := 0; scanned < openNowScan; {
+		size := min(openNowBatch, openNowScan-scanned)
+		var batch []models.Restaurant
+		if err := afterRating(query, c).Limit(size).Find(&batch).Error; err != nil {
+			return nil, err
+		}
+		for i := range batch {
+			r := &batch[i]
+			scanned++
+			c = ratingCursor(r)
+			if !isOpenAt(r, at) {
+				continue
+			}
+			if skip > 0 {
+				skip--
+				continue
+			}
+			// One extra tells us whether there is a next page.
+			if open = append(open, *r); len(open) > limit {
+				break scan
+			}
+		}
+		if len(batch) < size {
+			exhausted = true
+			break
+		}
+	}
+
+	switch {
+	case len(open) > limit:
+		open = open[:limit]
+		page.NextCursor = encodeCursor(ratingCursor(&open[limit-1]))
+	case !exhausted:
+		page.NextCursor = encodeCursor(c)
+	}
+	page.Items = make([]dto.RestaurantSummary, len(open))
+	for i := range open {
+		page.Items[i] = toSummary(&open[i])
+	}
+	return page, nil
+}
+
+// ratingCursor returns the cursor that resumes a rating-ordered listing
+// after r.
+func ratingCursor(r *models.Restaurant) cursor {
+	return cursor{Rating: r.Rating, ID: r.ID}
+}
+
+// afterRating narrows a rating-ordered query to the restaurants after the
+// cursor's. A cursor without an ID, from the first page, narrows nothing.
+func afterRating(query *gorm.DB, c cursor) *gorm.DB {
+	switch {
+	case c.ID != "" && c.Rating != nil:
+		return query.Where("rating < ? OR (rating = ? AND id > ?) OR rating IS NULL", *c.Rating, *c.Rating, c.ID)
+	case c.ID != "":
+		return query.Where("rating IS NULL AND id > ?", c.ID)
+	}
+	return query
+}
+
+// preloadHours preloads what isOpenAt needs: weekly hours and recent and
+// upcoming hours overrides.
+func preloadHours(query *gorm.DB) *gorm.DB {
+	since := time.Now().AddDate(0, 0, -2).Format("2006-01-02")
+	return query.Preload("Hours").Preload("HoursOverrides", "date >= ?", since)
 }
 
 // GetRestaurant returns full restaurant details.
 func GetRestaurant(db *gorm.DB, id string) (*dto.RestaurantDetail, error) {
 	var r models.Restaurant
-	if err := db.Preload("Hours").First(&r, "id = ?", id).Error; err != nil {
+	if err := db.Preload("Hours").Preload("TurnTimes").
+		Preload("HoursOverrides", func(db *gorm.DB) *gorm.DB {
+			return db.Where("date >= ?", time.Now().Format("2006-01-02")).Order("date, open_time")
+		}).
+		First(&r, "id = ?", id).Error; err != nil {
 		return nil, err
 	}
 	detail := toDetail(&r)
@@ -182,33 +526,52 @@ func CreateRestaurant(db *gorm.DB, in dto.RestaurantIn) (*dto.RestaurantDetail,
 	if err := checkDuplicateRestaurant(db, in.Name, in.City); err != nil {
 		return nil, err
 	}
+	cuisines, features, err := restaurantTerms(db, in)
+	if err != nil {
+		return nil, err
+	}
 	r := models.Restaurant{
-		ID:          models.NewID(),
-		Name:        in.Name,
-		Description: in.Description,
-		Cuisines:    joinCSV(in.Cuisines),
-		PriceRange:  models.PriceRange(in.PriceRange),
-		Address:     in.Address,
-		City:        in.City,
-		State:       in.State,
-		ZipCode:     in.ZipCode,
-		Country:     in.Country,
-		Latitude:    in.Latitude,
-		Longitude:   in.Longitude,
-		Phone:       in.Phone,
-		Email:       in.Email,
-		Website:     in.Website,
-		Features:    joinCSV(in.Features),
-		TotalSeats:  in.TotalSeats,
-		IsActive:    true,
+		ID:                 models.NewID(),
+		Name:               in.Name,
+		Description:        in.Description,
+		Cuisines:           models.TermsCSV(cuisines),
+		PriceRange:         models.PriceRange(in.PriceRange),
+		Address:            in.Address,
+		City:               in.City,
+		State:              in.State,
+		ZipCode:            in.ZipCode,
+		Country:            in.Country,
+		Latitude:           in.Latitude,
+		Longitude:          in.Longitude,
+		Phone:              in.Phone,
+		Email:              in.Email,
+		Website:            in.Website,
+		Features:           models.TermsCSV(features),
+		TotalSeats:         in.TotalSeats,
+		LastSeatingMinutes: in.LastSeatingMinutes,
+		TurnMinutes:        in.TurnMinutes,
+		IsActive:           true,
+		CuisineTerms:       cuisines,
+		FeatureTerms:       features,
 	}
 
 	if r.Country == "" {
 		r.Country = "US"
 	}
+	tz, err := resolveTimezone(in.Timezone, r.Country, r.State)
+	if err != nil {
+		return nil, err
+	}
+	r.Timezone = tz
 	if r.TotalSeats == 0 {
 		r.TotalSeats = 50
 	}
+	if r.LastSeatingMinutes <= 0 {
+		r.LastSeatingMinutes = defaultLastSeatingMinutes
+	}
+	if r.TurnMinutes <= 0 {
+		r.TurnMinutes = defaultTurnMinutes
+	}
 	if r.PriceRange == "" {
 		r.PriceRange = models.PriceModerate
 	}
@@ -224,10 +587,12 @@ func CreateRestaurant(db *gorm.DB, in dto.RestaurantIn) (*dto.RestaurantDetail,
 		}
 	}
 	r.Hours = hours
+	r.TurnTimes = toTurnTimes(r.ID, in.TurnTimes)
 
 	if err := db.Create(&r).Error; err != nil {
 		return nil, err
 	}
+	reindex(db, r.ID)
 
 	detail := toDetail(&r)
 	return &detail, nil
@@ -239,23 +604,40 @@ func UpdateRestaurant(db *gorm.DB, id string, in dto.RestaurantIn) (*dto.Restaur
 	if err := db.First(&r, "id = ?", id).Error; err != nil {
 		return nil, err
 	}
+	cuisines, features, err := restaurantTerms(db, in)
+	if err != nil {
+		return nil, err
+	}
 
 	r.Name = in.Name
 	r.Description = in.Description
-	r.Cuisines = joinCSV(in.Cuisines)
+	r.Cuisines = models.TermsCSV(cuisines)
 	r.PriceRange = models.PriceRange(in.PriceRange)
 	r.Address = in.Address
 	r.City = in.City
 	r.State = in.State
 	r.ZipCode = in.ZipCode
 	r.Country = in.Country
+	tz, err := resolveTimezone(in.Timezone, r.Country, r.State)
+	if err != nil {
+		return nil, err
+	}
+	r.Timezone = tz
 	r.Latitude = in.Latitude
 	r.Longitude = in.Longitude
 	r.Phone = in.Phone
 	r.Email = in.Email
 	r.Website = in.Website
-	r.Features = joinCSV(in.Features)
+	r.Features = models.TermsCSV(features)
 	r.TotalSeats = in.TotalSeats
+	r.LastSeatingMinutes = in.LastSeatingMinutes
+	if r.LastSeatingMinutes <= 0 {
+		r.LastSeatingMinutes = defaultLastSeatingMinutes
+	}
+	r.TurnMinutes = in.TurnMinutes
+	if r.TurnMinutes <= 0 {
+		r.TurnMinutes = defaultTurnMinutes
+	}
 
 	// Replace hours
 	db.Where("restaurant_id = ?", id).Delete(&models.OperatingHours{})
@@ -271,24 +653,48 @@ func UpdateRestaurant(db *gorm.DB, id string, in dto.RestaurantIn) (*dto.Restaur
 	}
 	r.Hours = hours
 
+	// Replace turn times
+	db.Where("restaurant_id = ?", id).Delete(&models.TurnTime{})
+	r.TurnTimes = toTurnTimes(r.ID, in.TurnTimes)
+
 	if err := db.Save(&r).Error; err != nil {
 		return nil, err
 	}
+	if err := setRestaurantTerms(db, &r, cuisines, features); err != nil {
+		return nil, err
+	}
+	reindex(db, id)
 	// Reload with hours
 	return GetRestaurant(db, id)
 }
 
 // --- Menu ---
 
-// GetMenu returns the full menu grouped by category.
-func GetMenu(db *gorm.DB, restaurantID string) (*dto.MenuOut, error) {
+// GetMenu returns the full menu grouped by category, in the owner's
+// display order. Items that contain or may contain any of excludeAllergens
+// are left out.
+func GetMenu(db *gorm.DB, restaurantID string, excludeAllergens []string) (*dto.MenuOut, error) {
 	var r models.Restaurant
 	if err := db.First(&r, "id = ?", restaurantID).Error; err != nil {
 		return nil, err
 	}
 
 	var items []models.MenuItem
-	db.Where("restaurant_id = ?", restaurantID).Order("category, name").Find(&items)
+	query := preloadOptions(db).Model(&models.MenuItem{}).Where("restaurant_id = ?", restaurantID)
+	if len(excludeAllergens) > 0 {
+		idx, err := loadTaxonomy(db)
+		if err != nil {
+			return nil, err
+		}
+		allergens, err := resolveTerms(idx, models.KindAllergen, excludeAllergens)
+		if err != nil {
+			return nil, err
+		}
+		query = withoutAllergens(query, allergens)
+	}
+	if err := query.Order("category, sort_order, name").Find(&items).Error; err != nil {
+		return nil, err
+	}
 
 	categories := make(map[string][]dto.MenuItemOut)
 	currency := "USD"
@@ -302,6 +708,7 @@ func GetMenu(db *gorm.DB, restaurantID string) (*dto.MenuOut, error) {
 		RestaurantID:   restaurantID,
 		RestaurantName: r.Name,
 		Currency:       currency,
+		CategoryOrder:  categoryOrder(db, restaurantID, categories),
 		Categories:     categories,
 	}, nil
 }
@@ -312,20 +719,53 @@ func AddMenuItem(db *gorm.DB, restaurantID string, in dto.MenuItemIn) (*dto.Menu
 	if err := db.First(&r, "id = ?", restaurantID).Error; err != nil {
 		return nil, err
 	}
+	if err := validateMenuItem(in.Name, in.Price); err != nil {
+		return nil, err
+	}
+	externalID := strings.TrimSpace(in.ExternalID)
+	if err := checkExternalID(db, restaurantID, externalID, ""); err != nil {
+		return nil, err
+	}
+	idx, err := loadTaxonomy(db)
+	if err != nil {
+		return nil, err
+	}
+	dietary, err := resolveTerms(idx, models.KindDietary, in.DietaryLabels)
+	if err != nil {
+		return nil, err
+	}
+	allergens, mayContain, err := resolveAllergens(idx, in.Allergens, in.MayContainAllergens)
+	if err != nil {
+		return nil, err
+	}
+	itemID := models.NewID()
+	groups, err := buildOptionGroups(idx, itemID, in.OptionGroups)
+	if err != nil {
+		return nil, err
+	}
 
 	item := models.MenuItem{
-		ID:            models.NewID(),
+		ID:            itemID,
 		RestaurantID:  restaurantID,
+		ExternalID:    externalID,
 		Category:      in.Category,
 		Name:          in.Name,
 		Description:   in.Description,
 		Price:         in.Price,
 		Currency:      in.Currency,
-		DietaryLabels: joinCSV(in.DietaryLabels),
-		IsAvailable:   in.IsAvailable,
+		DietaryLabels: models.TermsCSV(dietary),
+		DietaryTerms:  dietary,
+		IsAvailable:   in.IsAvailable == nil || *in.IsAvailable,
 		IsPopular:     in.IsPopular,
 		ImageURL:      in.ImageURL,
 		Calories:      in.Calories,
+
+		Allergens:           models.TermsCSV(allergens),
+		MayContainAllergens: models.TermsCSV(mayContain),
+		AllergensDeclared:   in.Allergens != nil || in.MayContainAllergens != nil,
+		AllergenTerms:       allergens,
+		MayContainTerms:     mayContain,
+		OptionGroups:        groups,
 	}
 	if item.Currency == "" {
 		item.Currency = "USD"
@@ -333,10 +773,24 @@ func AddMenuItem(db *gorm.DB, restaurantID string, in dto.MenuItemIn) (*dto.Menu
 	if item.Category == "" {
 		item.Category = "Main"
 	}
+	if in.SortOrder != nil {
+		item.SortOrder = *in.SortOrder
+	} else {
+		item.SortOrder = nextSortOrder(db, restaurantID, item.Category)
+	}
 
 	if err := db.Create(&item).Error; err != nil {
 		return nil, err
 	}
+	// gorm skips zero values for fields with a default, so persist an
+	// explicitly unavailable item with a follow-up update.
+	if in.IsAvailable != nil && !*in.IsAvailable {
+		item.IsAvailable = false
+		if err := db.Model(&item).Update("is_available", false).Error; err != nil {
+			return nil, err
+		}
+	}
+	reindex(db, restaurantID)
 
 	out := toMenuItemOut(&item)
 	return &out, nil
@@ -345,104 +799,447 @@ func AddMenuItem(db *gorm.DB, restaurantID string, in dto.MenuItemIn) (*dto.Menu
 // --- Reservations ---
 
 // CheckAvailability returns available time slots for a given date.
+// Slots are derived from the restaurant's operating hours for that weekday;
+// when it is closed the result is empty and Reason explains why. Dates and
+// times are in the restaurant's time zone, and slots that have already
+// passed there are left out.
 func CheckAvailability(db *gorm.DB, restaurantID, date string, partySize int) (*dto.AvailabilityOut, error) {
+	day, err := parseDate(date)
+	if err != nil {
+		return nil, err
+	}
+
 	var r models.Restaurant
-	if err := db.First(&r, "id = ?", restaurantID).Error; err != nil {
+	if err := preloadSchedule(db).First(&r, "id = ?", restaurantID).Error; err != nil {
 		return nil, err
 	}
 
-	var existing []models.Reservation
-	db.Where("restaurant_id = ? AND date = ? AND status = ?",
-		restaurantID, date, models.StatusConfirmed).Find(&existing)
+	out := &dto.AvailabilityOut{
+		RestaurantID:   restaurantID,
+		RestaurantName: r.Name,
+		Date:           date,
+		AvailableTimes: []string{},
+		MaxPartySize:   maxPartySize(&r),
+	}
 
-	bookedSeats := make(map[string]int)
-	for _, res := range existing {
-		bookedSeats[res.Time] += res.PartySize
+	today, now := restaurantClock(&r)
+	if day.Before(today) {
+		out.Reason = "That date has already passed at the restaurant"
+		return out, nil
 	}
 
-	var available []string
-	for hour := 11; hour <= 21; hour++ {
-		for _, minute := range []int{0, 30} {
-			slot := fmt.Sprintf("%02d:%02d", hour, minute)
-			if bookedSeats[slot]+partySize <= r.TotalSeats {
-				available = append(available, slot)
+	slots, reason := seatingSlots(&r, day)
+	if len(slots) == 0 {
+		out.Reason = reason
+		return out, nil
+	}
+	if day.Equal(today) {
+		upcoming := slots[:0]
+		for _, m := range slots {
+			if m > now {
+				upcoming = append(upcoming, m)
 			}
 		}
+		if len(upcoming) == 0 {
+			out.Reason = "No more seating times today"
+			return out, nil
+		}
+		slots = upcoming
 	}
 
-	return &dto.AvailabilityOut{
-		RestaurantID:   restaurantID,
-		RestaurantName: r.Name,
-		Date:           date,
-		AvailableTimes: available,
-		MaxPartySize:   r.TotalSeats,
-	}, nil
+	bookings := loadBookings(db, &r, day)
+	for _, m := range slots {
+		if _, ok := seatParty(&r, bookings, m, partySize); ok {
+			out.AvailableTimes = append(out.AvailableTimes, formatClock(m))
+		}
+	}
+	if len(out.AvailableTimes) == 0 {
+		if partySize > out.MaxPartySize {
+			out.Reason = fmt.Sprintf("Restaurant seats at most %d guests", out.MaxPartySize)
+		} else {
+			out.Reason = fmt.Sprintf("Fully booked for a party of %d", partySize)
+		}
+	}
+	return out, nil
 }
 
-// MakeReservation creates a reservation.
+// MakeReservation creates a reservation. The returned manage token is shown
+// only once; the guest needs it to cancel or modify the booking.
+// The request is validated and the party must fit within the restaurant's
+// capacity for the whole dining duration, counting every confirmed
+// reservation that overlaps it. The capacity check and insert run under a
+// per-restaurant lock so concurrent bookings can't oversell a slot.
 func MakeReservation(db *gorm.DB, restaurantID string, in dto.ReservationIn) (*dto.ReservationOut, error) {
-	var r models.Restaurant
-	if err := db.First(&r, "id = ?", restaurantID).Error; err != nil {
+	if err := validateGuest(in); err != nil {
 		return nil, err
 	}
 
-	res := models.Reservation{
-		ID:              models.NewID(),
-		RestaurantID:    restaurantID,
-		CustomerName:    in.CustomerName,
-		CustomerEmail:   in.CustomerEmail,
-		CustomerPhone:   in.CustomerPhone,
-		PartySize:       in.PartySize,
-		Date:            in.Date,
-		Time:            in.Time,
-		Status:          models.StatusConfirmed,
-		SpecialRequests: in.SpecialRequests,
-	}
+	var out dto.ReservationOut
+	err := withRestaurantLock(db, restaurantID, func(tx *gorm.DB, r *models.Restaurant) error {
+		slot, err := validateSlot(tx, r, in.Date, in.Time, in.PartySize, "")
+		if err != nil {
+			return err
+		}
+
+		rawToken, tokenHash := models.GenerateManageToken()
+		res := models.Reservation{
+			ID:              models.NewID(),
+			RestaurantID:    restaurantID,
+			CustomerName:    strings.TrimSpace(in.CustomerName),
+			CustomerEmail:   in.CustomerEmail,
+			CustomerPhone:   in.CustomerPhone,
+			PartySize:       in.PartySize,
+			Date:            slot.date(),
+			Time:            slot.clock(),
+			Status:          models.StatusConfirmed,
+			SpecialRequests: in.SpecialRequests,
+			TableIDs:        slot.tableIDs(),
+			ManageTokenHash: tokenHash,
+		}
+		if err := tx.Create(&res).Error; err != nil {
+			return err
+		}
 
-	if err := db.Create(&res).Error; err != nil {
+		out = toReservationOut(&res, r.Name)
+		if err := enqueueEvent(tx, r.OwnerID, models.EventReservationCreated, out); err != nil {
+			return err
+		}
+		if err := enqueueNotification(tx, &res, models.NotifyConfirmation, guestMessageData(r, &res)); err != nil {
+			return err
+		}
+		out.ManageToken = rawToken
+		return nil
+	})
+	if err != nil {
 		return nil, err
 	}
-
-	out := toReservationOut(&res, r.Name)
 	return &out, nil
 }
 
-// ListReservations returns reservations for a restaurant, optionally filtered by date.
-func ListReservations(db *gorm.DB, restaurantID, date string) []dto.ReservationOut {
+// reservationSorts maps the sort keys accepted by ListReservations to SQL.
+var reservationSorts = map[string]string{
+	"date":        "date ASC, time ASC, id ASC",
+	"-date":       "date DESC, time DESC, id DESC",
+	"party_size":  "party_size ASC, date ASC, time ASC, id ASC",
+	"-party_size": "party_size DESC, date ASC, time ASC, id ASC",
+	"created_at":  "created_at ASC, id ASC",
+	"-created_at": "created_at DESC, id DESC",
+}
+
+// ListReservations returns a restaurant's reservations for its owner,
+// filtered, sorted and paged by q. Assigned tables are included for
+// restaurants with a table inventory.
+func ListReservations(db *gorm.DB, restaurantID string, q dto.ReservationQuery) (*dto.Page[dto.ReservationOut], error) {
+	offset, err := startOffset(q.Cursor, q.Offset)
+	if err != nil {
+		return nil, err
+	}
+
+	query := db.Model(&models.Reservation{}).Where("restaurant_id = ?", restaurantID)
+
+	if q.DateFrom != "" {
+		if _, err := parseDate(q.DateFrom); err != nil {
+			return nil, err
+		}
+		query = query.Where("date >= ?", q.DateFrom)
+	}
+	if q.DateTo != "" {
+		if _, err := parseDate(q.DateTo); err != nil {
+			return nil, err
+		}
+		query = query.Where("date <= ?", q.DateTo)
+	}
+	if len(q.Statuses) > 0 {
+		for _, st := range q.Statuses {
+			if !models.ReservationStatus(st).Valid() {
+				return nil, fmt.Errorf("%w: unknown status %q", ErrInvalidInput, st)
+			}
+		}
+		query = query.Where("status IN ?", q.Statuses)
+	}
+	if q.MinPartySize > 0 {
+		query = query.Where("party_size >= ?", q.MinPartySize)
+	}
+	if q.MaxPartySize > 0 {
+		query = query.Where("party_size <= ?", q.MaxPartySize)
+	}
+
+	sortKey := q.Sort
+	if sortKey == "" {
+		sortKey = "date"
+	}
+	order, ok := reservationSorts[sortKey]
+	if !ok {
+		return nil, fmt.Errorf("%w: unknown sort %q", ErrInvalidInput, q.Sort)
+	}
+
 	var r models.Restaurant
-	db.First(&r, "id = ?", restaurantID)
+	db.Preload("Tables").First(&r, "id = ?", restaurantID)
+	tableNames := make(map[string]string, len(r.Tables))
+	for _, t := range r.Tables {
+		tableNames[t.ID] = t.Name
+	}
 
-	query := db.Where("restaurant_id = ?", restaurantID)
-	if date != "" {
-		query = query.Where("date = ?", date)
+	limit := q.Limit
+	if limit <= 0 {
+		limit = 50
 	}
 
+	var total int64
+	if err := query.Session(&gorm.Session{}).Count(&total).Error; err != nil {
+		return nil, err
+	}
 	var reservations []models.Reservation
-	query.Order("date, time").Find(&reservations)
+	if err := query.Order(order).Offset(offset).Limit(limit).Find(&reservations).Error; err != nil {
+		return nil, err
+	}
 
-	results := make([]dto.ReservationOut, len(reservations))
+	page := &dto.Page[dto.ReservationOut]{
+		Items:         make([]dto.ReservationOut, len(reservations)),
+		NextCursor:    nextOffsetCursor(offset, len(reservations), total),
+		TotalEstimate: total,
+	}
 	for i := range reservations {
-		results[i] = toReservationOut(&reservations[i], r.Name)
+		page.Items[i] = toReservationOut(&reservations[i], r.Name)
+		for _, id := range splitCSV(reservations[i].TableIDs) {
+			if name, ok := tableNames[id]; ok {
+				page.Items[i].Tables = append(page.Items[i].Tables, name)
+			}
+		}
 	}
-	return results
+	return page, nil
 }
 
-// CancelReservation cancels a reservation by ID.
-func CancelReservation(db *gorm.DB, reservationID string) (*dto.ReservationOut, error) {
+// ModifyReservation changes the party size, date, time or special requests
+// of a confirmed reservation, keeping its ID and manage token. The caller
+// must present the manage token or be the owner (ownerID) of its restaurant.
+// A new date, time or party size goes through the same checks as
+// MakeReservation, with the reservation's current seats released first; if
+// the new slot doesn't fit, the booking is left unchanged. The previous
+// details are recorded as a ReservationChange, and any seats the change
+// frees are offered to the waitlist.
+func ModifyReservation(db *gorm.DB, reservationID, manageToken, ownerID string, in dto.ReservationUpdateIn) (*dto.ReservationOut, error) {
 	var res models.Reservation
 	if err := db.First(&res, "id = ?", reservationID).Error; err != nil {
 		return nil, err
 	}
-	res.Status = models.StatusCancelled
-	db.Save(&res)
+	actor, err := authorizeReservation(db, &res, manageToken, ownerID)
+	if err != nil {
+		return nil, err
+	}
 
-	var r models.Restaurant
-	db.First(&r, "id = ?", res.RestaurantID)
+	var out dto.ReservationOut
+	err = withRestaurantLock(db, res.RestaurantID, func(tx *gorm.DB, r *models.Restaurant) error {
+		// Re-read under the lock so a concurrent cancel or change is seen.
+		if err := tx.First(&res, "id = ?", reservationID).Error; err != nil {
+			return err
+		}
+		if res.Status != models.StatusConfirmed {
+			return fmt.Errorf("%w: only confirmed reservations can be changed (this one is %s)", ErrInvalidInput, res.Status)
+		}
+
+		change := models.ReservationChange{
+			ReservationID:           res.ID,
+			ChangedBy:               actor,
+			PreviousPartySize:       res.PartySize,
+			PreviousDate:            res.Date,
+			PreviousTime:            res.Time,
+			PreviousSpecialRequests: res.SpecialRequests,
+			PreviousTableIDs:        res.TableIDs,
+		}
+
+		partySize, date, clock := res.PartySize, res.Date, res.Time
+		if in.PartySize != nil {
+			partySize = *in.PartySize
+		}
+		if in.Date != nil {
+			date = *in.Date
+		}
+		if in.Time != nil {
+			clock = *in.Time
+		}
+		if partySize != res.PartySize || date != res.Date || clock != res.Time {
+			slot, err := validateSlot(tx, r, date, clock, partySize, res.ID)
+			if err != nil {
+				return err
+			}
+			res.PartySize = partySize
+			res.Date = slot.date()
+			res.Time = slot.clock()
+			res.TableIDs = slot.tableIDs()
+		}
+		if in.SpecialRequests != nil {
+			res.SpecialRequests = *in.SpecialRequests
+		}
+
+		if res.PartySize == change.PreviousPartySize && res.Date == change.PreviousDate &&
+			res.Time == change.PreviousTime && res.SpecialRequests == change.PreviousSpecialRequests {
+			out = toReservationOut(&res, r.Name)
+			return nil
+		}
+		if res.Date != change.PreviousDate || res.Time != change.PreviousTime {
+			res.ReminderSentAt = nil
+		}
+		if err := tx.Save(&res).Error; err != nil {
+			return err
+		}
+		if err := tx.Create(&change).Error; err != nil {
+			return err
+		}
+		data := guestMessageData(r, &res)
+		data.Previous = &notify.PreviousBooking{
+			Date:        change.PreviousDate,
+			DisplayDate: notify.DisplayDate(change.PreviousDate),
+			Time:        change.PreviousTime,
+			PartySize:   change.PreviousPartySize,
+		}
+		if err := enqueueNotification(tx, &res, models.NotifyModification, data); err != nil {
+			return err
+		}
+		// A smaller party or a move frees seats at the previous time.
+		if res.PartySize < change.PreviousPartySize || res.Date != change.PreviousDate || res.Time != change.PreviousTime {
+			if err := promoteWaitlist(tx, r, change.PreviousDate); err != nil {
+				return err
+			}
+		}
 
-	out := toReservationOut(&res, r.Name)
+		out = toReservationOut(&res, r.Name)
+		out.Previous = &dto.ReservationChangeOut{
+			PartySize:       change.PreviousPartySize,
+			Date:            change.PreviousDate,
+			Time:            change.PreviousTime,
+			SpecialRequests: change.PreviousSpecialRequests,
+			ChangedBy:       change.ChangedBy,
+			ChangedAt:       change.CreatedAt.Format("2006-01-02T15:04:05Z"),
+		}
+		return enqueueEvent(tx, r.OwnerID, models.EventReservationModified, out)
+	})
+	if err != nil {
+		return nil, err
+	}
 	return &out, nil
 }
 
+// CancelReservation cancels a reservation by ID. The caller must present the
+// reservation's manage token, or be the owner (ownerID) of its restaurant.
+// Only confirmed reservations can be cancelled. The freed capacity is
+// offered to the restaurant's waitlist in the same transaction.
+func CancelReservation(db *gorm.DB, reservationID, manageToken, ownerID string) (*dto.ReservationOut, error) {
+	var res models.Reservation
+	if err := db.First(&res, "id = ?", reservationID).Error; err != nil {
+		return nil, err
+	}
+	actor, err := authorizeReservation(db, &res, manageToken, ownerID)
+	if err != nil {
+		return nil, err
+	}
+	return transitionReservation(db, &res, models.StatusCancelled, actor, ownerID)
+}
+
+// UpdateReservationStatus moves a reservation through the lifecycle on
+// behalf of its restaurant's owner: confirmed to seated, no_show or
+// cancelled, and seated to completed. Other moves fail with
+// ErrInvalidTransition.
+func UpdateReservationStatus(db *gorm.DB, reservationID, ownerID string, status models.ReservationStatus) (*dto.ReservationOut, error) {
+	if !status.Valid() {
+		return nil, fmt.Errorf("%w: unknown status %q", ErrInvalidInput, status)
+	}
+	var res models.Reservation
+	if err := db.First(&res, "id = ?", reservationID).Error; err != nil {
+		return nil, err
+	}
+	if !RestaurantBelongsToOwner(db, res.RestaurantID, ownerID) {
+		return nil, ErrNotAuthorized
+	}
+	return transitionReservation(db, &res, status, actorOwner, ownerID)
+}
+
+// transitionReservation applies a status change under the restaurant lock,
+// promoting waitlisted parties when the change frees the reservation's
+// seats, as a cancellation, no-show or early finish does.
+// res is re-read under the lock so a concurrent change is seen.
+func transitionReservation(db *gorm.DB, res *models.Reservation, status models.ReservationStatus, actor, ownerID string) (*dto.ReservationOut, error) {
+	var out dto.ReservationOut
+	err := withRestaurantLock(db, res.RestaurantID, func(tx *gorm.DB, r *models.Restaurant) error {
+		if err := tx.First(res, "id = ?", res.ID).Error; err != nil {
+			return err
+		}
+		freesSeats := res.Status.HoldsSeats() && !status.HoldsSeats()
+		if err := setStatus(tx, res, status, actor, ownerID); err != nil {
+			return err
+		}
+		out = toReservationOut(res, r.Name)
+		if status == models.StatusCancelled {
+			if err := enqueueEvent(tx, r.OwnerID, models.EventReservationCancelled, out); err != nil {
+				return err
+			}
+			data := guestMessageData(r, res)
+			data.CancelledBy = "guest"
+			if actor == actorOwner {
+				data.CancelledBy = "restaurant"
+			}
+			if err := enqueueNotification(tx, res, models.NotifyCancellation, data); err != nil {
+				return err
+			}
+		}
+		if freesSeats {
+			return promoteWaitlist(tx, r, res.Date)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// ListNoShows returns, for one of an owner's restaurants, how many times
+// each guest has not shown up, most frequent first. Guests are matched by
+// email, then phone, then name. email and phone narrow the result to a
+// single guest.
+func ListNoShows(db *gorm.DB, restaurantID, email, phone string) []dto.GuestNoShowOut {
+	query := db.Where("restaurant_id = ? AND status = ?", restaurantID, models.StatusNoShow)
+	if email != "" {
+		query = query.Where("LOWER(customer_email) = ?", strings.ToLower(email))
+	}
+	if phone != "" {
+		query = query.Where("customer_phone = ?", phone)
+	}
+	var reservations []models.Reservation
+	query.Order("date DESC, time DESC").Find(&reservations)
+
+	var results []dto.GuestNoShowOut
+	index := make(map[string]int)
+	for _, res := range reservations {
+		var key string
+		switch {
+		case res.CustomerEmail != "":
+			key = "email:" + strings.ToLower(res.CustomerEmail)
+		case res.CustomerPhone != "":
+			key = "phone:" + res.CustomerPhone
+		default:
+			key = "name:" + strings.ToLower(res.CustomerName)
+		}
+		if i, ok := index[key]; ok {
+			results[i].NoShows++
+			continue
+		}
+		index[key] = len(results)
+		results = append(results, dto.GuestNoShowOut{
+			CustomerName:   res.CustomerName,
+			CustomerEmail:  res.CustomerEmail,
+			CustomerPhone:  res.CustomerPhone,
+			NoShows:        1,
+			LastNoShowDate: res.Date,
+		})
+	}
+	sort.SliceStable(results, func(i, j int) bool { return results[i].NoShows > results[j].NoShows })
+	if results == nil {
+		results = []dto.GuestNoShowOut{}
+	}
+	return results
+}
+
 // --- Recommendations ---
 
 type scoredRestaurant struct {
@@ -451,8 +1248,10 @@ type scoredRestaurant struct {
 	reasons    []string
 }
 
-// GetRecommendations generates ranked restaurant recommendations.
-func GetRecommendations(db *gorm.DB, cuisine, city, priceRange string, features, dietaryNeeds []string, occasion string, limit int) []dto.RecommendationOut {
+// GetRecommendations generates ranked restaurant recommendations. With
+// excludeAllergens, only restaurants with available dishes free of all of
+// them are recommended, and dietary needs are matched against those dishes.
+func GetRecommendations(db *gorm.DB, cuisine, city, priceRange string, features, dietaryNeeds, excludeAllergens []string, occasion string, limit int) ([]dto.RecommendationOut, error) {
 	query := db.Where("is_active = ?", true)
 	if city != "" {
 		query = query.Where("city LIKE ?", "%"+city+"%")
@@ -461,6 +1260,27 @@ func GetRecommendations(db *gorm.DB, cuisine, city, priceRange string, features,
 	var candidates []models.Restaurant
 	query.Find(&candidates)
 
+	// Compare canonical forms, so "bbq" finds Barbecue and "GF" finds
+	// gluten_free. Values outside the vocabulary simply don't match.
+	idx, _ := loadTaxonomy(db)
+	var allergens []models.TaxonomyTerm
+	if len(excludeAllergens) > 0 {
+		// Unlike the other preferences, a misspelled allergen must not be
+		// ignored.
+		if idx == nil {
+			return nil, fmt.Errorf("failed to load allergens")
+		}
+		var err error
+		if allergens, err = resolveTerms(idx, models.KindAllergen, excludeAllergens); err != nil {
+			return nil, err
+		}
+	}
+	if cuisine != "" {
+		cuisine = canonicalTerm(idx, models.KindCuisine, cuisine)
+	}
+	features = canonicalTerms(idx, models.KindFeature, features)
+	dietaryNeeds = canonicalTerms(idx, models.KindDietary, dietaryNeeds)
+
 	var scored []scoredRestaurant
 
 	for i := range candidates {
@@ -475,7 +1295,7 @@ func GetRecommendations(db *gorm.DB, cuisine, city, priceRange string, features,
 		// Cuisine match
 		if cuisine != "" {
 			for _, c := range rCuisines {
-				if strings.Contains(strings.ToLower(c), strings.ToLower(cuisine)) {
+				if strings.EqualFold(c, cuisine) {
 					score += 0.3
 					reasons = append(reasons, fmt.Sprintf("Serves %s cuisine", cuisine))
 					break
@@ -494,7 +1314,7 @@ func GetRecommendations(db *gorm.DB, cuisine, city, priceRange string, features,
 			var matched []string
 			for _, f := range features {
 				for _, rf := range rFeatures {
-					if strings.Contains(strings.ToLower(rf), strings.ToLower(f)) {
+					if strings.EqualFold(rf, f) {
 						matched = append(matched, f)
 						break
 					}
@@ -506,10 +1326,24 @@ func GetRecommendations(db *gorm.DB, cuisine, city, priceRange string, features,
 			}
 		}
 
-		// Dietary needs — check menu items
+		// Allergens and dietary needs — check menu items
+		var menuItems []models.MenuItem
+		if len(allergens) > 0 || len(dietaryNeeds) > 0 {
+			items := db.Model(&models.MenuItem{}).Where("restaurant_id = ? AND is_available = ?", r.ID, true)
+			withoutAllergens(items, allergens).Find(&menuItems)
+		}
+		if len(allergens) > 0 {
+			if len(menuItems) == 0 {
+				continue
+			}
+			dishes := "dishes"
+			if len(menuItems) == 1 {
+				dishes = "dish"
+			}
+			reasons = append(reasons, fmt.Sprintf("%d %s without %s", len(menuItems), dishes,
+				strings.ReplaceAll(models.TermsCSV(allergens), ",", ", ")))
+		}
 		if len(dietaryNeeds) > 0 {
-			var menuItems []models.MenuItem
-			db.Where("restaurant_id = ? AND is_available = ?", r.ID, true).Find(&menuItems)
 
 			dietMatches := make(map[string]bool)
 			for _, item := range menuItems {
@@ -597,7 +1431,7 @@ func GetRecommendations(db *gorm.DB, cuisine, city, priceRange string, features,
 			RelevanceScore: math.Round(scored[i].score*100) / 100,
 		}
 	}
-	return results
+	return results, nil
 }
 
 // --- Owner Registration ---
@@ -637,16 +1471,36 @@ func RotateAPIKey(db *gorm.DB, ownerID string) (string, error) {
 
 // --- Ownership Helpers ---
 
-// ListOwnerRestaurants returns all restaurants belonging to the given owner.
-func ListOwnerRestaurants(db *gorm.DB, ownerID string) []dto.RestaurantSummary {
+// ListOwnerRestaurants returns a page of the given owner's restaurants,
+// ordered by name.
+func ListOwnerRestaurants(db *gorm.DB, ownerID, cursorParam string, limit int) (*dto.Page[dto.RestaurantSummary], error) {
+	offset, err := startOffset(cursorParam, 0)
+	if err != nil {
+		return nil, err
+	}
+	if limit <= 0 {
+		limit = 50
+	}
+
+	query := db.Model(&models.Restaurant{}).Where("owner_id = ? AND is_active = ?", ownerID, true)
+	var total int64
+	if err := query.Session(&gorm.Session{}).Count(&total).Error; err != nil {
+		return nil, err
+	}
 	var restaurants []models.Restaurant
-	db.Where("owner_id = ? AND is_active = ?", ownerID, true).
-		Order("name ASC").Find(&restaurants)
-	results := make([]dto.RestaurantSummary, len(restaurants))
+	if err := query.Order("name ASC, id ASC").Offset(offset).Limit(limit).Find(&restaurants).Error; err != nil {
+		return nil, err
+	}
+
+	page := &dto.Page[dto.RestaurantSummary]{
+		Items:         make([]dto.RestaurantSummary, len(restaurants)),
+		NextCursor:    nextOffsetCursor(offset, len(restaurants), total),
+		TotalEstimate: total,
+	}
 	for i := range restaurants {
-		results[i] = toSummary(&restaurants[i])
+		page.Items[i] = toSummary(&restaurants[i])
 	}
-	return results
+	return page, nil
 }
 
 // RestaurantBelongsToOwner checks if the owner owns the restaurant.
@@ -661,34 +1515,53 @@ func CreateRestaurantForOwner(db *gorm.DB, ownerID string, in dto.RestaurantIn)
 	if err := checkDuplicateRestaurant(db, in.Name, in.City); err != nil {
 		return nil, err
 	}
+	cuisines, features, err := restaurantTerms(db, in)
+	if err != nil {
+		return nil, err
+	}
 	r := models.Restaurant{
-		ID:          models.NewID(),
-		OwnerID:     ownerID,
-		Name:        in.Name,
-		Description: in.Description,
-		Cuisines:    joinCSV(in.Cuisines),
-		PriceRange:  models.PriceRange(in.PriceRange),
-		Address:     in.Address,
-		City:        in.City,
-		State:       in.State,
-		ZipCode:     in.ZipCode,
-		Country:     in.Country,
-		Latitude:    in.Latitude,
-		Longitude:   in.Longitude,
-		Phone:       in.Phone,
-		Email:       in.Email,
-		Website:     in.Website,
-		Features:    joinCSV(in.Features),
-		TotalSeats:  in.TotalSeats,
-		IsActive:    true,
+		ID:                 models.NewID(),
+		OwnerID:            ownerID,
+		Name:               in.Name,
+		Description:        in.Description,
+		Cuisines:           models.TermsCSV(cuisines),
+		PriceRange:         models.PriceRange(in.PriceRange),
+		Address:            in.Address,
+		City:               in.City,
+		State:              in.State,
+		ZipCode:            in.ZipCode,
+		Country:            in.Country,
+		Latitude:           in.Latitude,
+		Longitude:          in.Longitude,
+		Phone:              in.Phone,
+		Email:              in.Email,
+		Website:            in.Website,
+		Features:           models.TermsCSV(features),
+		TotalSeats:         in.TotalSeats,
+		LastSeatingMinutes: in.LastSeatingMinutes,
+		TurnMinutes:        in.TurnMinutes,
+		IsActive:           true,
+		CuisineTerms:       cuisines,
+		FeatureTerms:       features,
 	}
 
 	if r.Country == "" {
 		r.Country = "US"
 	}
+	tz, err := resolveTimezone(in.Timezone, r.Country, r.State)
+	if err != nil {
+		return nil, err
+	}
+	r.Timezone = tz
 	if r.TotalSeats == 0 {
 		r.TotalSeats = 50
 	}
+	if r.LastSeatingMinutes <= 0 {
+		r.LastSeatingMinutes = defaultLastSeatingMinutes
+	}
+	if r.TurnMinutes <= 0 {
+		r.TurnMinutes = defaultTurnMinutes
+	}
 	if r.PriceRange == "" {
 		r.PriceRange = models.PriceModerate
 	}
@@ -704,10 +1577,12 @@ func CreateRestaurantForOwner(db *gorm.DB, ownerID string, in dto.RestaurantIn)
 		}
 	}
 	r.Hours = hours
+	r.TurnTimes = toTurnTimes(r.ID, in.TurnTimes)
 
 	if err := db.Create(&r).Error; err != nil {
 		return nil, err
 	}
+	reindex(db, r.ID)
 
 	detail := toDetail(&r)
 	return &detail, nil
@@ -715,9 +1590,89 @@ func CreateRestaurantForOwner(db *gorm.DB, ownerID string, in dto.RestaurantIn)
 
 // --- Bulk Menu Import ---
 
+// Bulk import row actions.
+const (
+	importCreated   = "created"
+	importUpdated   = "updated"
+	importUnchanged = "unchanged"
+	importRemoved   = "removed"
+	importError     = "error"
+)
+
+// importKey identifies a menu item by category and name, ignoring case.
+func importKey(category, name string) string {
+	return strings.ToLower(category) + "\x00" + strings.ToLower(name)
+}
+
+// importSource describes where bulk import items came from.
+type importSource struct {
+	rows    []int           // row number to report for each item; nil numbers them from 1
+	omitted map[string]bool // fields the source doesn't have, which matched items keep
+}
+
+// keepOmitted restores the fields of an updated item that the import
+// didn't supply.
+func keepOmitted(m, previous *models.MenuItem, omitted map[string]bool) {
+	if omitted["category"] {
+		m.Category = previous.Category
+	}
+	if omitted["description"] {
+		m.Description = previous.Description
+	}
+	if omitted["currency"] {
+		m.Currency = previous.Currency
+	}
+	if omitted["dietary_labels"] {
+		m.DietaryLabels = previous.DietaryLabels
+	}
+	if omitted["is_popular"] {
+		m.IsPopular = previous.IsPopular
+	}
+	if omitted["image_url"] {
+		m.ImageURL = previous.ImageURL
+	}
+	if omitted["calories"] {
+		m.Calories = previous.Calories
+	}
+	if omitted["allergens"] {
+		m.Allergens = previous.Allergens
+	}
+	if omitted["may_contain_allergens"] {
+		m.MayContainAllergens = previous.MayContainAllergens
+	}
+	if (omitted["allergens"] || omitted["may_contain_allergens"]) && previous.AllergensDeclared {
+		m.AllergensDeclared = true
+	}
+	if omitted["option_groups"] {
+		m.OptionGroups = previous.OptionGroups
+	}
+}
+
+// importedItem is a planned change to one menu item.
+type importedItem struct {
+	item       models.MenuItem
+	dietary    []models.TaxonomyTerm
+	allergens  []models.TaxonomyTerm
+	mayContain []models.TaxonomyTerm
+	groups     []models.MenuOptionGroup
+	create     bool
+	changes    []string
+	report     int // index of its row in the report
+}
+
 // BulkImportMenu imports menu items for a restaurant.
-// Strategy "replace" deletes all existing items first. "merge" appends.
+// Strategy "replace" deletes all existing items first. "merge" updates the
+// items that match by external ID, or else by category and name, adds the
+// rest, and with RemoveMissing deletes items the payload leaves out. Every
+// row is checked before anything changes; if any has an error, or with
+// DryRun, the report is returned and the menu is left as it was.
 func BulkImportMenu(db *gorm.DB, restaurantID string, in dto.BulkMenuImportIn) (*dto.BulkMenuImportOut, error) {
+	return bulkImportMenu(db, restaurantID, in, importSource{})
+}
+
+// bulkImportMenu is BulkImportMenu for items from src. When src omits the
+// category, merge matches items by name alone.
+func bulkImportMenu(db *gorm.DB, restaurantID string, in dto.BulkMenuImportIn, src importSource) (*dto.BulkMenuImportOut, error) {
 	var r models.Restaurant
 	if err := db.First(&r, "id = ?", restaurantID).Error; err != nil {
 		return nil, fmt.Errorf("restaurant not found")
@@ -727,37 +1682,288 @@ func BulkImportMenu(db *gorm.DB, restaurantID string, in dto.BulkMenuImportIn) (
 	if strategy == "" {
 		strategy = "replace"
 	}
+	if strategy != "replace" && strategy != "merge" {
+		return nil, fmt.Errorf("%w: unknown strategy %q (use replace or merge)", ErrInvalidInput, strategy)
+	}
+	merge := strategy == "merge"
+	key := importKey
+	if src.omitted["category"] {
+		key = func(_, name string) string { return importKey("", name) }
+	}
 
-	err := db.Transaction(func(tx *gorm.DB) error {
-		if strategy == "replace" {
-			if err := tx.Where("restaurant_id = ?", restaurantID).Delete(&models.MenuItem{}).Error; err != nil {
-				return fmt.Errorf("failed to clear existing menu: %w", err)
+	idx, err := loadTaxonomy(db)
+	if err != nil {
+		return nil, err
+	}
+	var existing []models.MenuItem
+	if err := preloadOptions(db).Where("restaurant_id = ?", restaurantID).
+		Order("category, sort_order, name").Find(&existing).Error; err != nil {
+		return nil, err
+	}
+
+	// Index the current menu for matching, and find where each category
+	// ends so new items go after it.
+	byExternalID := make(map[string]*models.MenuItem)
+	byName := make(map[string][]*models.MenuItem)
+	nextOrder := make(map[string]int)
+	if merge {
+		for i := range existing {
+			m := &existing[i]
+			if m.ExternalID != "" {
+				byExternalID[m.ExternalID] = m
+			}
+			k := key(m.Category, m.Name)
+			byName[k] = append(byName[k], m)
+			if m.SortOrder >= nextOrder[m.Category] {
+				nextOrder[m.Category] = m.SortOrder + 1
 			}
 		}
+	}
+
+	out := &dto.BulkMenuImportOut{
+		RestaurantID: restaurantID,
+		Strategy:     strategy,
+		DryRun:       in.DryRun,
+		Rows:         make([]dto.MenuImportRowOut, 0, len(in.Items)),
+	}
+	planned := make([]*importedItem, len(in.Items))
+	claimed := make(map[string]int)        // existing item ID -> row
+	seenExternalID := make(map[string]int) // external ID -> row
+	seenName := make(map[string]int)       // importKey -> row
+	for i, item := range in.Items {
+		row := i + 1
+		if src.rows != nil {
+			row = src.rows[i]
+		}
+		externalID := strings.TrimSpace(item.ExternalID)
+		category := strings.TrimSpace(item.Category)
+		if category == "" {
+			category = "Main"
+		}
+		name := strings.TrimSpace(item.Name)
+		report := dto.MenuImportRowOut{Row: row, ExternalID: externalID, Category: category, Name: name}
+		fail := func(err error) {
+			report.Action = importError
+			report.Error = strings.TrimPrefix(err.Error(), ErrInvalidInput.Error()+": ")
+			out.Errors++
+			out.Rows = append(out.Rows, report)
+		}
 
-		for _, item := range in.Items {
-			m := models.MenuItem{
-				ID:            models.NewID(),
-				RestaurantID:  restaurantID,
-				Category:      item.Category,
-				Name:          item.Name,
-				Description:   item.Description,
-				Price:         item.Price,
-				Currency:      item.Currency,
-				DietaryLabels: joinCSV(item.DietaryLabels),
-				IsAvailable:   item.IsAvailable,
-				IsPopular:     item.IsPopular,
-				ImageURL:      item.ImageURL,
-				Calories:      item.Calories,
+		if err := validateMenuItem(name, item.Price); err != nil {
+			fail(err)
+			continue
+		}
+		if len(externalID) > maxExternalID {
+			fail(fmt.Errorf("external_id must be at most %d characters", maxExternalID))
+			continue
+		}
+		dietary, err := resolveTerms(idx, models.KindDietary, item.DietaryLabels)
+		if err != nil {
+			fail(err)
+			continue
+		}
+		nameKey := key(category, name)
+		if prev, ok := seenExternalID[externalID]; ok && externalID != "" {
+			fail(fmt.Errorf("external_id %q is also used by row %d", externalID, prev))
+			continue
+		}
+		if prev, ok := seenName[nameKey]; ok && externalID == "" {
+			fail(fmt.Errorf("%s / %s is also in row %d; give the items distinct external IDs", category, name, prev))
+			continue
+		}
+		if externalID != "" {
+			seenExternalID[externalID] = row
+		} else {
+			seenName[nameKey] = row
+		}
+
+		var match *models.MenuItem
+		if merge {
+			if externalID != "" {
+				match = byExternalID[externalID]
 			}
-			if m.Currency == "" {
-				m.Currency = "USD"
+			if match == nil {
+				for _, m := range byName[nameKey] {
+					if _, taken := claimed[m.ID]; !taken && (externalID == "" || m.ExternalID == "") {
+						match = m
+						break
+					}
+				}
+			}
+		}
+		if match != nil {
+			if prev, taken := claimed[match.ID]; taken {
+				fail(fmt.Errorf("matches the same menu item as row %d", prev))
+				continue
+			}
+			claimed[match.ID] = row
+		}
+
+		allergens, mayContain, err := resolveAllergens(idx, item.Allergens, item.MayContainAllergens)
+		if err != nil {
+			fail(err)
+			continue
+		}
+		p := &importedItem{dietary: dietary, allergens: allergens, mayContain: mayContain}
+		if match == nil {
+			p.create = true
+			p.item = models.MenuItem{ID: models.NewID(), RestaurantID: restaurantID}
+		} else {
+			p.item = *match
+		}
+		if p.groups, err = buildOptionGroups(idx, p.item.ID, item.OptionGroups); err != nil {
+			fail(err)
+			continue
+		}
+		m := &p.item
+		previous := *m
+		if externalID != "" {
+			m.ExternalID = externalID
+		}
+		m.Category = category
+		m.Name = name
+		m.Description = item.Description
+		m.Price = item.Price
+		m.Currency = item.Currency
+		if m.Currency == "" {
+			m.Currency = "USD"
+		}
+		m.DietaryLabels = models.TermsCSV(dietary)
+		// An omitted is_available keeps the matched item's value, and new
+		// items start out available.
+		switch {
+		case item.IsAvailable != nil:
+			m.IsAvailable = *item.IsAvailable
+		case p.create:
+			m.IsAvailable = true
+		}
+		m.IsPopular = item.IsPopular
+		m.ImageURL = item.ImageURL
+		m.Calories = item.Calories
+		m.Allergens = models.TermsCSV(allergens)
+		m.MayContainAllergens = models.TermsCSV(mayContain)
+		m.AllergensDeclared = item.Allergens != nil || item.MayContainAllergens != nil
+		m.OptionGroups = p.groups
+		if !p.create {
+			keepOmitted(m, &previous, src.omitted)
+			// A kept allergen list can clash with the new one.
+			if p.allergens, p.mayContain, err = resolveAllergens(idx, splitCSV(m.Allergens), splitCSV(m.MayContainAllergens)); err != nil {
+				fail(err)
+				continue
+			}
+		}
+		switch {
+		case item.SortOrder != nil:
+			m.SortOrder = *item.SortOrder
+		case p.create || m.Category != previous.Category:
+			if nextOrder[m.Category] == 0 {
+				nextOrder[m.Category] = 1
 			}
-			if m.Category == "" {
-				m.Category = "Main"
+			m.SortOrder = nextOrder[m.Category]
+			nextOrder[m.Category]++
+		}
+
+		report.Category = m.Category
+		report.Action = importCreated
+		if !p.create {
+			p.changes = menuItemChanges(&previous, m)
+			report.ItemID = m.ID
+			report.Changes = p.changes
+			report.Action = importUpdated
+			if len(p.changes) == 0 {
+				report.Action = importUnchanged
 			}
-			if err := tx.Create(&m).Error; err != nil {
-				return fmt.Errorf("failed to import item %q: %w", item.Name, err)
+		}
+		p.report = len(out.Rows)
+		planned[i] = p
+		out.Rows = append(out.Rows, report)
+	}
+
+	// Items the import deletes: the whole menu for replace, and with
+	// RemoveMissing, whatever a merge didn't match.
+	var removed []string
+	if !merge || in.RemoveMissing {
+		for _, m := range existing {
+			if _, kept := claimed[m.ID]; kept {
+				continue
+			}
+			removed = append(removed, m.ID)
+			out.Rows = append(out.Rows, dto.MenuImportRowOut{
+				Action:     importRemoved,
+				ItemID:     m.ID,
+				ExternalID: m.ExternalID,
+				Category:   m.Category,
+				Name:       m.Name,
+			})
+		}
+	}
+	for _, row := range out.Rows {
+		switch row.Action {
+		case importCreated:
+			out.Created++
+		case importUpdated:
+			out.Updated++
+		case importUnchanged:
+			out.Unchanged++
+		case importRemoved:
+			out.Removed++
+		}
+	}
+	if out.Errors > 0 || in.DryRun {
+		return out, nil
+	}
+
+	err = db.Transaction(func(tx *gorm.DB) error {
+		if len(removed) > 0 {
+			if err := deleteMenuItemLinks(tx, removed); err != nil {
+				return fmt.Errorf("failed to remove menu items: %w", err)
+			}
+			if err := tx.Where("id IN ?", removed).Delete(&models.MenuItem{}).Error; err != nil {
+				return fmt.Errorf("failed to remove menu items: %w", err)
+			}
+		}
+		for _, p := range planned {
+			switch {
+			case p.create:
+				p.item.DietaryTerms = p.dietary
+				p.item.AllergenTerms = p.allergens
+				p.item.MayContainTerms = p.mayContain
+				available := p.item.IsAvailable
+				if err := tx.Create(&p.item).Error; err != nil {
+					return fmt.Errorf("failed to import item %q: %w", p.item.Name, err)
+				}
+				// gorm skips zero values for fields with a default, and fills
+				// the default back in, so persist an unavailable item with a
+				// follow-up update.
+				if !available {
+					if err := tx.Model(&p.item).Update("is_available", false).Error; err != nil {
+						return fmt.Errorf("failed to import item %q: %w", p.item.Name, err)
+					}
+				}
+			case len(p.changes) > 0:
+				if slices.Contains(p.changes, "dietary_labels") {
+					if err := tx.Model(&p.item).Association("DietaryTerms").Replace(p.dietary); err != nil {
+						return fmt.Errorf("failed to import item %q: %w", p.item.Name, err)
+					}
+				}
+				if slices.Contains(p.changes, "allergens") {
+					if err := tx.Model(&p.item).Association("AllergenTerms").Replace(p.allergens); err != nil {
+						return fmt.Errorf("failed to import item %q: %w", p.item.Name, err)
+					}
+				}
+				if slices.Contains(p.changes, "may_contain_allergens") {
+					if err := tx.Model(&p.item).Association("MayContainTerms").Replace(p.mayContain); err != nil {
+						return fmt.Errorf("failed to import item %q: %w", p.item.Name, err)
+					}
+				}
+				if slices.Contains(p.changes, "option_groups") {
+					if err := replaceOptionGroups(tx, p.item.ID, p.item.OptionGroups); err != nil {
+						return fmt.Errorf("failed to import item %q: %w", p.item.Name, err)
+					}
+				}
+				if err := tx.Omit("OptionGroups").Save(&p.item).Error; err != nil {
+					return fmt.Errorf("failed to import item %q: %w", p.item.Name, err)
+				}
 			}
 		}
 		return nil
@@ -765,10 +1971,42 @@ func BulkImportMenu(db *gorm.DB, restaurantID string, in dto.BulkMenuImportIn) (
 	if err != nil {
 		return nil, err
 	}
+	reindex(db, restaurantID)
 
-	return &dto.BulkMenuImportOut{
-		RestaurantID: restaurantID,
-		Imported:     len(in.Items),
-		Strategy:     strategy,
-	}, nil
+	for _, p := range planned {
+		if p.create {
+			out.Rows[p.report].ItemID = p.item.ID
+		}
+	}
+	out.Imported = out.Created + out.Updated
+	return out, nil
+}
+
+// menuItemChanges lists the JSON names of the fields that differ between
+// two versions of a menu item.
+func menuItemChanges(before, after *models.MenuItem) []string {
+	var changes []string
+	diff := func(field string, changed bool) {
+		if changed {
+			changes = append(changes, field)
+		}
+	}
+	diff("external_id", before.ExternalID != after.ExternalID)
+	diff("category", before.Category != after.Category)
+	diff("name", before.Name != after.Name)
+	diff("description", before.Description != after.Description)
+	diff("price", before.Price != after.Price)
+	diff("currency", before.Currency != after.Currency)
+	diff("dietary_labels", before.DietaryLabels != after.DietaryLabels)
+	diff("is_available", before.IsAvailable != after.IsAvailable)
+	diff("is_popular", before.IsPopular != after.IsPopular)
+	diff("image_url", before.ImageURL != after.ImageURL)
+	diff("calories", (before.Calories == nil) != (after.Calories == nil) ||
+		before.Calories != nil && *before.Calories != *after.Calories)
+	diff("sort_order", before.SortOrder != after.SortOrder)
+	diff("allergens", before.Allergens != after.Allergens)
+	diff("may_contain_allergens", before.MayContainAllergens != after.MayContainAllergens)
+	diff("allergens_declared", before.AllergensDeclared != after.AllergensDeclared)
+	diff("option_groups", !sameOptionGroups(before.OptionGroups, after.OptionGroups))
+	return changes
 }
@@ -0,0 +1,59 @@
+package services
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/agenteats/agenteats/internal/config"
+	"github.com/agenteats/agenteats/internal/database"
+	"github.com/agenteats/agenteats/internal/dto"
+)
+
+// openTestDB sets up a migrated, empty database for one test, the way the
+// API server does at startup.
+func openTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	database.Init(&config.Config{DatabaseURL: filepath.Join(t.TempDir(), "test.db")})
+	return database.DB
+}
+
+// createTestRestaurant creates a restaurant with in's details, filling in
+// the required ones it leaves out. It is in UTC unless in says otherwise,
+// and without hours it is open 11:00-23:00 every day.
+func createTestRestaurant(t *testing.T, db *gorm.DB, in dto.RestaurantIn) *dto.RestaurantDetail {
+	t.Helper()
+	if in.Name == "" {
+		in.Name = "Trattoria " + t.Name()
+	}
+	if in.City == "" {
+		in.City = "Boston"
+	}
+	if in.Address == "" {
+		in.Address = "1 Main St"
+	}
+	if in.TotalSeats == 0 {
+		in.TotalSeats = 40
+	}
+	if in.Timezone == "" {
+		in.Timezone = "UTC"
+	}
+	if in.Hours == nil {
+		for _, day := range []string{"monday", "tuesday", "wednesday", "thursday", "friday", "saturday", "sunday"} {
+			in.Hours = append(in.Hours, dto.OperatingHoursIn{Day: day, OpenTime: "11:00", CloseTime: "23:00"})
+		}
+	}
+	r, err := CreateRestaurant(db, in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return r
+}
+
+// daysFromNow returns the date n days from today in UTC, which test
+// restaurants use.
+func daysFromNow(n int) string {
+	return time.Now().UTC().AddDate(0, 0, n).Format("2006-01-02")
+}
@@ -0,0 +1,261 @@
+package services
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"gorm.io/gorm"
+
+	"github.com/agenteats/agenteats/internal/dto"
+	"github.com/agenteats/agenteats/internal/models"
+)
+
+// maxCombinedTables is the most tables that will be pushed together for a
+// single party.
+const maxCombinedTables = 3
+
+func toTableOut(t *models.DiningTable) dto.TableOut {
+	return dto.TableOut{
+		ID:           t.ID,
+		Name:         t.Name,
+		MinCapacity:  t.MinCapacity,
+		MaxCapacity:  t.MaxCapacity,
+		Section:      t.Section,
+		IsCombinable: t.IsCombinable,
+		IsActive:     t.IsActive,
+	}
+}
+
+func validateTable(in dto.TableIn) error {
+	if strings.TrimSpace(in.Name) == "" {
+		return fmt.Errorf("%w: name is required", ErrInvalidInput)
+	}
+	if in.MaxCapacity < 1 {
+		return fmt.Errorf("%w: max_capacity must be at least 1", ErrInvalidInput)
+	}
+	if in.MinCapacity > in.MaxCapacity {
+		return fmt.Errorf("%w: min_capacity cannot exceed max_capacity", ErrInvalidInput)
+	}
+	return nil
+}
+
+func applyTableIn(t *models.DiningTable, in dto.TableIn) {
+	t.Name = strings.TrimSpace(in.Name)
+	t.MinCapacity = in.MinCapacity
+	t.MaxCapacity = in.MaxCapacity
+	t.Section = in.Section
+	t.IsCombinable = in.IsCombinable
+	if t.MinCapacity < 1 {
+		t.MinCapacity = 1
+	}
+	if in.IsActive != nil {
+		t.IsActive = *in.IsActive
+	}
+}
+
+// --- Table Management ---
+
+// ListTables returns all tables for a restaurant, ordered by section and name.
+func ListTables(db *gorm.DB, restaurantID string) []dto.TableOut {
+	var tables []models.DiningTable
+	db.Where("restaurant_id = ?", restaurantID).Order("section, name").Find(&tables)
+	results := make([]dto.TableOut, len(tables))
+	for i := range tables {
+		results[i] = toTableOut(&tables[i])
+	}
+	return results
+}
+
+// CreateTable adds a table to a restaurant's floor plan.
+func CreateTable(db *gorm.DB, restaurantID string, in dto.TableIn) (*dto.TableOut, error) {
+	if err := validateTable(in); err != nil {
+		return nil, err
+	}
+	var r models.Restaurant
+	if err := db.First(&r, "id = ?", restaurantID).Error; err != nil {
+		return nil, err
+	}
+
+	t := models.DiningTable{
+		ID:           models.NewID(),
+		RestaurantID: restaurantID,
+		IsActive:     true,
+	}
+	applyTableIn(&t, in)
+	if err := db.Create(&t).Error; err != nil {
+		return nil, err
+	}
+	// gorm skips zero values for fields with a default, so persist an
+	// explicitly inactive table with a follow-up update.
+	if !t.IsActive {
+		db.Model(&t).Update("is_active", false)
+	}
+
+	out := toTableOut(&t)
+	return &out, nil
+}
+
+// UpdateTable replaces a table's attributes.
+func UpdateTable(db *gorm.DB, restaurantID, tableID string, in dto.TableIn) (*dto.TableOut, error) {
+	if err := validateTable(in); err != nil {
+		return nil, err
+	}
+	var t models.DiningTable
+	if err := db.First(&t, "id = ? AND restaurant_id = ?", tableID, restaurantID).Error; err != nil {
+		return nil, err
+	}
+	applyTableIn(&t, in)
+	if err := db.Save(&t).Error; err != nil {
+		return nil, err
+	}
+	out := toTableOut(&t)
+	return &out, nil
+}
+
+// DeleteTable removes a table. Existing reservations keep their assignment
+// history, but the table is no longer offered for new bookings.
+func DeleteTable(db *gorm.DB, restaurantID, tableID string) error {
+	result := db.Where("id = ? AND restaurant_id = ?", tableID, restaurantID).Delete(&models.DiningTable{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// --- Table Assignment ---
+
+// bestFit picks the tables for a party from the free ones: the smallest
+// single table whose capacity range covers the party, or failing that the
+// combination of up to maxCombinedTables combinable tables in one section
+// with the fewest spare seats. It returns nil if nothing fits.
+func bestFit(free []models.DiningTable, partySize int) []models.DiningTable {
+	var single *models.DiningTable
+	for i := range free {
+		t := &free[i]
+		if partySize < t.MinCapacity || partySize > t.MaxCapacity {
+			continue
+		}
+		if single == nil || t.MaxCapacity < single.MaxCapacity {
+			single = t
+		}
+	}
+	if single != nil {
+		return []models.DiningTable{*single}
+	}
+
+	sections := make(map[string][]models.DiningTable)
+	for _, t := range free {
+		if t.IsCombinable {
+			sections[t.Section] = append(sections[t.Section], t)
+		}
+	}
+
+	var best []models.DiningTable
+	bestSeats := 0
+	var search func(group, picked []models.DiningTable, from, seats int)
+	search = func(group, picked []models.DiningTable, from, seats int) {
+		if len(picked) >= 2 && seats >= partySize {
+			if best == nil || seats < bestSeats || (seats == bestSeats && len(picked) < len(best)) {
+				best = append([]models.DiningTable(nil), picked...)
+				bestSeats = seats
+			}
+			return
+		}
+		if len(picked) == maxCombinedTables {
+			return
+		}
+		for i := from; i < len(group); i++ {
+			search(group, append(picked, group[i]), i+1, seats+group[i].MaxCapacity)
+		}
+	}
+	for _, group := range sections {
+		sort.Slice(group, func(i, j int) bool { return group[i].MaxCapacity > group[j].MaxCapacity })
+		search(group, nil, 0, 0)
+	}
+	return best
+}
+
+// maxTablePartySize returns the largest party the restaurant's tables can
+// seat, either at one table or by combining tables within a section.
+func maxTablePartySize(tables []models.DiningTable) int {
+	largest := 0
+	sections := make(map[string][]int)
+	for _, t := range tables {
+		if t.MaxCapacity > largest {
+			largest = t.MaxCapacity
+		}
+		if t.IsCombinable {
+			sections[t.Section] = append(sections[t.Section], t.MaxCapacity)
+		}
+	}
+	for _, caps := range sections {
+		if len(caps) < 2 {
+			continue
+		}
+		sort.Sort(sort.Reverse(sort.IntSlice(caps)))
+		sum := 0
+		for i := 0; i < len(caps) && i < maxCombinedTables; i++ {
+			sum += caps[i]
+		}
+		if sum > largest {
+			largest = sum
+		}
+	}
+	return largest
+}
+
+// maxPartySize returns the largest party the restaurant can seat.
+func maxPartySize(r *models.Restaurant) int {
+	if len(r.Tables) > 0 {
+		return maxTablePartySize(r.Tables)
+	}
+	return r.TotalSeats
+}
+
+// assignTables returns the tables a party would be seated at if it arrived
+// at start, given the bookings already on the books. Overlapping bookings
+// that already hold tables keep them; older bookings made before any tables
+// were set up are seated first by best fit so they still consume inventory.
+func assignTables(r *models.Restaurant, bookings []booking, start, partySize int) []models.DiningTable {
+	end := start + turnMinutes(r, partySize)
+
+	busy := make(map[string]bool)
+	var unassigned []booking
+	for _, b := range bookings {
+		if b.start >= end || b.end <= start {
+			continue
+		}
+		if len(b.tableIDs) == 0 {
+			unassigned = append(unassigned, b)
+			continue
+		}
+		for _, id := range b.tableIDs {
+			busy[id] = true
+		}
+	}
+
+	free := make([]models.DiningTable, 0, len(r.Tables))
+	for _, t := range r.Tables {
+		if !busy[t.ID] {
+			free = append(free, t)
+		}
+	}
+
+	sort.Slice(unassigned, func(i, j int) bool { return unassigned[i].seats > unassigned[j].seats })
+	for _, b := range unassigned {
+		for _, t := range bestFit(free, b.seats) {
+			for i := range free {
+				if free[i].ID == t.ID {
+					free = append(free[:i], free[i+1:]...)
+					break
+				}
+			}
+		}
+	}
+
+	return bestFit(free, partySize)
+}
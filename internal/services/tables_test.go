@@ -0,0 +1,99 @@
+package services
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/agenteats/agenteats/internal/models"
+)
+
+func TestBestFit(t *testing.T) {
+	table := func(name, section string, min, max int, combinable bool) models.DiningTable {
+		return models.DiningTable{ID: name, Name: name, Section: section, MinCapacity: min, MaxCapacity: max, IsCombinable: combinable}
+	}
+	floor := []models.DiningTable{
+		table("T2", "main", 1, 2, false),
+		table("T4a", "main", 2, 4, true),
+		table("T4b", "main", 2, 4, true),
+		table("T6", "patio", 4, 6, false),
+		table("P2", "patio", 1, 2, true),
+	}
+
+	tests := []struct {
+		name  string
+		free  []models.DiningTable
+		party int
+		want  []string
+	}{
+		{name: "smallest table that fits", free: floor, party: 2, want: []string{"T2"}},
+		{name: "next size up", free: floor, party: 3, want: []string{"T4a"}},
+		{name: "respects minimum capacity", free: floor[3:], party: 1, want: []string{"P2"}},
+		{name: "large single table", free: floor, party: 5, want: []string{"T6"}},
+		{name: "combines within a section", free: floor, party: 8, want: []string{"T4a", "T4b"}},
+		{name: "taken table is skipped", free: floor[1:], party: 2, want: []string{"P2"}},
+		{name: "nothing big enough", free: floor, party: 20, want: nil},
+		{
+			name: "fewest spare seats",
+			free: []models.DiningTable{
+				table("A4a", "a", 1, 4, true),
+				table("A4b", "a", 1, 4, true),
+				table("A2", "a", 1, 2, true),
+			},
+			party: 6,
+			want:  []string{"A4a", "A2"},
+		},
+		{
+			name: "never across sections",
+			free: []models.DiningTable{
+				table("A4", "a", 1, 4, true),
+				table("B4", "b", 1, 4, true),
+			},
+			party: 8,
+			want:  nil,
+		},
+		{
+			name: "only combinable tables",
+			free: []models.DiningTable{
+				table("A4", "a", 1, 4, true),
+				table("A4x", "a", 1, 4, false),
+			},
+			party: 8,
+			want:  nil,
+		},
+		{
+			name: "at most three tables",
+			free: []models.DiningTable{
+				table("A2a", "a", 1, 2, true),
+				table("A2b", "a", 1, 2, true),
+				table("A2c", "a", 1, 2, true),
+				table("A2d", "a", 1, 2, true),
+			},
+			party: 7,
+			want:  nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, tbl := range bestFit(slices.Clone(tt.free), tt.party) {
+				got = append(got, tbl.Name)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Fatalf("bestFit(party of %d) = %v, want %v", tt.party, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMaxTablePartySize(t *testing.T) {
+	tables := []models.DiningTable{
+		{Section: "main", MaxCapacity: 4, IsCombinable: true},
+		{Section: "main", MaxCapacity: 4, IsCombinable: true},
+		{Section: "main", MaxCapacity: 2, IsCombinable: true},
+		{Section: "main", MaxCapacity: 2, IsCombinable: true},
+		{Section: "patio", MaxCapacity: 8},
+	}
+	if got := maxTablePartySize(tables); got != 10 {
+		t.Fatalf("maxTablePartySize = %d, want 10", got)
+	}
+}
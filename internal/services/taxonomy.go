@@ -0,0 +1,167 @@
+package services
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"gorm.io/gorm"
+
+	"github.com/agenteats/agenteats/internal/dto"
+	"github.com/agenteats/agenteats/internal/models"
+)
+
+// GetTaxonomy lists every cuisine, feature, dietary label and allergen
+// with its accepted synonyms, sorted by slug.
+func GetTaxonomy(db *gorm.DB) (*dto.TaxonomyOut, error) {
+	var terms []models.TaxonomyTerm
+	if err := db.Preload("Synonyms").Order("kind, slug").Find(&terms).Error; err != nil {
+		return nil, err
+	}
+	out := &dto.TaxonomyOut{
+		Cuisines:      []dto.TaxonomyTermOut{},
+		Features:      []dto.TaxonomyTermOut{},
+		DietaryLabels: []dto.TaxonomyTermOut{},
+		Allergens:     []dto.TaxonomyTermOut{},
+	}
+	for _, t := range terms {
+		synonyms := make([]string, 0, len(t.Synonyms))
+		for _, s := range t.Synonyms {
+			if s.Key != models.TaxonomyKey(t.Label) {
+				synonyms = append(synonyms, s.Key)
+			}
+		}
+		sort.Strings(synonyms)
+		term := dto.TaxonomyTermOut{Slug: t.Slug, Label: t.Label, Synonyms: synonyms}
+		switch t.Kind {
+		case models.KindCuisine:
+			out.Cuisines = append(out.Cuisines, term)
+		case models.KindFeature:
+			out.Features = append(out.Features, term)
+		case models.KindDietary:
+			out.DietaryLabels = append(out.DietaryLabels, term)
+		case models.KindAllergen:
+			out.Allergens = append(out.Allergens, term)
+		}
+	}
+	return out, nil
+}
+
+// loadTaxonomy reads the vocabularies for resolving input.
+func loadTaxonomy(db *gorm.DB) (*models.TaxonomyIndex, error) {
+	var terms []models.TaxonomyTerm
+	if err := db.Preload("Synonyms").Find(&terms).Error; err != nil {
+		return nil, err
+	}
+	return models.NewTaxonomyIndex(terms), nil
+}
+
+// termNoun names a vocabulary in error messages.
+func termNoun(kind models.TaxonomyKind) string {
+	if kind == models.KindDietary {
+		return "dietary label"
+	}
+	return string(kind)
+}
+
+// resolveTerm returns the term value names, or ErrInvalidInput.
+func resolveTerm(idx *models.TaxonomyIndex, kind models.TaxonomyKind, value string) (*models.TaxonomyTerm, error) {
+	term := idx.Lookup(kind, value)
+	if term == nil {
+		return nil, fmt.Errorf("%w: unknown %s %q (GET /taxonomy lists accepted values)", ErrInvalidInput, termNoun(kind), value)
+	}
+	return term, nil
+}
+
+// resolveTerms maps input values to their canonical terms, in order and
+// without duplicates. Blank values are skipped; unknown ones are an
+// ErrInvalidInput.
+func resolveTerms(idx *models.TaxonomyIndex, kind models.TaxonomyKind, values []string) ([]models.TaxonomyTerm, error) {
+	out := []models.TaxonomyTerm{}
+	seen := make(map[uint]bool)
+	for _, v := range values {
+		if strings.TrimSpace(v) == "" {
+			continue
+		}
+		term, err := resolveTerm(idx, kind, v)
+		if err != nil {
+			return nil, err
+		}
+		if !seen[term.ID] {
+			seen[term.ID] = true
+			out = append(out, term.Bare())
+		}
+	}
+	return out, nil
+}
+
+// restaurantTerms resolves a restaurant's cuisines and features.
+func restaurantTerms(db *gorm.DB, in dto.RestaurantIn) (cuisines, features []models.TaxonomyTerm, err error) {
+	idx, err := loadTaxonomy(db)
+	if err != nil {
+		return nil, nil, err
+	}
+	if cuisines, err = resolveTerms(idx, models.KindCuisine, in.Cuisines); err != nil {
+		return nil, nil, err
+	}
+	if features, err = resolveTerms(idx, models.KindFeature, in.Features); err != nil {
+		return nil, nil, err
+	}
+	return cuisines, features, nil
+}
+
+// setRestaurantTerms replaces a saved restaurant's cuisine and feature
+// links.
+func setRestaurantTerms(db *gorm.DB, r *models.Restaurant, cuisines, features []models.TaxonomyTerm) error {
+	if err := db.Model(r).Association("CuisineTerms").Replace(cuisines); err != nil {
+		return err
+	}
+	return db.Model(r).Association("FeatureTerms").Replace(features)
+}
+
+// canonicalTerm returns the display form of value if it is in the
+// vocabulary, or value unchanged.
+func canonicalTerm(idx *models.TaxonomyIndex, kind models.TaxonomyKind, value string) string {
+	if idx != nil {
+		if term := idx.Lookup(kind, value); term != nil {
+			return term.Display()
+		}
+	}
+	return value
+}
+
+// canonicalTerms applies canonicalTerm to each value.
+func canonicalTerms(idx *models.TaxonomyIndex, kind models.TaxonomyKind, values []string) []string {
+	out := make([]string, len(values))
+	for i, v := range values {
+		out[i] = canonicalTerm(idx, kind, v)
+	}
+	return out
+}
+
+// withoutAllergens leaves out menu items that contain or may contain any
+// of the allergens, or that have an option containing one, since a guest
+// may pick it. Items whose allergens haven't been declared are left out
+// too: nothing says they're safe.
+func withoutAllergens(query *gorm.DB, allergens []models.TaxonomyTerm) *gorm.DB {
+	if len(allergens) == 0 {
+		return query
+	}
+	query = query.Where("menu_items.allergens_declared = ?", true)
+	for _, a := range allergens {
+		query = query.
+			Where("NOT "+hasTermSQL("menu_item_allergens", "menu_items", "menu_item_id"), a.ID).
+			Where("NOT "+hasTermSQL("menu_item_may_contain_allergens", "menu_items", "menu_item_id"), a.ID).
+			Where(`NOT EXISTS (SELECT 1 FROM menu_option_groups g
+				JOIN menu_options o ON o.group_id = g.id
+				JOIN menu_option_allergens oa ON oa.menu_option_id = o.id
+				WHERE g.menu_item_id = menu_items.id AND oa.taxonomy_term_id = ?)`, a.ID)
+	}
+	return query
+}
+
+// hasTermSQL is an EXISTS condition on a join table linking the current
+// row (table.id) to a taxonomy term, taking the term ID as its argument.
+func hasTermSQL(joinTable, table, fk string) string {
+	return fmt.Sprintf("EXISTS (SELECT 1 FROM %s jt WHERE jt.%s = %s.id AND jt.taxonomy_term_id = ?)", joinTable, fk, table)
+}
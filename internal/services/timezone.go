@@ -0,0 +1,72 @@
+package services
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	// Embed the zone database so time zones work in minimal containers
+	// without /usr/share/zoneinfo.
+	_ "time/tzdata"
+
+	"github.com/agenteats/agenteats/internal/models"
+)
+
+// locations caches loaded time zones by IANA name.
+var locations sync.Map
+
+// resolveTimezone validates an owner-supplied IANA time zone, or derives one
+// from the restaurant's country and state when none is given.
+func resolveTimezone(tz, country, state string) (string, error) {
+	if tz == "" {
+		return models.DefaultTimezone(country, state), nil
+	}
+	if _, err := time.LoadLocation(tz); err != nil || tz == "Local" {
+		return "", fmt.Errorf("%w: unknown time zone %q (use an IANA name such as America/New_York)", ErrInvalidInput, tz)
+	}
+	return tz, nil
+}
+
+// restaurantLocation returns the restaurant's time zone, falling back to
+// one derived from its address for restaurants created before time zones
+// were stored, and to UTC if that fails.
+func restaurantLocation(r *models.Restaurant) *time.Location {
+	tz := r.Timezone
+	if tz == "" {
+		tz = models.DefaultTimezone(r.Country, r.State)
+	}
+	if loc, ok := locations.Load(tz); ok {
+		return loc.(*time.Location)
+	}
+	loc, err := time.LoadLocation(tz)
+	if err != nil {
+		loc = time.UTC
+	}
+	locations.Store(tz, loc)
+	return loc
+}
+
+// restaurantClock returns the current date at the restaurant (in the same
+// form as parseDate) and the minutes past midnight there.
+func restaurantClock(r *models.Restaurant) (today time.Time, minutes int) {
+	now := time.Now().In(restaurantLocation(r))
+	today, _ = time.Parse("2006-01-02", now.Format("2006-01-02"))
+	return today, now.Hour()*60 + now.Minute()
+}
+
+// isOpenAt reports whether the restaurant is open at instant t, in its own
+// time zone, counting date overrides and the overnight part of the
+// previous day's service. r must have Hours and HoursOverrides loaded.
+func isOpenAt(r *models.Restaurant, t time.Time) bool {
+	local := t.In(restaurantLocation(r))
+	day, _ := time.Parse("2006-01-02", local.Format("2006-01-02"))
+	m := local.Hour()*60 + local.Minute()
+
+	if opens, closes, ok := dayWindow(r, day); ok && m >= opens && m < closes {
+		return true
+	}
+	if opens, closes, ok := dayWindow(r, day.AddDate(0, 0, -1)); ok && closes > minutesPerDay {
+		return m+minutesPerDay >= opens && m+minutesPerDay < closes
+	}
+	return false
+}
@@ -0,0 +1,396 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/agenteats/agenteats/internal/dto"
+	"github.com/agenteats/agenteats/internal/models"
+)
+
+func toWaitlistOut(e *models.WaitlistEntry, restaurantName string) dto.WaitlistOut {
+	return dto.WaitlistOut{
+		ID:              e.ID,
+		RestaurantID:    e.RestaurantID,
+		RestaurantName:  restaurantName,
+		CustomerName:    e.CustomerName,
+		CustomerEmail:   e.CustomerEmail,
+		CustomerPhone:   e.CustomerPhone,
+		PartySize:       e.PartySize,
+		Date:            e.Date,
+		TimeFrom:        e.TimeFrom,
+		TimeTo:          e.TimeTo,
+		SpecialRequests: e.SpecialRequests,
+		Status:          string(e.Status),
+		ReservationID:   e.ReservationID,
+		CreatedAt:       e.CreatedAt.Format("2006-01-02T15:04:05Z"),
+	}
+}
+
+// waitlistPosition returns an entry's place in line among the waiting
+// entries for the same restaurant and date.
+func waitlistPosition(db *gorm.DB, e *models.WaitlistEntry) int {
+	var ahead int64
+	db.Model(&models.WaitlistEntry{}).
+		Where("restaurant_id = ? AND date = ? AND status = ? AND created_at < ?",
+			e.RestaurantID, e.Date, models.WaitlistWaiting, e.CreatedAt).
+		Count(&ahead)
+	return int(ahead) + 1
+}
+
+// windowSlots returns the seating slots on day that fall inside [from, to]
+// and haven't passed yet at the restaurant.
+func windowSlots(r *models.Restaurant, day time.Time, from, to int) []int {
+	slots, _ := seatingSlots(r, day)
+	today, now := restaurantClock(r)
+	var inWindow []int
+	for _, m := range slots {
+		if day.Equal(today) && m <= now {
+			continue
+		}
+		if m >= from && m <= to {
+			inWindow = append(inWindow, m)
+		}
+	}
+	return inWindow
+}
+
+// windowTime is a seating time inside a waitlist window.
+type windowTime struct {
+	date  string
+	clock string
+}
+
+// waitlistTimes returns the seating times in the window from-to starting on
+// day, in order. A window that ends before it starts runs past midnight, so
+// its later part falls on the next day.
+func waitlistTimes(r *models.Restaurant, day time.Time, from, to int) []windowTime {
+	var out []windowTime
+	add := func(d time.Time, from, to int) {
+		for _, m := range windowSlots(r, d, from, to) {
+			out = append(out, windowTime{date: d.Format("2006-01-02"), clock: formatClock(m)})
+		}
+	}
+	if to >= from {
+		add(day, from, to)
+		return out
+	}
+	add(day, from, minutesPerDay-1)
+	add(day.AddDate(0, 0, 1), 0, to)
+	return out
+}
+
+// promoteEntry books a waitlist entry into the first seating time in its
+// window (or at clock, if given) that passes the same checks as
+// MakeReservation. The reservation reuses the entry's manage token. It
+// returns ErrSlotUnavailable if no time fits.
+func promoteEntry(tx *gorm.DB, r *models.Restaurant, e *models.WaitlistEntry, clock string) (*models.Reservation, error) {
+	if e.Status != models.WaitlistWaiting {
+		return nil, fmt.Errorf("%w: waitlist entry is %s", ErrInvalidTransition, e.Status)
+	}
+
+	day, err := parseDate(e.Date)
+	if err != nil {
+		return nil, err
+	}
+	from, _ := parseClock(e.TimeFrom)
+	to, _ := parseClock(e.TimeTo)
+	candidates := []windowTime{{date: e.Date, clock: clock}}
+	if clock == "" {
+		candidates = waitlistTimes(r, day, from, to)
+	} else if m, err := parseClock(clock); err == nil && to < from && m <= to {
+		// An after-midnight time in an overnight window is on the next day.
+		candidates[0].date = day.AddDate(0, 0, 1).Format("2006-01-02")
+	}
+
+	for _, c := range candidates {
+		slot, err := validateSlot(tx, r, c.date, c.clock, e.PartySize, "")
+		if errors.Is(err, ErrSlotUnavailable) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		res := models.Reservation{
+			ID:              models.NewID(),
+			RestaurantID:    r.ID,
+			CustomerName:    e.CustomerName,
+			CustomerEmail:   e.CustomerEmail,
+			CustomerPhone:   e.CustomerPhone,
+			PartySize:       e.PartySize,
+			Date:            slot.date(),
+			Time:            slot.clock(),
+			Status:          models.StatusConfirmed,
+			SpecialRequests: e.SpecialRequests,
+			TableIDs:        slot.tableIDs(),
+			ManageTokenHash: e.ManageTokenHash,
+		}
+		if err := tx.Create(&res).Error; err != nil {
+			return nil, err
+		}
+		e.Status = models.WaitlistPromoted
+		e.ReservationID = res.ID
+		if err := tx.Save(e).Error; err != nil {
+			return nil, err
+		}
+		if err := enqueueEvent(tx, r.OwnerID, models.EventReservationCreated, toReservationOut(&res, r.Name)); err != nil {
+			return nil, err
+		}
+		if err := enqueueNotification(tx, &res, models.NotifyConfirmation, guestMessageData(r, &res)); err != nil {
+			return nil, err
+		}
+		return &res, nil
+	}
+	return nil, ErrSlotUnavailable
+}
+
+// promoteWaitlist offers capacity freed on date to waiting parties, first
+// come first served. Entries on the neighbouring days are tried too, since a
+// booking near midnight overlaps them. Entries that still don't fit, or
+// whose date has passed, stay on the list.
+func promoteWaitlist(tx *gorm.DB, r *models.Restaurant, date string) error {
+	day, err := parseDate(date)
+	if err != nil {
+		return nil
+	}
+	dates := []string{
+		day.AddDate(0, 0, -1).Format("2006-01-02"),
+		date,
+		day.AddDate(0, 0, 1).Format("2006-01-02"),
+	}
+
+	var entries []models.WaitlistEntry
+	if err := tx.Where("restaurant_id = ? AND date IN ? AND status = ?", r.ID, dates, models.WaitlistWaiting).
+		Order("created_at").Find(&entries).Error; err != nil {
+		return err
+	}
+	for i := range entries {
+		_, err := promoteEntry(tx, r, &entries[i], "")
+		switch {
+		case err == nil,
+			errors.Is(err, ErrSlotUnavailable),
+			errors.Is(err, ErrInvalidInput),
+			errors.Is(err, ErrPartyTooLarge),
+			errors.Is(err, ErrRestaurantClosed),
+			errors.Is(err, ErrRestaurantInactive):
+			continue
+		default:
+			return err
+		}
+	}
+	return nil
+}
+
+// --- Waitlist ---
+
+// JoinWaitlist adds a party to a restaurant's waitlist for a date and time
+// window. A window whose time_to is before its time_from runs past midnight
+// into the next day. The party must be one the restaurant could seat and the
+// window must contain at least one seating time. The returned manage token is shown
+// only once; it lets the guest check or leave the waitlist, and manages the
+// reservation if the entry is promoted.
+func JoinWaitlist(db *gorm.DB, restaurantID string, in dto.WaitlistIn) (*dto.WaitlistOut, error) {
+	if err := validateGuest(dto.ReservationIn{
+		CustomerName:  in.CustomerName,
+		CustomerEmail: in.CustomerEmail,
+		CustomerPhone: in.CustomerPhone,
+	}); err != nil {
+		return nil, err
+	}
+	if in.TimeTo == "" {
+		in.TimeTo = in.TimeFrom
+	}
+
+	var r models.Restaurant
+	if err := preloadSchedule(db).First(&r, "id = ?", restaurantID).Error; err != nil {
+		return nil, err
+	}
+	if !r.IsActive {
+		return nil, ErrRestaurantInactive
+	}
+	if in.PartySize < 1 {
+		return nil, fmt.Errorf("%w: party_size must be at least 1", ErrInvalidInput)
+	}
+	if limit := maxPartySize(&r); in.PartySize > limit {
+		return nil, fmt.Errorf("%w: %s seats at most %d guests", ErrPartyTooLarge, r.Name, limit)
+	}
+
+	day, err := parseDate(in.Date)
+	if err != nil {
+		return nil, err
+	}
+	from, err := parseClock(in.TimeFrom)
+	if err != nil {
+		return nil, err
+	}
+	to, err := parseClock(in.TimeTo)
+	if err != nil {
+		return nil, err
+	}
+	if today, _ := restaurantClock(&r); day.Before(today) {
+		return nil, fmt.Errorf("%w: date %s is in the past", ErrInvalidInput, in.Date)
+	}
+	if len(waitlistTimes(&r, day, from, to)) == 0 {
+		return nil, fmt.Errorf("%w: no seating times between %s and %s on %s",
+			ErrRestaurantClosed, formatClock(from), formatClock(to), day.Format("Monday, January 2"))
+	}
+
+	rawToken, tokenHash := models.GenerateManageToken()
+	e := models.WaitlistEntry{
+		ID:              models.NewID(),
+		RestaurantID:    restaurantID,
+		CustomerName:    strings.TrimSpace(in.CustomerName),
+		CustomerEmail:   in.CustomerEmail,
+		CustomerPhone:   in.CustomerPhone,
+		PartySize:       in.PartySize,
+		Date:            day.Format("2006-01-02"),
+		TimeFrom:        formatClock(from),
+		TimeTo:          formatClock(to),
+		SpecialRequests: in.SpecialRequests,
+		Status:          models.WaitlistWaiting,
+		ManageTokenHash: tokenHash,
+	}
+	if err := db.Create(&e).Error; err != nil {
+		return nil, err
+	}
+
+	out := toWaitlistOut(&e, r.Name)
+	out.Position = waitlistPosition(db, &e)
+	out.ManageToken = rawToken
+	return &out, nil
+}
+
+// GetWaitlistEntry returns a waitlist entry to the guest holding its manage
+// token or to the restaurant's owner, with its place in line while waiting
+// or the booked time once promoted.
+func GetWaitlistEntry(db *gorm.DB, entryID, manageToken, ownerID string) (*dto.WaitlistOut, error) {
+	var e models.WaitlistEntry
+	if err := db.First(&e, "id = ?", entryID).Error; err != nil {
+		return nil, err
+	}
+	if _, err := authorizeGuestOrOwner(db, e.RestaurantID, e.ManageTokenHash, manageToken, ownerID); err != nil {
+		return nil, err
+	}
+
+	var r models.Restaurant
+	db.First(&r, "id = ?", e.RestaurantID)
+
+	out := toWaitlistOut(&e, r.Name)
+	switch e.Status {
+	case models.WaitlistWaiting:
+		out.Position = waitlistPosition(db, &e)
+	case models.WaitlistPromoted:
+		var res models.Reservation
+		if db.First(&res, "id = ?", e.ReservationID).Error == nil {
+			out.ReservationTime = res.Time
+		}
+	}
+	return &out, nil
+}
+
+// LeaveWaitlist takes a waiting party off the waitlist. Once an entry has
+// been promoted, the guest should cancel the reservation instead.
+func LeaveWaitlist(db *gorm.DB, entryID, manageToken, ownerID string) (*dto.WaitlistOut, error) {
+	var e models.WaitlistEntry
+	if err := db.First(&e, "id = ?", entryID).Error; err != nil {
+		return nil, err
+	}
+	if _, err := authorizeGuestOrOwner(db, e.RestaurantID, e.ManageTokenHash, manageToken, ownerID); err != nil {
+		return nil, err
+	}
+
+	// Lock so the entry can't be promoted while it is being removed.
+	var out dto.WaitlistOut
+	err := withRestaurantLock(db, e.RestaurantID, func(tx *gorm.DB, r *models.Restaurant) error {
+		if err := tx.First(&e, "id = ?", entryID).Error; err != nil {
+			return err
+		}
+		if e.Status != models.WaitlistWaiting {
+			return fmt.Errorf("%w: waitlist entry is %s", ErrInvalidTransition, e.Status)
+		}
+		e.Status = models.WaitlistLeft
+		if err := tx.Save(&e).Error; err != nil {
+			return err
+		}
+		out = toWaitlistOut(&e, r.Name)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// ListWaitlist returns a restaurant's waitlist for its owner, oldest first,
+// optionally filtered by date and status.
+func ListWaitlist(db *gorm.DB, restaurantID, date, status string) ([]dto.WaitlistOut, error) {
+	var r models.Restaurant
+	if err := db.First(&r, "id = ?", restaurantID).Error; err != nil {
+		return nil, err
+	}
+
+	query := db.Where("restaurant_id = ?", restaurantID)
+	if date != "" {
+		if _, err := parseDate(date); err != nil {
+			return nil, err
+		}
+		query = query.Where("date = ?", date)
+	}
+	if status != "" {
+		switch models.WaitlistStatus(status) {
+		case models.WaitlistWaiting, models.WaitlistPromoted, models.WaitlistLeft:
+		default:
+			return nil, fmt.Errorf("%w: unknown status %q", ErrInvalidInput, status)
+		}
+		query = query.Where("status = ?", status)
+	}
+
+	var entries []models.WaitlistEntry
+	query.Order("date, created_at").Find(&entries)
+
+	results := make([]dto.WaitlistOut, len(entries))
+	positions := make(map[string]int)
+	for i := range entries {
+		results[i] = toWaitlistOut(&entries[i], r.Name)
+		if entries[i].Status == models.WaitlistWaiting {
+			positions[entries[i].Date]++
+			results[i].Position = positions[entries[i].Date]
+		}
+	}
+	return results, nil
+}
+
+// PromoteWaitlistEntry lets the owner book a waiting party now, at clock or
+// at the first time in the entry's window that fits. Capacity is checked
+// exactly as for MakeReservation.
+func PromoteWaitlistEntry(db *gorm.DB, entryID, ownerID string, in dto.WaitlistPromoteIn) (*dto.ReservationOut, error) {
+	var e models.WaitlistEntry
+	if err := db.First(&e, "id = ?", entryID).Error; err != nil {
+		return nil, err
+	}
+	if !RestaurantBelongsToOwner(db, e.RestaurantID, ownerID) {
+		return nil, ErrNotAuthorized
+	}
+
+	var out dto.ReservationOut
+	err := withRestaurantLock(db, e.RestaurantID, func(tx *gorm.DB, r *models.Restaurant) error {
+		if err := tx.First(&e, "id = ?", entryID).Error; err != nil {
+			return err
+		}
+		res, err := promoteEntry(tx, r, &e, in.Time)
+		if err != nil {
+			return err
+		}
+		out = toReservationOut(res, r.Name)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
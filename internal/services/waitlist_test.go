@@ -0,0 +1,153 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/agenteats/agenteats/internal/dto"
+	"github.com/agenteats/agenteats/internal/models"
+)
+
+func TestCancelPromotesWaitlist(t *testing.T) {
+	db := openTestDB(t)
+	r := createTestRestaurant(t, db, dto.RestaurantIn{TotalSeats: 4})
+	date := daysFromNow(3)
+
+	booked, err := MakeReservation(db, r.ID, dto.ReservationIn{CustomerName: "Booked", PartySize: 4, Date: date, Time: "19:00"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := MakeReservation(db, r.ID, dto.ReservationIn{CustomerName: "Later", PartySize: 4, Date: date, Time: "21:00"}); err != nil {
+		t.Fatal(err)
+	}
+	join := func(name string, party int, from, to string) *dto.WaitlistOut {
+		e, err := JoinWaitlist(db, r.ID, dto.WaitlistIn{CustomerName: name, PartySize: party, Date: date, TimeFrom: from, TimeTo: to})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return e
+	}
+	outside := join("Outside the window", 2, "21:00", "21:30")
+	first := join("First", 3, "19:00", "19:30")
+	second := join("Second", 2, "19:00", "19:30")
+	if first.Position != 2 || second.Position != 3 {
+		t.Fatalf("positions %d and %d, want 2 and 3", first.Position, second.Position)
+	}
+
+	if _, err := CancelReservation(db, booked.ID, booked.ManageToken, ""); err != nil {
+		t.Fatal(err)
+	}
+
+	status := func(e *dto.WaitlistOut) models.WaitlistEntry {
+		var got models.WaitlistEntry
+		if err := db.First(&got, "id = ?", e.ID).Error; err != nil {
+			t.Fatal(err)
+		}
+		return got
+	}
+	promoted := status(first)
+	if promoted.Status != models.WaitlistPromoted || promoted.ReservationID == "" {
+		t.Fatalf("first in line is %s, want promoted", promoted.Status)
+	}
+	res, err := GetReservation(db, promoted.ReservationID, first.ManageToken, "")
+	if err != nil {
+		t.Fatalf("entry's manage token doesn't manage the booking: %v", err)
+	}
+	if res.Time != "19:00" || res.PartySize != 3 || res.Status != string(models.StatusConfirmed) {
+		t.Fatalf("promoted to %s for %d (%s), want 19:00 for 3", res.Time, res.PartySize, res.Status)
+	}
+
+	// One seat is left at 19:00, so the second party keeps waiting, as does
+	// the party whose window the cancellation didn't free.
+	for _, e := range []*dto.WaitlistOut{second, outside} {
+		if got := status(e); got.Status != models.WaitlistWaiting {
+			t.Errorf("%s is %s, want waiting", got.CustomerName, got.Status)
+		}
+	}
+}
+
+func TestWaitlistWindowPastMidnight(t *testing.T) {
+	db := openTestDB(t)
+	var hours []dto.OperatingHoursIn
+	for _, day := range []string{"monday", "tuesday", "wednesday", "thursday", "friday", "saturday", "sunday"} {
+		hours = append(hours, dto.OperatingHoursIn{Day: day, OpenTime: "18:00", CloseTime: "02:00"})
+	}
+	r := createTestRestaurant(t, db, dto.RestaurantIn{TotalSeats: 4, Hours: hours})
+	date, next := daysFromNow(3), daysFromNow(4)
+
+	if _, err := MakeReservation(db, r.ID, dto.ReservationIn{CustomerName: "Late", PartySize: 4, Date: date, Time: "23:00"}); err != nil {
+		t.Fatal(err)
+	}
+	later, err := MakeReservation(db, r.ID, dto.ReservationIn{CustomerName: "Later", PartySize: 4, Date: next, Time: "00:30"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	e, err := JoinWaitlist(db, r.ID, dto.WaitlistIn{CustomerName: "Night owl", PartySize: 2, Date: date, TimeFrom: "23:30", TimeTo: "00:30"})
+	if err != nil {
+		t.Fatalf("overnight window rejected: %v", err)
+	}
+
+	if _, err := CancelReservation(db, later.ID, later.ManageToken, ""); err != nil {
+		t.Fatal(err)
+	}
+	var got models.WaitlistEntry
+	if err := db.First(&got, "id = ?", e.ID).Error; err != nil {
+		t.Fatal(err)
+	}
+	if got.Status != models.WaitlistPromoted {
+		t.Fatalf("entry is %s, want promoted", got.Status)
+	}
+	res, err := GetReservation(db, got.ReservationID, e.ManageToken, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Date != next || res.Time != "00:30" {
+		t.Errorf("promoted to %s %s, want %s 00:30", res.Date, res.Time, next)
+	}
+}
+
+func TestFreedSeatsPromoteWaitlist(t *testing.T) {
+	db := openTestDB(t)
+	owner, err := RegisterOwner(db, dto.RegisterOwnerIn{Name: "Owner", Email: "owner@example.com"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := createTestRestaurant(t, db, dto.RestaurantIn{TotalSeats: 4})
+	db.Model(&models.Restaurant{}).Where("id = ?", r.ID).Update("owner_id", owner.ID)
+	date := daysFromNow(3)
+
+	booked, err := MakeReservation(db, r.ID, dto.ReservationIn{CustomerName: "Booked", PartySize: 4, Date: date, Time: "19:00"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	join := func(name string) *dto.WaitlistOut {
+		e, err := JoinWaitlist(db, r.ID, dto.WaitlistIn{CustomerName: name, PartySize: 2, Date: date, TimeFrom: "19:00"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return e
+	}
+	status := func(e *dto.WaitlistOut) models.WaitlistStatus {
+		var got models.WaitlistEntry
+		if err := db.First(&got, "id = ?", e.ID).Error; err != nil {
+			t.Fatal(err)
+		}
+		return got.Status
+	}
+
+	first := join("First")
+	party := 2
+	if _, err := ModifyReservation(db, booked.ID, booked.ManageToken, "", dto.ReservationUpdateIn{PartySize: &party}); err != nil {
+		t.Fatal(err)
+	}
+	if got := status(first); got != models.WaitlistPromoted {
+		t.Fatalf("after a smaller party, first is %s, want promoted", got)
+	}
+
+	second := join("Second")
+	if _, err := UpdateReservationStatus(db, booked.ID, owner.ID, models.StatusNoShow); err != nil {
+		t.Fatal(err)
+	}
+	if got := status(second); got != models.WaitlistPromoted {
+		t.Fatalf("after a no-show, second is %s, want promoted", got)
+	}
+}
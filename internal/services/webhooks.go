@@ -0,0 +1,350 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/agenteats/agenteats/internal/dto"
+	"github.com/agenteats/agenteats/internal/models"
+	"github.com/agenteats/agenteats/internal/webhooks"
+)
+
+// webhookEnvelope is the JSON body of every webhook delivery.
+type webhookEnvelope struct {
+	ID        string              `json:"id"`
+	Type      models.WebhookEvent `json:"type"`
+	CreatedAt string              `json:"created_at"`
+	Data      any                 `json:"data"`
+}
+
+// enqueueEvent adds a pending delivery of event to each of ownerID's active
+// subscriptions that want it. It runs in the caller's transaction, so the
+// delivery is recorded if and only if the change it describes is.
+func enqueueEvent(tx *gorm.DB, ownerID string, event models.WebhookEvent, data any) error {
+	if ownerID == "" {
+		return nil
+	}
+	var subs []models.WebhookSubscription
+	if err := tx.Where("owner_id = ? AND is_active = ?", ownerID, true).Find(&subs).Error; err != nil {
+		return err
+	}
+	var deliveries []models.WebhookDelivery
+	now := time.Now().UTC()
+	env := webhookEnvelope{
+		ID:        models.NewID(),
+		Type:      event,
+		CreatedAt: now.Format("2006-01-02T15:04:05Z"),
+		Data:      data,
+	}
+	var payload []byte
+	for _, s := range subs {
+		if !s.Wants(event) {
+			continue
+		}
+		if payload == nil {
+			var err error
+			if payload, err = json.Marshal(env); err != nil {
+				return err
+			}
+		}
+		deliveries = append(deliveries, models.WebhookDelivery{
+			ID:             models.NewID(),
+			SubscriptionID: s.ID,
+			OwnerID:        ownerID,
+			EventID:        env.ID,
+			Event:          event,
+			Payload:        string(payload),
+			Status:         models.DeliveryPending,
+			NextAttemptAt:  now,
+		})
+	}
+	if len(deliveries) == 0 {
+		return nil
+	}
+	return tx.Create(&deliveries).Error
+}
+
+// enqueueRestaurantEvent is enqueueEvent for the owner of a restaurant.
+func enqueueRestaurantEvent(tx *gorm.DB, restaurantID string, event models.WebhookEvent, data any) error {
+	var r models.Restaurant
+	if err := tx.Select("id", "owner_id").First(&r, "id = ?", restaurantID).Error; err != nil {
+		return err
+	}
+	return enqueueEvent(tx, r.OwnerID, event, data)
+}
+
+func toWebhookOut(s *models.WebhookSubscription) dto.WebhookOut {
+	return dto.WebhookOut{
+		ID:        s.ID,
+		URL:       s.URL,
+		Events:    splitCSV(s.Events),
+		IsActive:  s.IsActive,
+		CreatedAt: s.CreatedAt.Format("2006-01-02T15:04:05Z"),
+		UpdatedAt: s.UpdatedAt.Format("2006-01-02T15:04:05Z"),
+	}
+}
+
+func toWebhookDeliveryOut(d *models.WebhookDelivery) dto.WebhookDeliveryOut {
+	out := dto.WebhookDeliveryOut{
+		ID:             d.ID,
+		WebhookID:      d.SubscriptionID,
+		EventID:        d.EventID,
+		Event:          string(d.Event),
+		Status:         string(d.Status),
+		Attempts:       d.Attempts,
+		ResponseStatus: d.ResponseStatus,
+		LastError:      d.LastError,
+		ReplayOf:       d.ReplayOf,
+		CreatedAt:      d.CreatedAt.Format("2006-01-02T15:04:05Z"),
+		Payload:        json.RawMessage(d.Payload),
+	}
+	if d.Status == models.DeliveryPending {
+		out.NextAttemptAt = d.NextAttemptAt.UTC().Format("2006-01-02T15:04:05Z")
+	}
+	if d.LastAttemptAt != nil {
+		out.LastAttemptAt = d.LastAttemptAt.UTC().Format("2006-01-02T15:04:05Z")
+	}
+	if d.DeliveredAt != nil {
+		out.DeliveredAt = d.DeliveredAt.UTC().Format("2006-01-02T15:04:05Z")
+	}
+	return out
+}
+
+// minWebhookSecretLength is the shortest signing secret an owner may choose.
+const minWebhookSecretLength = 16
+
+// webhookResolveTimeout bounds the DNS lookup that checks a webhook URL
+// points to a public address.
+const webhookResolveTimeout = 5 * time.Second
+
+// validateWebhook checks a subscription payload and returns its events
+// in canonical, de-duplicated form. The URL must resolve to public
+// addresses only, so webhooks can't be used to reach internal services.
+func validateWebhook(in dto.WebhookIn) (string, error) {
+	u, err := url.Parse(strings.TrimSpace(in.URL))
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return "", fmt.Errorf("%w: url must be an absolute http or https URL", ErrInvalidInput)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), webhookResolveTimeout)
+	defer cancel()
+	if err := webhooks.CheckURL(ctx, u.String()); err != nil {
+		if errors.Is(err, webhooks.ErrForbiddenAddress) {
+			return "", fmt.Errorf("%w: url must point to a public address, not localhost or a private network", ErrInvalidInput)
+		}
+		return "", fmt.Errorf("%w: url host could not be resolved", ErrInvalidInput)
+	}
+	if in.Secret != "" && len(in.Secret) < minWebhookSecretLength {
+		return "", fmt.Errorf("%w: secret must be at least %d characters", ErrInvalidInput, minWebhookSecretLength)
+	}
+	var events []string
+	seen := make(map[models.WebhookEvent]bool)
+	for _, e := range in.Events {
+		ev := models.WebhookEvent(strings.ToLower(strings.TrimSpace(e)))
+		if !ev.Valid() {
+			return "", fmt.Errorf("%w: unknown event %q", ErrInvalidInput, e)
+		}
+		if !seen[ev] {
+			seen[ev] = true
+			events = append(events, string(ev))
+		}
+	}
+	if len(events) == 0 {
+		return "", fmt.Errorf("%w: events must name at least one event type", ErrInvalidInput)
+	}
+	return strings.Join(events, ","), nil
+}
+
+// CreateWebhook subscribes one of ownerID's URLs to events at all of the
+// owner's restaurants. The signing secret is returned only here.
+func CreateWebhook(db *gorm.DB, ownerID string, in dto.WebhookIn) (*dto.WebhookOut, error) {
+	events, err := validateWebhook(in)
+	if err != nil {
+		return nil, err
+	}
+	s := models.WebhookSubscription{
+		ID:       models.NewID(),
+		OwnerID:  ownerID,
+		URL:      strings.TrimSpace(in.URL),
+		Secret:   in.Secret,
+		Events:   events,
+		IsActive: true,
+	}
+	if s.Secret == "" {
+		s.Secret = models.GenerateWebhookSecret()
+	}
+	if err := db.Create(&s).Error; err != nil {
+		return nil, err
+	}
+	// gorm skips zero values for fields with a default, so persist an
+	// explicitly inactive subscription with a follow-up update.
+	if in.IsActive != nil && !*in.IsActive {
+		s.IsActive = false
+		if err := db.Model(&s).Update("is_active", false).Error; err != nil {
+			return nil, err
+		}
+	}
+	out := toWebhookOut(&s)
+	out.Secret = s.Secret
+	return &out, nil
+}
+
+// ListWebhooks returns ownerID's webhook subscriptions, oldest first.
+func ListWebhooks(db *gorm.DB, ownerID string) ([]dto.WebhookOut, error) {
+	var subs []models.WebhookSubscription
+	if err := db.Where("owner_id = ?", ownerID).Order("created_at ASC, id ASC").Find(&subs).Error; err != nil {
+		return nil, err
+	}
+	out := make([]dto.WebhookOut, len(subs))
+	for i := range subs {
+		out[i] = toWebhookOut(&subs[i])
+	}
+	return out, nil
+}
+
+// ownedWebhook loads one of ownerID's webhook subscriptions.
+func ownedWebhook(db *gorm.DB, webhookID, ownerID string) (*models.WebhookSubscription, error) {
+	var s models.WebhookSubscription
+	if err := db.First(&s, "id = ?", webhookID).Error; err != nil {
+		return nil, err
+	}
+	if s.OwnerID != ownerID {
+		return nil, ErrNotAuthorized
+	}
+	return &s, nil
+}
+
+// UpdateWebhook replaces a subscription's URL and events, and optionally
+// pauses, resumes or rotates the secret of it. Deliveries already queued
+// go to the new URL with the new secret.
+func UpdateWebhook(db *gorm.DB, ownerID, webhookID string, in dto.WebhookIn) (*dto.WebhookOut, error) {
+	events, err := validateWebhook(in)
+	if err != nil {
+		return nil, err
+	}
+	s, err := ownedWebhook(db, webhookID, ownerID)
+	if err != nil {
+		return nil, err
+	}
+	s.URL = strings.TrimSpace(in.URL)
+	s.Events = events
+	if in.IsActive != nil {
+		s.IsActive = *in.IsActive
+	}
+	if in.Secret != "" {
+		s.Secret = in.Secret
+	}
+	if err := db.Save(s).Error; err != nil {
+		return nil, err
+	}
+	out := toWebhookOut(s)
+	if in.Secret != "" {
+		out.Secret = s.Secret
+	}
+	return &out, nil
+}
+
+// DeleteWebhook removes a subscription together with its delivery log.
+// Deliveries still pending are dropped.
+func DeleteWebhook(db *gorm.DB, ownerID, webhookID string) error {
+	s, err := ownedWebhook(db, webhookID, ownerID)
+	if err != nil {
+		return err
+	}
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("subscription_id = ?", s.ID).Delete(&models.WebhookDelivery{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(s).Error
+	})
+}
+
+// ListWebhookDeliveries pages through a subscription's delivery log,
+// newest first.
+func ListWebhookDeliveries(db *gorm.DB, ownerID, webhookID string, q dto.DeliveryQuery) (*dto.Page[dto.WebhookDeliveryOut], error) {
+	s, err := ownedWebhook(db, webhookID, ownerID)
+	if err != nil {
+		return nil, err
+	}
+	query := db.Model(&models.WebhookDelivery{}).Where("subscription_id = ?", s.ID)
+	if q.Status != "" {
+		if !models.DeliveryStatus(q.Status).Valid() {
+			return nil, fmt.Errorf("%w: status must be pending, delivered or failed", ErrInvalidInput)
+		}
+		query = query.Where("status = ?", q.Status)
+	}
+	if q.Event != "" {
+		if !models.WebhookEvent(q.Event).Valid() {
+			return nil, fmt.Errorf("%w: unknown event %q", ErrInvalidInput, q.Event)
+		}
+		query = query.Where("event = ?", q.Event)
+	}
+
+	offset, err := startOffset(q.Cursor, q.Offset)
+	if err != nil {
+		return nil, err
+	}
+	limit := q.Limit
+	if limit <= 0 {
+		limit = 20
+	}
+
+	var total int64
+	if err := query.Session(&gorm.Session{}).Count(&total).Error; err != nil {
+		return nil, err
+	}
+	var deliveries []models.WebhookDelivery
+	if err := query.Order("created_at DESC, id DESC").Offset(offset).Limit(limit).Find(&deliveries).Error; err != nil {
+		return nil, err
+	}
+
+	page := &dto.Page[dto.WebhookDeliveryOut]{
+		Items:         make([]dto.WebhookDeliveryOut, len(deliveries)),
+		NextCursor:    nextOffsetCursor(offset, len(deliveries), total),
+		TotalEstimate: total,
+	}
+	for i := range deliveries {
+		page.Items[i] = toWebhookDeliveryOut(&deliveries[i])
+	}
+	return page, nil
+}
+
+// ReplayWebhookDelivery queues a delivered or failed delivery again, as a
+// new delivery with the same event ID and payload so receivers can tell it
+// is a repeat. Pending deliveries are already going to be retried and
+// can't be replayed.
+func ReplayWebhookDelivery(db *gorm.DB, ownerID, webhookID, deliveryID string) (*dto.WebhookDeliveryOut, error) {
+	s, err := ownedWebhook(db, webhookID, ownerID)
+	if err != nil {
+		return nil, err
+	}
+	var d models.WebhookDelivery
+	if err := db.First(&d, "id = ? AND subscription_id = ?", deliveryID, s.ID).Error; err != nil {
+		return nil, err
+	}
+	if d.Status == models.DeliveryPending {
+		return nil, fmt.Errorf("%w: delivery is still pending", ErrInvalidTransition)
+	}
+	replay := models.WebhookDelivery{
+		ID:             models.NewID(),
+		SubscriptionID: s.ID,
+		OwnerID:        ownerID,
+		EventID:        d.EventID,
+		Event:          d.Event,
+		Payload:        d.Payload,
+		Status:         models.DeliveryPending,
+		NextAttemptAt:  time.Now().UTC(),
+		ReplayOf:       d.ID,
+	}
+	if err := db.Create(&replay).Error; err != nil {
+		return nil, err
+	}
+	out := toWebhookDeliveryOut(&replay)
+	return &out, nil
+}
@@ -0,0 +1,153 @@
+package webhooks
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// ErrForbiddenAddress is returned when a webhook URL resolves to an address
+// deliveries may not be sent to: loopback, private, link-local (which
+// includes cloud metadata endpoints such as 169.254.169.254) and other
+// non-public ranges, unless AllowedNets lets them through.
+var ErrForbiddenAddress = errors.New("webhook address is not public")
+
+// AllowedNets are non-public ranges deliveries may be sent to anyway, such
+// as 127.0.0.0/8 for a receiver on the same machine during development. It
+// is empty by default and set once at startup, by AllowNets.
+var AllowedNets []*net.IPNet
+
+// AllowNets sets AllowedNets from a comma-separated list of CIDRs, as in
+// the WEBHOOK_ALLOW_NETS setting.
+func AllowNets(list string) error {
+	var nets []*net.IPNet
+	for _, c := range strings.Split(list, ",") {
+		if c = strings.TrimSpace(c); c == "" {
+			continue
+		}
+		_, n, err := net.ParseCIDR(c)
+		if err != nil {
+			return fmt.Errorf("allowed network %q: %w", c, err)
+		}
+		nets = append(nets, n)
+	}
+	AllowedNets = nets
+	return nil
+}
+
+// reservedNets are non-public ranges the net.IP predicates don't cover.
+var reservedNets = mustParseCIDRs(
+	"0.0.0.0/8",     // "this network"
+	"100.64.0.0/10", // carrier-grade NAT, also used for some metadata services
+	"192.0.0.0/24",  // IETF protocol assignments
+	"198.18.0.0/15", // benchmarking
+	"240.0.0.0/4",   // reserved, including broadcast
+	"64:ff9b::/96",  // NAT64, which can reach any IPv4 address
+)
+
+func mustParseCIDRs(cidrs ...string) []*net.IPNet {
+	nets := make([]*net.IPNet, len(cidrs))
+	for i, c := range cidrs {
+		_, n, err := net.ParseCIDR(c)
+		if err != nil {
+			panic(err)
+		}
+		nets[i] = n
+	}
+	return nets
+}
+
+// Allowed reports whether deliveries may be sent to ip: it is public, or in
+// AllowedNets.
+func Allowed(ip net.IP) bool {
+	for _, n := range AllowedNets {
+		if n.Contains(ip) {
+			return true
+		}
+	}
+	return PublicIP(ip)
+}
+
+// PublicIP reports whether ip is a public internet address.
+func PublicIP(ip net.IP) bool {
+	if ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() ||
+		ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() || ip.IsMulticast() {
+		return false
+	}
+	for _, n := range reservedNets {
+		if n.Contains(ip) {
+			return false
+		}
+	}
+	return true
+}
+
+// CheckURL resolves rawURL's host and returns ErrForbiddenAddress if any of
+// its addresses isn't Allowed. The dispatcher checks again when it
+// connects, since DNS can change in between.
+func CheckURL(ctx context.Context, rawURL string) error {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return err
+	}
+	host := strings.TrimSuffix(strings.ToLower(u.Hostname()), ".")
+	if host == "localhost" || strings.HasSuffix(host, ".localhost") {
+		// Always loopback, whatever the resolver says.
+		host = "127.0.0.1"
+	}
+	if ip := net.ParseIP(host); ip != nil {
+		if !Allowed(ip) {
+			return ErrForbiddenAddress
+		}
+		return nil
+	}
+	addrs, err := net.DefaultResolver.LookupIPAddr(ctx, host)
+	if err != nil {
+		return fmt.Errorf("resolve %s: %w", host, err)
+	}
+	for _, a := range addrs {
+		if !Allowed(a.IP) {
+			return ErrForbiddenAddress
+		}
+	}
+	return nil
+}
+
+// dialControl refuses connections to addresses that aren't Allowed. It runs after
+// DNS resolution, on the address actually dialled, so a host that resolves
+// to a public address when subscribed and a private one later is still
+// caught.
+func dialControl(network, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	ip := net.ParseIP(host)
+	if ip == nil || !Allowed(ip) {
+		return fmt.Errorf("%w: %s", ErrForbiddenAddress, host)
+	}
+	return nil
+}
+
+// NewClient returns an HTTP client for sending deliveries. It only connects
+// to Allowed addresses, including when following redirects, and ignores
+// proxy settings so the check applies to the receiver itself.
+func NewClient(timeout time.Duration) *http.Client {
+	dialer := &net.Dialer{Timeout: timeout, Control: dialControl}
+	return &http.Client{
+		Timeout: timeout,
+		Transport: &http.Transport{
+			DialContext:         dialer.DialContext,
+			TLSHandshakeTimeout: timeout,
+			MaxIdleConns:        100,
+			IdleConnTimeout:     90 * time.Second,
+		},
+	}
+}
@@ -0,0 +1,62 @@
+package webhooks
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+// allowNets sets AllowedNets for the rest of the test.
+func allowNets(t *testing.T, list string) {
+	t.Helper()
+	old := AllowedNets
+	t.Cleanup(func() { AllowedNets = old })
+	if err := AllowNets(list); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestCheckURL(t *testing.T) {
+	tests := []struct {
+		name      string
+		allow     string
+		url       string
+		forbidden bool
+	}{
+		{name: "public", url: "https://8.8.8.8/hook"},
+		{name: "public IPv6", url: "https://[2001:4860:4860::8888]/hook"},
+		{name: "localhost", url: "http://localhost:8080/hook", forbidden: true},
+		{name: "localhost subdomain", url: "http://api.localhost/hook", forbidden: true},
+		{name: "loopback", url: "http://127.0.0.1/hook", forbidden: true},
+		{name: "IPv6 loopback", url: "http://[::1]/hook", forbidden: true},
+		{name: "private", url: "http://10.0.0.1/hook", forbidden: true},
+		{name: "metadata", url: "http://169.254.169.254/latest/meta-data", forbidden: true},
+		{name: "carrier-grade NAT", url: "http://100.64.0.1/hook", forbidden: true},
+		{name: "unspecified", url: "http://0.0.0.0/hook", forbidden: true},
+		{name: "allowed loopback", allow: "127.0.0.0/8", url: "http://127.0.0.1:9000/hook"},
+		{name: "allowed localhost", allow: "127.0.0.0/8", url: "http://localhost:9000/hook"},
+		{name: "allowlist is exact", allow: "127.0.0.0/8", url: "http://10.0.0.1/hook", forbidden: true},
+		{name: "several nets", allow: "127.0.0.0/8, 10.0.0.0/8", url: "http://10.1.2.3/hook"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			allowNets(t, tt.allow)
+			err := CheckURL(context.Background(), tt.url)
+			if tt.forbidden {
+				if !errors.Is(err, ErrForbiddenAddress) {
+					t.Fatalf("CheckURL(%q) = %v, want ErrForbiddenAddress", tt.url, err)
+				}
+			} else if err != nil {
+				t.Fatalf("CheckURL(%q) = %v, want nil", tt.url, err)
+			}
+		})
+	}
+}
+
+func TestAllowNetsRejectsBadCIDR(t *testing.T) {
+	old := AllowedNets
+	t.Cleanup(func() { AllowedNets = old })
+	if err := AllowNets("127.0.0.1"); err == nil {
+		t.Fatal("AllowNets accepted an address without a prefix length")
+	}
+}
@@ -0,0 +1,196 @@
+package webhooks
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/agenteats/agenteats/internal/models"
+)
+
+// Dispatcher sends pending webhook deliveries. Several dispatchers can work
+// on one database: each claims a delivery before sending it, and a claim
+// that isn't resolved (because the process died) expires after Lease.
+type Dispatcher struct {
+	DB     *gorm.DB
+	Client *http.Client
+
+	Interval    time.Duration // how often Run looks for due deliveries
+	BatchSize   int           // deliveries claimed per pass
+	MaxAttempts int           // attempts before a delivery is marked failed
+	BaseDelay   time.Duration // wait before the first retry; doubles each attempt
+	MaxDelay    time.Duration // longest wait between retries
+	Lease       time.Duration // how long a claimed delivery is hidden from other dispatchers
+}
+
+// NewDispatcher returns a Dispatcher with the default schedule: eight
+// attempts, retried after 30s, 1m, 2m and so on, about an hour in all.
+func NewDispatcher(db *gorm.DB) *Dispatcher {
+	return &Dispatcher{
+		DB:          db,
+		Client:      NewClient(10 * time.Second),
+		Interval:    2 * time.Second,
+		BatchSize:   50,
+		MaxAttempts: 8,
+		BaseDelay:   30 * time.Second,
+		MaxDelay:    time.Hour,
+		Lease:       time.Minute,
+	}
+}
+
+// Run dispatches due deliveries every Interval until ctx is done.
+func (d *Dispatcher) Run(ctx context.Context) {
+	ticker := time.NewTicker(d.Interval)
+	defer ticker.Stop()
+	for {
+		if _, err := d.DispatchDue(ctx); err != nil && ctx.Err() == nil {
+			log.Printf("webhooks: %v", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// DispatchDue sends every pending delivery whose next attempt is due, up to
+// BatchSize, and returns how many it attempted.
+func (d *Dispatcher) DispatchDue(ctx context.Context) (int, error) {
+	var due []models.WebhookDelivery
+	if err := d.DB.WithContext(ctx).
+		Where("status = ? AND next_attempt_at <= ?", models.DeliveryPending, time.Now().UTC()).
+		Order("next_attempt_at ASC, id ASC").Limit(d.BatchSize).
+		Find(&due).Error; err != nil {
+		return 0, err
+	}
+	sent := 0
+	for i := range due {
+		if ctx.Err() != nil {
+			return sent, ctx.Err()
+		}
+		claimed, err := d.claim(ctx, &due[i])
+		if err != nil {
+			return sent, err
+		}
+		if !claimed {
+			continue
+		}
+		if err := d.deliver(ctx, &due[i]); err != nil {
+			return sent, err
+		}
+		sent++
+	}
+	return sent, nil
+}
+
+// claim counts an attempt against the delivery and pushes its next attempt
+// back by Lease. The update only matches if no other dispatcher has claimed
+// the delivery since it was read, which Attempts tells.
+func (d *Dispatcher) claim(ctx context.Context, dl *models.WebhookDelivery) (bool, error) {
+	now := time.Now().UTC()
+	result := d.DB.WithContext(ctx).Model(&models.WebhookDelivery{}).
+		Where("id = ? AND status = ? AND attempts = ?", dl.ID, models.DeliveryPending, dl.Attempts).
+		UpdateColumns(map[string]any{
+			"attempts":        dl.Attempts + 1,
+			"next_attempt_at": now.Add(d.Lease),
+			"last_attempt_at": now,
+		})
+	if result.Error != nil {
+		return false, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return false, nil
+	}
+	dl.Attempts++
+	dl.LastAttemptAt = &now
+	return true, nil
+}
+
+// deliver sends a claimed delivery and records the outcome.
+func (d *Dispatcher) deliver(ctx context.Context, dl *models.WebhookDelivery) error {
+	var sub models.WebhookSubscription
+	err := d.DB.WithContext(ctx).First(&sub, "id = ?", dl.SubscriptionID).Error
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		return d.fail(ctx, dl, 0, "subscription was deleted", true)
+	case err != nil:
+		return err
+	case !sub.IsActive:
+		return d.fail(ctx, dl, 0, "subscription is paused", true)
+	}
+
+	status, err := d.send(ctx, &sub, dl)
+	if err != nil {
+		return d.fail(ctx, dl, status, err.Error(), false)
+	}
+	now := time.Now().UTC()
+	return d.DB.WithContext(ctx).Model(&models.WebhookDelivery{}).Where("id = ?", dl.ID).
+		UpdateColumns(map[string]any{
+			"status":          models.DeliveryDelivered,
+			"response_status": status,
+			"last_error":      "",
+			"delivered_at":    now,
+		}).Error
+}
+
+// send POSTs the delivery's payload to the subscription's URL. Any 2xx
+// response is a success. Only the status of a failed response is recorded;
+// its body is the receiver's and may hold anything.
+func (d *Dispatcher) send(ctx context.Context, sub *models.WebhookSubscription, dl *models.WebhookDelivery) (int, error) {
+	body := []byte(dl.Payload)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, sub.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "AgentEats-Webhooks/1.0")
+	req.Header.Set(EventHeader, string(dl.Event))
+	req.Header.Set(DeliveryHeader, dl.ID)
+	req.Header.Set(SignatureHeader, Sign(sub.Secret, time.Now(), body))
+
+	resp, err := d.Client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("HTTP %d", resp.StatusCode)
+	}
+	return resp.StatusCode, nil
+}
+
+// fail records a failed attempt. The delivery is retried after a backoff
+// unless it is out of attempts or final is set.
+func (d *Dispatcher) fail(ctx context.Context, dl *models.WebhookDelivery, status int, msg string, final bool) error {
+	updates := map[string]any{
+		"response_status": status,
+		"last_error":      msg,
+	}
+	if final || dl.Attempts >= d.MaxAttempts {
+		updates["status"] = models.DeliveryFailed
+	} else {
+		updates["next_attempt_at"] = time.Now().UTC().Add(d.backoff(dl.Attempts))
+	}
+	return d.DB.WithContext(ctx).Model(&models.WebhookDelivery{}).Where("id = ?", dl.ID).
+		UpdateColumns(updates).Error
+}
+
+// backoff returns the wait after the given number of failed attempts:
+// BaseDelay, doubled for each further attempt, capped at MaxDelay.
+func (d *Dispatcher) backoff(attempts int) time.Duration {
+	delay := d.BaseDelay
+	for i := 1; i < attempts && delay < d.MaxDelay; i++ {
+		delay *= 2
+	}
+	if delay > d.MaxDelay {
+		delay = d.MaxDelay
+	}
+	return delay
+}
@@ -0,0 +1,211 @@
+package webhooks
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/agenteats/agenteats/internal/config"
+	"github.com/agenteats/agenteats/internal/database"
+	"github.com/agenteats/agenteats/internal/models"
+)
+
+const testSecret = "whsec_test"
+
+// newTestDispatcher returns a Dispatcher on a fresh database with one
+// pending delivery to url.
+func newTestDispatcher(t *testing.T, url string) (*Dispatcher, *models.WebhookDelivery) {
+	t.Helper()
+	database.Init(&config.Config{DatabaseURL: filepath.Join(t.TempDir(), "test.db")})
+	db := database.DB
+
+	sub := models.WebhookSubscription{
+		ID:       models.NewID(),
+		OwnerID:  models.NewID(),
+		URL:      url,
+		Secret:   testSecret,
+		Events:   string(models.EventReservationCreated),
+		IsActive: true,
+	}
+	if err := db.Create(&sub).Error; err != nil {
+		t.Fatal(err)
+	}
+	dl := models.WebhookDelivery{
+		ID:             models.NewID(),
+		SubscriptionID: sub.ID,
+		OwnerID:        sub.OwnerID,
+		EventID:        models.NewID(),
+		Event:          models.EventReservationCreated,
+		Payload:        `{"event":"reservation.created"}`,
+		Status:         models.DeliveryPending,
+		NextAttemptAt:  time.Now().UTC().Add(-time.Second),
+	}
+	if err := db.Create(&dl).Error; err != nil {
+		t.Fatal(err)
+	}
+	return NewDispatcher(db), &dl
+}
+
+func reload(t *testing.T, db *gorm.DB, id string) models.WebhookDelivery {
+	t.Helper()
+	var dl models.WebhookDelivery
+	if err := db.First(&dl, "id = ?", id).Error; err != nil {
+		t.Fatal(err)
+	}
+	return dl
+}
+
+// makeDue lets the delivery's next attempt run now.
+func makeDue(t *testing.T, db *gorm.DB, id string) {
+	t.Helper()
+	if err := db.Model(&models.WebhookDelivery{}).Where("id = ?", id).
+		Update("next_attempt_at", time.Now().UTC().Add(-time.Second)).Error; err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestDispatchDueDeliversSignedPayload(t *testing.T) {
+	allowNets(t, "127.0.0.0/8, ::1/128")
+	var got atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if err := Verify(testSecret, r.Header.Get(SignatureHeader), body, 5*time.Minute, time.Now()); err != nil {
+			t.Errorf("receiver: %v", err)
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		if ev := r.Header.Get(EventHeader); ev != string(models.EventReservationCreated) {
+			t.Errorf("%s = %q", EventHeader, ev)
+		}
+		got.Add(1)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	d, dl := newTestDispatcher(t, srv.URL)
+	n, err := d.DispatchDue(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 1 || got.Load() != 1 {
+		t.Fatalf("attempted %d, received %d; want 1 and 1", n, got.Load())
+	}
+	after := reload(t, d.DB, dl.ID)
+	if after.Status != models.DeliveryDelivered || after.Attempts != 1 ||
+		after.ResponseStatus != http.StatusNoContent || after.DeliveredAt == nil {
+		t.Fatalf("delivery = %+v, want delivered after 1 attempt", after)
+	}
+
+	// Nothing is left to send.
+	if n, err := d.DispatchDue(context.Background()); err != nil || n != 0 {
+		t.Fatalf("second pass attempted %d, %v; want 0", n, err)
+	}
+}
+
+func TestDispatchDueRetriesWithBackoff(t *testing.T) {
+	allowNets(t, "127.0.0.0/8, ::1/128")
+	var got atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got.Add(1)
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer srv.Close()
+
+	d, dl := newTestDispatcher(t, srv.URL)
+	d.MaxAttempts = 3
+	ctx := context.Background()
+
+	before := time.Now().UTC()
+	if _, err := d.DispatchDue(ctx); err != nil {
+		t.Fatal(err)
+	}
+	after := reload(t, d.DB, dl.ID)
+	if after.Status != models.DeliveryPending || after.Attempts != 1 ||
+		after.ResponseStatus != http.StatusInternalServerError || after.LastError != "HTTP 500" {
+		t.Fatalf("after one failure: %+v", after)
+	}
+	if wait := after.NextAttemptAt.Sub(before); wait < d.BaseDelay || wait > d.BaseDelay+time.Minute {
+		t.Fatalf("next attempt in %v, want about %v", wait, d.BaseDelay)
+	}
+
+	// Not due yet, so nothing is sent.
+	if n, err := d.DispatchDue(ctx); err != nil || n != 0 {
+		t.Fatalf("pass before the retry attempted %d, %v; want 0", n, err)
+	}
+
+	for i := 0; i < 2; i++ {
+		makeDue(t, d.DB, dl.ID)
+		if _, err := d.DispatchDue(ctx); err != nil {
+			t.Fatal(err)
+		}
+	}
+	after = reload(t, d.DB, dl.ID)
+	if after.Status != models.DeliveryFailed || after.Attempts != 3 || got.Load() != 3 {
+		t.Fatalf("after %d requests: %+v, want failed after 3 attempts", got.Load(), after)
+	}
+}
+
+func TestDispatchDueBlocksPrivateAddress(t *testing.T) {
+	allowNets(t, "")
+	var got atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got.Add(1)
+	}))
+	defer srv.Close()
+
+	d, dl := newTestDispatcher(t, srv.URL)
+	if _, err := d.DispatchDue(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if got.Load() != 0 {
+		t.Fatal("receiver on a loopback address got a request")
+	}
+	after := reload(t, d.DB, dl.ID)
+	if after.Status != models.DeliveryPending || !strings.Contains(after.LastError, ErrForbiddenAddress.Error()) {
+		t.Fatalf("delivery = %+v, want a pending retry with a forbidden-address error", after)
+	}
+}
+
+func TestDispatchDueFailsPausedSubscription(t *testing.T) {
+	allowNets(t, "127.0.0.0/8, ::1/128")
+	d, dl := newTestDispatcher(t, "http://127.0.0.1:1/hook")
+	if err := d.DB.Model(&models.WebhookSubscription{}).Where("id = ?", dl.SubscriptionID).
+		Update("is_active", false).Error; err != nil {
+		t.Fatal(err)
+	}
+	if _, err := d.DispatchDue(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if after := reload(t, d.DB, dl.ID); after.Status != models.DeliveryFailed {
+		t.Fatalf("status = %s, want failed", after.Status)
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	d := &Dispatcher{BaseDelay: 30 * time.Second, MaxDelay: time.Hour}
+	tests := []struct {
+		attempts int
+		want     time.Duration
+	}{
+		{1, 30 * time.Second},
+		{2, time.Minute},
+		{3, 2 * time.Minute},
+		{5, 8 * time.Minute},
+		{7, 32 * time.Minute},
+		{8, time.Hour},
+		{20, time.Hour},
+	}
+	for _, tt := range tests {
+		if got := d.backoff(tt.attempts); got != tt.want {
+			t.Errorf("backoff(%d) = %v, want %v", tt.attempts, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,80 @@
+// Package webhooks delivers owners' webhook events. Services write pending
+// deliveries to the webhook_deliveries table in the same transaction as the
+// change they describe; a Dispatcher sends them, signed with the
+// subscription's secret, and retries failures with exponential backoff.
+package webhooks
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Headers sent with every delivery.
+const (
+	SignatureHeader = "X-AgentEats-Signature"
+	EventHeader     = "X-AgentEats-Event"
+	DeliveryHeader  = "X-AgentEats-Delivery"
+)
+
+// ErrBadSignature is returned by Verify when a signature header is missing,
+// malformed, expired or doesn't match the body.
+var ErrBadSignature = errors.New("invalid webhook signature")
+
+// Sign returns the signature header value for body sent at t:
+// "t=<unix seconds>,v1=<hex HMAC-SHA256 of "<unix seconds>.<body>">".
+// Including the timestamp lets receivers reject replayed requests.
+func Sign(secret string, t time.Time, body []byte) string {
+	ts := strconv.FormatInt(t.Unix(), 10)
+	return "t=" + ts + ",v1=" + mac(secret, ts, body)
+}
+
+func mac(secret, ts string, body []byte) string {
+	h := hmac.New(sha256.New, []byte(secret))
+	h.Write([]byte(ts))
+	h.Write([]byte("."))
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// Verify checks a signature header produced by Sign against body. Requests
+// signed more than tolerance before or after now are rejected; a zero
+// tolerance skips the check.
+func Verify(secret, header string, body []byte, tolerance time.Duration, now time.Time) error {
+	var ts string
+	var sigs []string
+	for _, part := range strings.Split(header, ",") {
+		k, v, ok := strings.Cut(strings.TrimSpace(part), "=")
+		if !ok {
+			continue
+		}
+		switch k {
+		case "t":
+			ts = v
+		case "v1":
+			sigs = append(sigs, v)
+		}
+	}
+	sec, err := strconv.ParseInt(ts, 10, 64)
+	if err != nil || len(sigs) == 0 {
+		return fmt.Errorf("%w: malformed header", ErrBadSignature)
+	}
+	if tolerance > 0 {
+		age := now.Sub(time.Unix(sec, 0))
+		if age > tolerance || age < -tolerance {
+			return fmt.Errorf("%w: timestamp outside tolerance", ErrBadSignature)
+		}
+	}
+	want := mac(secret, ts, body)
+	for _, s := range sigs {
+		if hmac.Equal([]byte(s), []byte(want)) {
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: signature mismatch", ErrBadSignature)
+}
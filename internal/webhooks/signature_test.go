@@ -0,0 +1,47 @@
+package webhooks
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestVerify(t *testing.T) {
+	now := time.Unix(1_700_000_000, 0)
+	body := []byte(`{"event":"reservation.created"}`)
+	signed := Sign("whsec_test", now, body)
+
+	tests := []struct {
+		name      string
+		secret    string
+		header    string
+		body      []byte
+		tolerance time.Duration
+		now       time.Time
+		wantErr   bool
+	}{
+		{name: "valid", secret: "whsec_test", header: signed, body: body, tolerance: 5 * time.Minute, now: now},
+		{name: "within tolerance", secret: "whsec_test", header: signed, body: body, tolerance: 5 * time.Minute, now: now.Add(4 * time.Minute)},
+		{name: "no tolerance check", secret: "whsec_test", header: signed, body: body, now: now.Add(24 * time.Hour)},
+		{name: "extra signature", secret: "whsec_test", header: signed + ",v1=deadbeef", body: body, now: now},
+		{name: "tampered body", secret: "whsec_test", header: signed, body: []byte(`{"event":"review.created"}`), now: now, wantErr: true},
+		{name: "wrong secret", secret: "whsec_other", header: signed, body: body, now: now, wantErr: true},
+		{name: "expired", secret: "whsec_test", header: signed, body: body, tolerance: 5 * time.Minute, now: now.Add(6 * time.Minute), wantErr: true},
+		{name: "from the future", secret: "whsec_test", header: signed, body: body, tolerance: 5 * time.Minute, now: now.Add(-6 * time.Minute), wantErr: true},
+		{name: "missing timestamp", secret: "whsec_test", header: "v1=abc", body: body, now: now, wantErr: true},
+		{name: "missing signature", secret: "whsec_test", header: "t=1700000000", body: body, now: now, wantErr: true},
+		{name: "empty", secret: "whsec_test", header: "", body: body, now: now, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Verify(tt.secret, tt.header, tt.body, tt.tolerance, tt.now)
+			if tt.wantErr {
+				if !errors.Is(err, ErrBadSignature) {
+					t.Fatalf("Verify = %v, want ErrBadSignature", err)
+				}
+			} else if err != nil {
+				t.Fatalf("Verify = %v, want nil", err)
+			}
+		})
+	}
+}
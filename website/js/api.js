@@ -96,7 +96,7 @@ async function searchRestaurants(params = {}) {
 }
 
 async function listMyRestaurants() {
-  return apiFetch('/owners/restaurants');
+  return apiFetch('/owners/restaurants?limit=200');
 }
 
 // --- Menu ---
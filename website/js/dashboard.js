@@ -77,8 +77,8 @@ async function loadMyRestaurants() {
   const container = document.getElementById('restaurant-list');
 
   try {
-    const results = await AgentEatsAPI.listMyRestaurants();
-    myRestaurants = Array.isArray(results) ? results : [];
+    const page = await AgentEatsAPI.listMyRestaurants();
+    myRestaurants = (page && page.items) || [];
 
     if (myRestaurants.length === 0) {
       container.innerHTML = `